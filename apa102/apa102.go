@@ -6,6 +6,8 @@ package apa102 // import "tinygo.org/x/drivers/apa102"
 import (
 	"image/color"
 	"machine"
+
+	"tinygo.org/x/drivers/effects"
 )
 
 const (
@@ -19,12 +21,30 @@ const (
 	GRB
 )
 
+const (
+	// APA102 LEDs end a frame with one bits.
+	APA102 = iota
+
+	// SK9822 LEDs are mostly compatible with the APA102, but apply the
+	// brightness changes on the next frame and need a reset frame of zeros
+	// to update all LEDs.
+	SK9822
+)
+
+// Pixel is the color of a LED with its own brightness.
+type Pixel struct {
+	// Brightness is the 5 bit current control of the LED, from 0 to 31.
+	Brightness uint8
+	R, G, B    uint8
+}
+
 var startFrame = []byte{0x00, 0x00, 0x00, 0x00}
 
 // Device wraps APA102 SPI LEDs.
 type Device struct {
 	bus   SPI
 	Order int
+	Chip  int
 }
 
 // The SPI interface specifies the minimum functionality that a bus
@@ -37,7 +57,7 @@ type SPI interface {
 
 // New returns a new APA102 driver. Pass in a fully configured SPI bus.
 func New(b SPI) Device {
-	return Device{bus: b, Order: BGR}
+	return Device{bus: b, Order: BGR, Chip: APA102}
 }
 
 // NewSoftwareSPI returns a new APA102 driver that will use a software based
@@ -46,6 +66,15 @@ func NewSoftwareSPI(sckPin, sdoPin machine.Pin, delay uint32) Device {
 	return New(&bbSPI{SCK: sckPin, SDO: sdoPin, Delay: delay})
 }
 
+// Sink returns the device as an effects.Sink, to play LED animations on it.
+// The frames are written with WriteColors.
+func (d *Device) Sink() effects.Sink {
+	return effects.SinkFunc(func(cs []color.RGBA) error {
+		_, err := d.WriteColors(cs)
+		return err
+	})
+}
+
 // WriteColors writes the given RGBA color slice out using the APA102 protocol.
 // The A value (Alpha channel) is used for brightness, set to 0xff (255) for maximum.
 func (d Device) WriteColors(cs []color.RGBA) (n int, err error) {
@@ -54,23 +83,7 @@ func (d Device) WriteColors(cs []color.RGBA) (n int, err error) {
 	// write data
 	for _, c := range cs {
 		// brightness is scaled to 5 bit value
-		d.bus.Transfer(0xe0 | (c.A >> 3))
-
-		// set the colors
-		switch d.Order {
-		case BRG:
-			d.bus.Transfer(c.B)
-			d.bus.Transfer(c.R)
-			d.bus.Transfer(c.G)
-		case GRB:
-			d.bus.Transfer(c.G)
-			d.bus.Transfer(c.R)
-			d.bus.Transfer(c.B)
-		case BGR:
-			d.bus.Transfer(c.B)
-			d.bus.Transfer(c.G)
-			d.bus.Transfer(c.R)
-		}
+		d.writePixel(c.A>>3, c.R, c.G, c.B)
 	}
 
 	d.endFrame(len(cs))
@@ -78,6 +91,82 @@ func (d Device) WriteColors(cs []color.RGBA) (n int, err error) {
 	return len(cs), nil
 }
 
+// WritePixels writes the given pixels out using the APA102 protocol, with the
+// brightness of each of them.
+func (d Device) WritePixels(ps []Pixel) (n int, err error) {
+	d.startFrame()
+	for _, p := range ps {
+		d.writePixel(p.Brightness, p.R, p.G, p.B)
+	}
+	d.endFrame(len(ps))
+	return len(ps), nil
+}
+
+// WriteColors16 writes the given 16 bit per channel colors out using the
+// APA102 protocol. The A value is ignored.
+//
+// For each LED, the lowest brightness that can show the color is used, and
+// the color is sent with the PWM value that remains. This gives dim colors a
+// much better resolution than 8 bit colors at full brightness.
+func (d Device) WriteColors16(cs []color.RGBA64) (n int, err error) {
+	d.startFrame()
+	for _, c := range cs {
+		b, r, g, bl := HDR(c)
+		d.writePixel(b, r, g, bl)
+	}
+	d.endFrame(len(cs))
+	return len(cs), nil
+}
+
+// HDR returns the brightness and the 8 bit PWM values that show the 16 bit
+// per channel color c with the best resolution.
+func HDR(c color.RGBA64) (brightness, r, g, b uint8) {
+	max := c.R
+	if c.G > max {
+		max = c.G
+	}
+	if c.B > max {
+		max = c.B
+	}
+	// The LED current is brightness/31 of the full current. Pick the lowest
+	// current for which the brightest channel does not exceed 255 PWM steps.
+	scale := uint32(0xff * 0x101)
+	brightness = uint8((uint32(max)*31 + scale - 1) / scale)
+	if brightness == 0 {
+		return 0, 0, 0, 0
+	}
+	div := uint32(brightness) * 0x101
+	pwm := func(v uint16) uint8 {
+		p := (uint32(v)*31 + div/2) / div
+		if p > 0xff {
+			p = 0xff
+		}
+		return uint8(p)
+	}
+	return brightness, pwm(c.R), pwm(c.G), pwm(c.B)
+}
+
+// writePixel sends a single LED frame, in the color order of the device.
+func (d Device) writePixel(brightness, r, g, b uint8) {
+	d.bus.Transfer(0xe0 | brightness&0x1f)
+
+	// set the colors
+	switch d.Order {
+	case BRG:
+		d.bus.Transfer(b)
+		d.bus.Transfer(r)
+		d.bus.Transfer(g)
+	case GRB:
+		d.bus.Transfer(g)
+		d.bus.Transfer(r)
+		d.bus.Transfer(b)
+	case BGR:
+		d.bus.Transfer(b)
+		d.bus.Transfer(g)
+		d.bus.Transfer(r)
+	}
+}
+
 // Write the raw bytes using the APA102 protocol.
 func (d Device) Write(buf []byte) (n int, err error) {
 	d.startFrame()
@@ -95,7 +184,18 @@ func (d Device) startFrame() {
 // endFrame sends the end frame marker with one extra bit per LED so
 // long strands of LEDs receive the necessary termination for updates.
 // See https://cpldcpu.wordpress.com/2014/11/30/understanding-the-apa102-superled/
+//
+// The SK9822 needs a reset frame of zeros first, and zeros for the end frame
+// so that they are not taken as the start of a new LED frame.
+// See https://cpldcpu.wordpress.com/2016/12/13/sk9822-a-clone-of-the-apa102/
 func (d Device) endFrame(count int) {
+	if d.Chip == SK9822 {
+		d.bus.Tx(startFrame, nil)
+		for i := 0; i < (count+15)/16; i++ {
+			d.bus.Transfer(0x00)
+		}
+		return
+	}
 	for i := 0; i < count/16; i++ {
 		d.bus.Transfer(0xff)
 	}
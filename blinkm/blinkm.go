@@ -4,7 +4,11 @@
 //
 package blinkm // import "tinygo.org/x/drivers/blinkm"
 
-import "tinygo.org/x/drivers"
+import (
+	"image/color"
+
+	"tinygo.org/x/drivers"
+)
 
 // Device wraps an I2C connection to a BlinkM device.
 type Device struct {
@@ -38,6 +42,15 @@ func (d Device) SetRGB(r, g, b byte) error {
 	return nil
 }
 
+// WriteColors sets the RGB color on the BlinkM to the first color of the
+// slice, so it can be used as a single LED strip.
+func (d Device) WriteColors(cs []color.RGBA) error {
+	if len(cs) == 0 {
+		return nil
+	}
+	return d.SetRGB(cs[0].R, cs[0].G, cs[0].B)
+}
+
 // GetRGB gets the current RGB color on the BlinkM.
 func (d Device) GetRGB() (r, g, b byte, err error) {
 	color := []byte{0, 0, 0}
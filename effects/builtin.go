@@ -0,0 +1,199 @@
+package effects
+
+import (
+	"image/color"
+	"time"
+)
+
+// Solid shows a single color.
+func Solid(c color.RGBA) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		fill(frame, c)
+	})
+}
+
+// Rainbow shows the whole color wheel along the strip, turning once per
+// period.
+func Rainbow(period time.Duration) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		offset := phase(t, period, 1<<16)
+		for i := range frame {
+			h := offset + uint32(i)<<16/uint32(len(frame))
+			frame[i] = HSV(uint16(h), 0xff, 0xff)
+		}
+	})
+}
+
+// Chase moves a segment of width LEDs of color c over the background bg,
+// advancing one LED per step and wrapping around at the end of the strip.
+// A step of 0 shows a still segment.
+func Chase(c, bg color.RGBA, width int, step time.Duration) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		if len(frame) == 0 {
+			return
+		}
+		pos := int(steps(t, step) % int64(len(frame)))
+		for i := range frame {
+			if (i-pos+len(frame))%len(frame) < width {
+				frame[i] = c
+			} else {
+				frame[i] = bg
+			}
+		}
+	})
+}
+
+// Fade fades from a to b and back once per period.
+func Fade(a, b color.RGBA, period time.Duration) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		f := phase(t, period, 510)
+		if f > 255 {
+			f = 510 - f
+		}
+		fill(frame, Blend(a, b, uint8(f)))
+	})
+}
+
+// Fire shows flames rising from the start of the strip. The flames flicker
+// every step, seed selects a different flame. A step of 0 shows still flames.
+func Fire(step time.Duration, seed uint32) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		n := uint32(steps(t, step))
+		f := uint8(phase(t, step, 256))
+		for i := range frame {
+			heat := blend8(noise(seed, i, n), noise(seed, i, n+1), f)
+			// the flames are hotter at the base and cool down while rising
+			cooling := uint8(i * 255 / len(frame))
+			heat = uint8(uint16(heat)/2 + 128 - uint16(cooling)/2)
+			frame[i] = HeatColor(blend8(heat, 0, cooling))
+		}
+	})
+}
+
+// Reverse renders e from the end of the strip.
+func Reverse(e Effect) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		e.Render(frame, t)
+		for i, j := 0, len(frame)-1; i < j; i, j = i+1, j-1 {
+			frame[i], frame[j] = frame[j], frame[i]
+		}
+	})
+}
+
+// Segment renders e on n LEDs from start, leaving the others unchanged.
+func Segment(e Effect, start, n int) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		if start >= len(frame) {
+			return
+		}
+		end := start + n
+		if end > len(frame) {
+			end = len(frame)
+		}
+		e.Render(frame[start:end], t)
+	})
+}
+
+// Dim renders e scaled to the given brightness, from 0 (off) to 255.
+func Dim(e Effect, level uint8) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		e.Render(frame, t)
+		for i, c := range frame {
+			frame[i] = Scale(c, level)
+		}
+	})
+}
+
+// Delay starts e at time d: it is rendered at t-d, and at 0 before d.
+func Delay(e Effect, d time.Duration) Effect {
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		t -= d
+		if t < 0 {
+			t = 0
+		}
+		e.Render(frame, t)
+	})
+}
+
+// Add renders all effects and adds their colors, saturating at full
+// brightness.
+func Add(effects ...Effect) Effect {
+	var scratch []color.RGBA
+	return Func(func(frame []color.RGBA, t time.Duration) {
+		fill(frame, color.RGBA{})
+		for _, e := range effects {
+			scratch = render(e, scratch, len(frame), t)
+			for i, c := range scratch {
+				frame[i] = AddColors(frame[i], c)
+			}
+		}
+	})
+}
+
+// Crossfade renders from until start, then fades to to during d.
+func Crossfade(from, to Effect, start, d time.Duration) Effect {
+	return &crossfade{from: from, to: to, start: start, duration: d}
+}
+
+type crossfade struct {
+	from, to Effect
+	start    time.Duration
+	duration time.Duration
+	scratch  []color.RGBA
+}
+
+func (c *crossfade) Render(frame []color.RGBA, t time.Duration) {
+	switch {
+	case t < c.start:
+		c.from.Render(frame, t)
+	case t >= c.start+c.duration:
+		c.to.Render(frame, t)
+	default:
+		f := uint8((t - c.start) * 255 / c.duration)
+		c.to.Render(frame, t)
+		c.scratch = render(c.from, c.scratch, len(frame), t)
+		for i, from := range c.scratch {
+			frame[i] = Blend(from, frame[i], f)
+		}
+	}
+}
+
+// render renders e in the scratch buffer, growing it to n LEDs if needed,
+// and returns it.
+func render(e Effect, scratch []color.RGBA, n int, t time.Duration) []color.RGBA {
+	if cap(scratch) < n {
+		scratch = make([]color.RGBA, n)
+	}
+	scratch = scratch[:n]
+	e.Render(scratch, t)
+	return scratch
+}
+
+// phase returns the position of t in the current period, scaled from 0 to
+// max (excluded).
+func phase(t, period time.Duration, max uint32) uint32 {
+	if period <= 0 {
+		return 0
+	}
+	return uint32(uint64(t%period) * uint64(max) / uint64(period))
+}
+
+// steps returns the number of whole steps in t, or 0 if step is not
+// positive.
+func steps(t, step time.Duration) int64 {
+	if step <= 0 {
+		return 0
+	}
+	return int64(t / step)
+}
+
+// noise returns a pseudo-random value for the LED i at step n.
+func noise(seed uint32, i int, n uint32) uint8 {
+	x := seed ^ uint32(i)*0x9e3779b1 ^ n*0x85ebca77
+	x ^= x >> 15
+	x *= 0x2c1b3c6d
+	x ^= x >> 12
+	x *= 0x297a2d39
+	x ^= x >> 15
+	return uint8(x >> 24)
+}
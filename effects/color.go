@@ -0,0 +1,81 @@
+package effects
+
+import "image/color"
+
+// HSV returns the color with hue h, a full turn of the color wheel being
+// 65536, saturation s and value v.
+func HSV(h uint16, s, v uint8) color.RGBA {
+	// 6 sectors of the color wheel, with a position from 0 to 255 in each
+	sector := uint32(h) * 6 >> 16
+	pos := uint32(h)*6*256>>16 - sector*256
+
+	max := uint32(v)
+	min := max * (255 - uint32(s)) / 255
+	up := min + (max-min)*pos/255
+	down := max - (max-min)*pos/255
+
+	var r, g, b uint32
+	switch sector {
+	case 0:
+		r, g, b = max, up, min
+	case 1:
+		r, g, b = down, max, min
+	case 2:
+		r, g, b = min, max, up
+	case 3:
+		r, g, b = min, down, max
+	case 4:
+		r, g, b = up, min, max
+	default:
+		r, g, b = max, min, down
+	}
+	return color.RGBA{uint8(r), uint8(g), uint8(b), 0xff}
+}
+
+// Blend returns the color between a and b at f, from 0 (a) to 255 (b).
+func Blend(a, b color.RGBA, f uint8) color.RGBA {
+	return color.RGBA{
+		blend8(a.R, b.R, f),
+		blend8(a.G, b.G, f),
+		blend8(a.B, b.B, f),
+		blend8(a.A, b.A, f),
+	}
+}
+
+func blend8(a, b, f uint8) uint8 {
+	return uint8((int32(a)*(255-int32(f)) + int32(b)*int32(f) + 127) / 255)
+}
+
+// Scale returns c with its components scaled by level, from 0 (off) to 255
+// (unchanged).
+func Scale(c color.RGBA, level uint8) color.RGBA {
+	return Blend(color.RGBA{A: c.A}, c, level)
+}
+
+// AddColors returns the sum of two colors, saturating at 255.
+func AddColors(a, b color.RGBA) color.RGBA {
+	return color.RGBA{add8(a.R, b.R), add8(a.G, b.G), add8(a.B, b.B), add8(a.A, b.A)}
+}
+
+func add8(a, b uint8) uint8 {
+	if s := uint16(a) + uint16(b); s < 0xff {
+		return uint8(s)
+	}
+	return 0xff
+}
+
+// HeatColor returns the color of a flame with the given heat, from black to
+// red, yellow and white.
+func HeatColor(heat uint8) color.RGBA {
+	// scale to 0..191 and ramp up inside each third
+	t := uint16(heat) * 191 / 255
+	ramp := uint8(t&0x3f) << 2
+	switch {
+	case t >= 0x80:
+		return color.RGBA{0xff, 0xff, ramp, 0xff}
+	case t >= 0x40:
+		return color.RGBA{0xff, ramp, 0, 0xff}
+	default:
+		return color.RGBA{ramp, 0, 0, 0xff}
+	}
+}
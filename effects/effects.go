@@ -0,0 +1,146 @@
+// Package effects implements LED strip animations, such as rainbows, chases,
+// fades and fire, working on a []color.RGBA frame.
+//
+// Effects render a frame for a given time, without depending on the frames
+// rendered before, so the same time always gives the same frame. A Player
+// renders the current effect at a fixed frame rate, crossfades between
+// effects and writes the frames to a Sink, such as the ws2812 and blinkm
+// drivers, or the apa102 driver through its Sink method.
+package effects // import "tinygo.org/x/drivers/effects"
+
+import (
+	"image/color"
+	"time"
+)
+
+// Sink is a device showing frames of colors, such as a LED strip.
+type Sink interface {
+	WriteColors(buf []color.RGBA) error
+}
+
+// SinkFunc is a Sink implemented by a function. It adapts drivers whose
+// WriteColors method has another signature, as apa102.Device.Sink does.
+type SinkFunc func(buf []color.RGBA) error
+
+// WriteColors calls f.
+func (f SinkFunc) WriteColors(buf []color.RGBA) error {
+	return f(buf)
+}
+
+// Effect is an animation.
+type Effect interface {
+	// Render draws the frame at time t since the start of the effect.
+	Render(frame []color.RGBA, t time.Duration)
+}
+
+// Func is an Effect implemented by a function.
+type Func func(frame []color.RGBA, t time.Duration)
+
+// Render calls f.
+func (f Func) Render(frame []color.RGBA, t time.Duration) {
+	f(frame, t)
+}
+
+// Player plays effects on a sink at a fixed frame rate.
+type Player struct {
+	sink     Sink
+	frame    []color.RGBA
+	effect   Effect
+	interval time.Duration
+	now      time.Duration
+	start    time.Time
+	next     time.Duration
+	started  bool
+}
+
+// NewPlayer returns a player for a sink of n LEDs, rendering fps frames per
+// second. It plays nothing (all LEDs off) until Play is called.
+func NewPlayer(sink Sink, n int, fps int) *Player {
+	if fps <= 0 {
+		fps = 50
+	}
+	return &Player{
+		sink:     sink,
+		frame:    make([]color.RGBA, n),
+		interval: time.Second / time.Duration(fps),
+	}
+}
+
+// Frame returns the last rendered frame.
+func (p *Player) Frame() []color.RGBA {
+	return p.frame
+}
+
+// Play switches to the effect immediately. The effect starts at the time of
+// the last rendered frame.
+func (p *Player) Play(e Effect) {
+	p.effect = Delay(e, p.now)
+}
+
+// CrossfadeTo switches to the effect by crossfading from the current one
+// during d.
+func (p *Player) CrossfadeTo(e Effect, d time.Duration) {
+	from := p.effect
+	if from == nil {
+		from = Solid(color.RGBA{})
+	}
+	p.effect = &crossfade{
+		from:     from,
+		to:       Delay(e, p.now),
+		start:    p.now,
+		duration: d,
+	}
+}
+
+// Render renders the frame at time t since the start of the player and
+// returns it.
+func (p *Player) Render(t time.Duration) []color.RGBA {
+	p.now = t
+	if c, ok := p.effect.(*crossfade); ok && t >= c.start+c.duration {
+		// the crossfade is over
+		p.effect = c.to
+	}
+	if p.effect == nil {
+		fill(p.frame, color.RGBA{})
+	} else {
+		p.effect.Render(p.frame, t)
+	}
+	return p.frame
+}
+
+// Show renders the frame at time t and writes it to the sink.
+func (p *Player) Show(t time.Duration) error {
+	return p.sink.WriteColors(p.Render(t))
+}
+
+// Step waits for the time of the next frame, then renders and writes it. The
+// first call starts the clock of the player. Frames are skipped if Step is
+// not called often enough.
+func (p *Player) Step() error {
+	if !p.started {
+		p.start = time.Now()
+		p.started = true
+	}
+	if wait := p.next - time.Since(p.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	t := time.Since(p.start)
+	p.next = (t/p.interval + 1) * p.interval
+	return p.Show(t)
+}
+
+// Run plays the effects forever, or until the sink returns an error.
+func (p *Player) Run() error {
+	for {
+		if err := p.Step(); err != nil {
+			return err
+		}
+	}
+}
+
+// fill sets all LEDs of a frame to c.
+func fill(frame []color.RGBA, c color.RGBA) {
+	for i := range frame {
+		frame[i] = c
+	}
+}
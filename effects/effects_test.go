@@ -0,0 +1,118 @@
+package effects
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+var (
+	black = color.RGBA{}
+	red   = color.RGBA{0xff, 0, 0, 0xff}
+	blue  = color.RGBA{0, 0, 0xff, 0xff}
+)
+
+type fakeSink struct {
+	frames [][]color.RGBA
+}
+
+func (s *fakeSink) WriteColors(buf []color.RGBA) error {
+	s.frames = append(s.frames, append([]color.RGBA(nil), buf...))
+	return nil
+}
+
+func TestHSV(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(HSV(0, 0xff, 0xff), qt.Equals, red)
+	c.Assert(HSV(0x10000/3, 0xff, 0xff), qt.Equals, color.RGBA{0, 0xff, 0, 0xff})
+	c.Assert(HSV(0x20000/3+1, 0xff, 0xff), qt.Equals, blue)
+	c.Assert(HSV(0x1234, 0, 0x80), qt.Equals, color.RGBA{0x80, 0x80, 0x80, 0xff})
+	c.Assert(HSV(0x8000, 0xff, 0), qt.Equals, color.RGBA{0, 0, 0, 0xff})
+}
+
+func TestBlend(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(Blend(red, blue, 0), qt.Equals, red)
+	c.Assert(Blend(red, blue, 255), qt.Equals, blue)
+	c.Assert(Blend(red, blue, 128), qt.Equals, color.RGBA{0x7f, 0, 0x80, 0xff})
+	c.Assert(AddColors(color.RGBA{200, 10, 0, 0}, color.RGBA{100, 10, 0, 0}), qt.Equals, color.RGBA{255, 20, 0, 0})
+}
+
+func TestDeterministic(t *testing.T) {
+	c := qt.New(t)
+	for _, e := range []Effect{
+		Rainbow(time.Second),
+		Fire(30*time.Millisecond, 42),
+		Fade(red, blue, time.Second),
+		Chase(red, black, 3, 10*time.Millisecond),
+	} {
+		a := make([]color.RGBA, 30)
+		b := make([]color.RGBA, 30)
+		e.Render(a, 1234*time.Millisecond)
+		Rainbow(time.Second).Render(b, 0) // render something else in between
+		e.Render(b, 1234*time.Millisecond)
+		c.Assert(a, qt.DeepEquals, b)
+	}
+}
+
+func TestChase(t *testing.T) {
+	c := qt.New(t)
+	frame := make([]color.RGBA, 5)
+	e := Chase(red, black, 2, 10*time.Millisecond)
+	e.Render(frame, 35*time.Millisecond)
+	c.Assert(frame, qt.DeepEquals, []color.RGBA{black, black, black, red, red})
+	e.Render(frame, 40*time.Millisecond)
+	c.Assert(frame, qt.DeepEquals, []color.RGBA{red, black, black, black, red})
+}
+
+func TestZeroStep(t *testing.T) {
+	c := qt.New(t)
+	frame := make([]color.RGBA, 5)
+	Chase(red, black, 2, 0).Render(frame, 35*time.Millisecond)
+	c.Assert(frame, qt.DeepEquals, []color.RGBA{red, red, black, black, black})
+
+	// still flames
+	a := make([]color.RGBA, 5)
+	b := make([]color.RGBA, 5)
+	Fire(0, 1).Render(a, 0)
+	Fire(0, 1).Render(b, time.Second)
+	c.Assert(a, qt.DeepEquals, b)
+}
+
+func TestCompose(t *testing.T) {
+	c := qt.New(t)
+	frame := make([]color.RGBA, 4)
+	e := Add(Segment(Solid(red), 0, 2), Reverse(Segment(Solid(blue), 0, 3)))
+	e.Render(frame, 0)
+	c.Assert(frame, qt.DeepEquals, []color.RGBA{
+		red,
+		{0xff, 0, 0xff, 0xff},
+		blue,
+		blue,
+	})
+}
+
+func TestPlayerCrossfade(t *testing.T) {
+	c := qt.New(t)
+	sink := &fakeSink{}
+	p := NewPlayer(sink, 2, 50)
+	c.Assert(p.Show(0), qt.IsNil)
+	c.Assert(sink.frames[0], qt.DeepEquals, []color.RGBA{black, black})
+
+	p.Play(Solid(red))
+	p.Render(time.Second)
+	p.CrossfadeTo(Chase(blue, black, 1, 100*time.Millisecond), time.Second)
+
+	// the new effect starts with the crossfade
+	frame := p.Render(1500 * time.Millisecond)
+	c.Assert(frame, qt.DeepEquals, []color.RGBA{
+		Blend(red, black, 127),
+		Blend(red, blue, 127),
+	})
+	frame = p.Render(2 * time.Second)
+	c.Assert(frame, qt.DeepEquals, []color.RGBA{blue, black})
+	frame = p.Render(2100 * time.Millisecond)
+	c.Assert(frame, qt.DeepEquals, []color.RGBA{black, blue})
+}
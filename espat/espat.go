@@ -20,6 +20,7 @@ package espat // import "tinygo.org/x/drivers/espat"
 
 import (
 	"errors"
+	"io"
 	"machine"
 	"strconv"
 	"strings"
@@ -35,8 +36,21 @@ type Device struct {
 	// command responses that come back from the ESP8266/ESP32
 	response []byte
 
-	// data received from a TCP/UDP connection forwarded by the ESP8266/ESP32
-	socketdata []byte
+	// whether the ESP8266/ESP32 is in multiple connection mode
+	mux bool
+
+	// links in use, indexed by link ID
+	links [MaxLinks]bool
+
+	// links in use closed by the peer or the ESP8266/ESP32
+	closed [MaxLinks]bool
+
+	// whether the TCP server is started, and its connections not accepted yet
+	server  bool
+	pending [MaxLinks]bool
+
+	// data received from each TCP/UDP connection forwarded by the ESP8266/ESP32
+	socketdata [MaxLinks][]byte
 }
 
 // ActiveDevice is the currently configured Device in use. There can only be one.
@@ -44,7 +58,7 @@ var ActiveDevice *Device
 
 // New returns a new espat driver. Pass in a fully configured UART bus.
 func New(b machine.UART) *Device {
-	return &Device{bus: b, response: make([]byte, 512), socketdata: make([]byte, 0, 1024)}
+	return &Device{bus: b, response: make([]byte, 512)}
 }
 
 // Configure sets up the device for communication.
@@ -127,22 +141,32 @@ func (d Device) Reset() {
 	d.Response(100)
 }
 
-// ReadSocket returns the data that has already been read in from the responses.
-func (d *Device) ReadSocket(b []byte) (n int, err error) {
+// ReadSocket returns the data that has already been read in from the responses
+// for the given link. Once the link is closed by the peer and all its data has
+// been read, it returns io.EOF.
+func (d *Device) ReadSocket(sock net.Socket, b []byte) (n int, err error) {
+	if !d.isOpen(sock) {
+		return 0, ErrInvalidLink
+	}
+
 	// make sure no data in buffer
 	d.Response(300)
 
+	data := d.socketdata[sock]
+	if len(data) == 0 && d.closed[sock] {
+		return 0, io.EOF
+	}
 	count := len(b)
-	if len(b) >= len(d.socketdata) {
+	if len(b) >= len(data) {
 		// copy it all, then clear socket data
-		count = len(d.socketdata)
-		copy(b, d.socketdata[:count])
-		d.socketdata = d.socketdata[:0]
+		count = len(data)
+		copy(b, data[:count])
+		d.socketdata[sock] = data[:0]
 	} else {
 		// copy all we can, then keep the remaining socket data around
-		copy(b, d.socketdata[:count])
-		copy(d.socketdata, d.socketdata[count:])
-		d.socketdata = d.socketdata[:len(d.socketdata)-count]
+		copy(b, data[:count])
+		copy(data, data[count:])
+		d.socketdata[sock] = data[:len(data)-count]
 	}
 
 	return count, nil
@@ -170,6 +194,9 @@ func (d *Device) Response(timeout int) ([]byte, error) {
 				return nil, d.parseIPD(end)
 			}
 
+			// keep track of the connections opened and closed
+			d.parseLinks(string(d.response[:end]))
+
 			// if "OK" then the command worked
 			if strings.Contains(string(d.response[:end]), "OK") {
 				return d.response[start:end], nil
@@ -194,29 +221,89 @@ func (d *Device) Response(timeout int) ([]byte, error) {
 	}
 }
 
+// parseIPD loads the data of a "+IPD" message into the socket data of its
+// link. The messages before and after the data are parsed by parseLinks, the
+// data itself is not.
 func (d *Device) parseIPD(end int) error {
-	// find the "+IPD," to get length
-	s := strings.Index(string(d.response[:end]), "+IPD,")
+	r := string(d.response[:end])
+
+	// find the "+IPD," to get link ID and length
+	s := strings.Index(r, "+IPD,")
+	if s < 0 {
+		return errors.New("invalid +IPD message")
+	}
+	d.parseLinks(r[:s])
 
 	// find the ":"
-	e := strings.Index(string(d.response[:end]), ":")
+	e := strings.IndexByte(r[s:], ':')
+	if e < 0 {
+		return errors.New("invalid +IPD message")
+	}
+	e += s
 
-	// find the data length
+	// in multiple connection mode, the link ID precedes the data length
+	link := 0
 	val := string(d.response[s+5 : e])
+	if c := strings.Index(val, ","); c >= 0 {
+		id, err := strconv.Atoi(val[:c])
+		if err != nil || id < 0 || id >= MaxLinks {
+			return ErrInvalidLink
+		}
+		link = id
+		val = val[c+1:]
+	}
 
-	// TODO: verify count
-	_, err := strconv.Atoi(val)
+	count, err := strconv.Atoi(val)
 	if err != nil {
 		// not expected data here. what to do?
 		return err
 	}
 
 	// load up the socket data
-	d.socketdata = append(d.socketdata, d.response[e+1:end]...)
+	data := d.response[e+1 : end]
+	if count < len(data) {
+		data = data[:count]
+		d.parseLinks(r[e+1+count:])
+	}
+	d.socketdata[link] = append(d.socketdata[link], data...)
 	return nil
 }
 
-// IsSocketDataAvailable returns of there is socket data available
-func (d *Device) IsSocketDataAvailable() bool {
-	return len(d.socketdata) > 0 || d.bus.Buffered() > 0
+// parseLinks looks for the "<link ID>,CONNECT" messages of new connections
+// to the TCP server, and the "<link ID>,CLOSED" messages of the connections
+// closed, which are either not accepted yet or read until io.EOF. The text r
+// must not hold the data of "+IPD" messages.
+func (d *Device) parseLinks(r string) {
+	for i := 2; i < len(r); i++ {
+		if r[i-1] != ',' || r[i-2] < '0' || r[i-2] >= '0'+MaxLinks {
+			continue
+		}
+		link := r[i-2] - '0'
+		switch {
+		case strings.HasPrefix(r[i:], "CONNECT\r\n"):
+			if d.server && !d.links[link] {
+				d.pending[link] = true
+				// the data of the previous connection on the link is stale
+				d.socketdata[link] = d.socketdata[link][:0]
+			}
+		case strings.HasPrefix(r[i:], "CLOSED"):
+			d.pending[link] = false
+			if d.links[link] {
+				d.closed[link] = true
+			}
+		}
+	}
+}
+
+// IsSocketDataAvailable returns of there is socket data available for the
+// given link, or if the link is closed, so that ReadSocket returns io.EOF.
+func (d *Device) IsSocketDataAvailable(sock net.Socket) bool {
+	if !d.isOpen(sock) {
+		return false
+	}
+	if len(d.socketdata[sock]) == 0 && d.bus.Buffered() > 0 {
+		// the buffered data may be for another link, so read it in first
+		d.Response(100)
+	}
+	return len(d.socketdata[sock]) > 0 || d.closed[sock]
 }
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"strconv"
 	"strings"
+
+	"tinygo.org/x/drivers/net"
 )
 
 const (
@@ -12,6 +14,21 @@ const (
 
 	TCPTransferModeNormal      = 0
 	TCPTransferModeUnvarnished = 1
+
+	// MaxLinks is the number of concurrent connections in multiple
+	// connection mode.
+	MaxLinks = 5
+
+	// serverSocket is the socket handle of the TCP server.
+	serverSocket = net.Socket(MaxLinks)
+)
+
+var (
+	// ErrInvalidLink is returned when using a link that is not connected.
+	ErrInvalidLink = errors.New("invalid link ID")
+
+	// ErrServerStarted is returned when starting a second TCP server.
+	ErrServerStarted = errors.New("TCP server already started")
 )
 
 // GetDNS returns the IP address for a domain name.
@@ -32,54 +49,151 @@ func (d *Device) GetDNS(domain string) (string, error) {
 	return res[0], nil
 }
 
-// ConnectTCPSocket creates a new TCP socket connection for the ESP8266/ESP32.
-// Currently only supports single connection mode.
-func (d *Device) ConnectTCPSocket(addr, port string) error {
-	protocol := "TCP"
-	val := "\"" + protocol + "\",\"" + addr + "\"," + port + ",120"
-	err := d.Set(TCPConnect, val)
+// ConnectTCPSocket creates a new TCP socket connection for the ESP8266/ESP32,
+// and returns its link ID.
+func (d *Device) ConnectTCPSocket(addr, port string) (net.Socket, error) {
+	val := "\"TCP\",\"" + addr + "\"," + port + ",120"
+	return d.connect(val, 3000)
+}
+
+// ConnectUDPSocket creates a new UDP connection for the ESP8266/ESP32,
+// and returns its link ID.
+func (d *Device) ConnectUDPSocket(addr, sendport, listenport string) (net.Socket, error) {
+	val := "\"UDP\",\"" + addr + "\"," + sendport + "," + listenport + ",2"
+	return d.connect(val, 3000)
+}
+
+// ConnectSSLSocket creates a new SSL socket connection for the ESP8266/ESP32,
+// and returns its link ID.
+func (d *Device) ConnectSSLSocket(addr, port string) (net.Socket, error) {
+	val := "\"SSL\",\"" + addr + "\"," + port + ",120"
+	// this operation takes longer, so wait up to 6 seconds to complete.
+	return d.connect(val, 6000)
+}
+
+// connect starts a connection on a free link.
+func (d *Device) connect(val string, timeout int) (net.Socket, error) {
+	if err := d.useMux(); err != nil {
+		return 0, err
+	}
+
+	link := -1
+	for i, used := range d.links {
+		if !used {
+			link = i
+			break
+		}
+	}
+	if link < 0 {
+		return 0, net.ErrNoSocket
+	}
+
+	// the link is in use before it connects, so that its CONNECT message is
+	// not taken for an incoming connection
+	d.links[link] = true
+	d.closed[link] = false
+	d.socketdata[link] = d.socketdata[link][:0]
+	err := d.Set(TCPConnect, strconv.Itoa(link)+","+val)
+	if err == nil {
+		_, err = d.Response(timeout)
+	}
 	if err != nil {
-		return err
+		d.links[link] = false
+		return 0, err
 	}
-	_, e := d.Response(3000)
-	if e != nil {
-		return e
+	return net.Socket(link), nil
+}
+
+// useMux puts the ESP8266/ESP32 in multiple connection mode, which is needed
+// to use link IDs.
+func (d *Device) useMux() error {
+	if d.mux {
+		return nil
 	}
+	if err := d.SetMux(TCPMuxMultiple); err != nil {
+		return err
+	}
+	d.mux = true
 	return nil
 }
 
-// ConnectUDPSocket creates a new UDP connection for the ESP8266/ESP32.
-func (d *Device) ConnectUDPSocket(addr, sendport, listenport string) error {
-	protocol := "UDP"
-	val := "\"" + protocol + "\",\"" + addr + "\"," + sendport + "," + listenport + ",2"
-	err := d.Set(TCPConnect, val)
+// ListenTCPSocket starts the TCP server of the ESP8266/ESP32 on the given port.
+// There can only be one server, and its connections use the same link IDs
+// as the client connections.
+func (d *Device) ListenTCPSocket(port string) (net.Socket, error) {
+	if d.server {
+		return 0, ErrServerStarted
+	}
+	if err := d.useMux(); err != nil {
+		return 0, err
+	}
+	err := d.Set(ServerConfig, "1,"+port)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, e := d.Response(3000)
-	if e != nil {
-		return e
+	_, err = d.Response(pause)
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	d.server = true
+	return serverSocket, nil
 }
 
-// ConnectSSLSocket creates a new SSL socket connection for the ESP8266/ESP32.
-// Currently only supports single connection mode.
-func (d *Device) ConnectSSLSocket(addr, port string) error {
-	protocol := "SSL"
-	val := "\"" + protocol + "\",\"" + addr + "\"," + port + ",120"
-	d.Set(TCPConnect, val)
-	// this operation takes longer, so wait up to 6 seconds to complete.
-	_, err := d.Response(6000)
+// AcceptSocket returns the link ID of a new connection to the TCP server.
+func (d *Device) AcceptSocket(listener net.Socket) (net.Socket, error) {
+	if listener != serverSocket || !d.server {
+		return 0, ErrInvalidLink
+	}
+
+	// read in any connection messages
+	if d.bus.Buffered() > 0 {
+		d.Response(100)
+	}
+
+	for i, pending := range d.pending {
+		if pending {
+			d.pending[i] = false
+			d.links[i] = true
+			d.closed[i] = false
+			return net.Socket(i), nil
+		}
+	}
+	return 0, net.ErrNoConnection
+}
+
+// stopServer stops the TCP server of the ESP8266/ESP32, which also closes
+// its connections.
+func (d *Device) stopServer() error {
+	d.server = false
+	d.pending = [MaxLinks]bool{}
+	err := d.Set(ServerConfig, "0,1")
 	if err != nil {
 		return err
 	}
-	return nil
+	_, err = d.Response(pause)
+	return err
+}
+
+// isOpen returns whether the link is connected.
+func (d *Device) isOpen(sock net.Socket) bool {
+	return sock >= 0 && sock < MaxLinks && d.links[sock]
 }
 
-// DisconnectSocket disconnects the ESP8266/ESP32 from the current TCP/UDP connection.
-func (d *Device) DisconnectSocket() error {
-	err := d.Execute(TCPClose)
+// DisconnectSocket disconnects the ESP8266/ESP32 from the given TCP/UDP connection.
+func (d *Device) DisconnectSocket(sock net.Socket) error {
+	if sock == serverSocket && d.server {
+		return d.stopServer()
+	}
+	if !d.isOpen(sock) {
+		return ErrInvalidLink
+	}
+	d.links[sock] = false
+	if d.closed[sock] {
+		// already closed by the peer
+		d.closed[sock] = false
+		return nil
+	}
+	err := d.Set(TCPClose, strconv.Itoa(int(sock)))
 	if err != nil {
 		return err
 	}
@@ -120,9 +234,10 @@ func (d *Device) GetTCPTransferMode() ([]byte, error) {
 	return d.Response(pause)
 }
 
-// StartSocketSend gets the ESP8266/ESP32 ready to receive TCP/UDP socket data.
-func (d *Device) StartSocketSend(size int) error {
-	val := strconv.Itoa(size)
+// StartSocketSend gets the ESP8266/ESP32 ready to receive TCP/UDP socket data
+// for the given link.
+func (d *Device) StartSocketSend(sock net.Socket, size int) error {
+	val := strconv.Itoa(int(sock)) + "," + strconv.Itoa(size)
 	d.Set(TCPSend, val)
 
 	// when ">" is received, it indicates
@@ -137,6 +252,25 @@ func (d *Device) StartSocketSend(size int) error {
 	return errors.New("StartSocketSend error:" + string(r))
 }
 
+// WriteSocket sends data to the given TCP/UDP connection.
+func (d *Device) WriteSocket(sock net.Socket, b []byte) (n int, err error) {
+	if !d.isOpen(sock) {
+		return 0, ErrInvalidLink
+	}
+	// specify that is a data transfer to the
+	// socket, not commands to the ESP8266/ESP32.
+	err = d.StartSocketSend(sock, len(b))
+	if err != nil {
+		return 0, err
+	}
+	n, err = d.Write(b)
+	if err != nil {
+		return n, err
+	}
+	_, err = d.Response(1000)
+	return n, err
+}
+
 // EndSocketSend tell the ESP8266/ESP32 the TCP/UDP socket data sending is complete,
 // and to return to command mode. This is only used in "unvarnished" raw mode.
 func (d *Device) EndSocketSend() error {
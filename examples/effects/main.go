@@ -0,0 +1,43 @@
+// Plays LED effects on a WS2812 strip with 10 LEDs, switching to the next
+// effect every 5 seconds.
+package main
+
+import (
+	"image/color"
+	"machine"
+	"time"
+
+	"tinygo.org/x/drivers/effects"
+	"tinygo.org/x/drivers/ws2812"
+)
+
+// Replace neo to match the pin that you are using if different.
+var neo machine.Pin = machine.NEOPIXELS
+
+func main() {
+	neo.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	ws := ws2812.New(neo)
+	ws.Gamma = true
+	ws.SetBrightness(64)
+
+	all := []effects.Effect{
+		effects.Rainbow(2 * time.Second),
+		effects.Fire(40*time.Millisecond, 1),
+		effects.Chase(color.RGBA{R: 0xff}, color.RGBA{B: 0x20}, 3, 80*time.Millisecond),
+		effects.Fade(color.RGBA{G: 0xff}, color.RGBA{R: 0xff, B: 0xff}, 3*time.Second),
+	}
+
+	player := effects.NewPlayer(ws, 10, 50)
+	player.Play(all[0])
+	next := 1
+	then := time.Now()
+	for {
+		if time.Since(then) > 5*time.Second {
+			then = time.Now()
+			player.CrossfadeTo(all[next], time.Second)
+			next = (next + 1) % len(all)
+		}
+		player.Step()
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"machine"
+	"strconv"
+	"time"
+
+	"tinygo.org/x/drivers/hd44780/widget"
+	"tinygo.org/x/drivers/hd44780i2c"
+)
+
+func main() {
+
+	machine.I2C0.Configure(machine.I2CConfig{
+		Frequency: machine.TWI_FREQ_400KHZ,
+	})
+
+	lcd := hd44780i2c.New(machine.I2C0, 0x27)
+
+	lcd.Configure(hd44780i2c.Config{
+		Width:  20,
+		Height: 4,
+	})
+
+	screen := widget.New(&lcd)
+
+	for i := 0; ; i++ {
+		value := i % 1000
+
+		// large numerals on the first 2 rows, a bar graph on the last one
+		screen.BigText(0, 0, strconv.Itoa(1000 + value)[1:])
+		screen.HBar(0, 3, 20, value, 999)
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -24,6 +24,7 @@ func main() {
 		Height:      2,  // required
 		CursorOn:    true,
 		CursorBlink: true,
+		// Expander: hd44780i2c.MCP23008, // for the Adafruit I2C / SPI backpack
 	})
 
 	lcd.Print([]byte(" TinyGo\n  LCD Test "))
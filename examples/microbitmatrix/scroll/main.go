@@ -0,0 +1,28 @@
+package main
+
+import (
+	"time"
+
+	"tinygo.org/x/drivers/microbitmatrix"
+)
+
+var display microbitmatrix.Device
+
+func main() {
+	display = microbitmatrix.New()
+	display.Configure(microbitmatrix.Config{Background: true})
+
+	for {
+		display.ScrollText("Hello, TinyGo!", 120*time.Millisecond)
+
+		// fade in a smiley
+		for level := uint8(1); level <= microbitmatrix.BrightnessMax; level++ {
+			for _, p := range [][2]int16{{1, 1}, {3, 1}, {0, 3}, {1, 4}, {2, 4}, {3, 4}, {4, 3}} {
+				display.SetBrightness(p[0], p[1], level)
+			}
+			time.Sleep(150 * time.Millisecond)
+		}
+		time.Sleep(time.Second)
+		display.ClearDisplay()
+	}
+}
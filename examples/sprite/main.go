@@ -0,0 +1,99 @@
+// Bounces a few sprites over a checkerboard tile map, redrawing only the
+// areas of the screen that changed.
+package main
+
+import (
+	"machine"
+
+	"tinygo.org/x/drivers/ili9341"
+	"tinygo.org/x/drivers/sprite"
+)
+
+const (
+	tileSize = 16
+	ballSize = 12
+)
+
+var (
+	tiles = &sprite.Tileset{
+		Width:  tileSize,
+		Height: tileSize,
+		Pixels: make([]uint16, 2*tileSize*tileSize),
+	}
+	ball = &sprite.Image{
+		Width:       ballSize,
+		Height:      ballSize,
+		Pixels:      make([]uint16, ballSize*ballSize),
+		Transparent: true,
+		Key:         0x0000,
+	}
+)
+
+func main() {
+	backlight.Configure(machine.PinConfig{machine.PinOutput})
+	display.Configure(ili9341.Config{})
+	display.SetRotation(ili9341.Rotation270)
+	backlight.High()
+
+	// two plain tiles
+	for i := range tiles.Pixels {
+		if i < tileSize*tileSize {
+			tiles.Pixels[i] = 0xAD75
+		} else {
+			tiles.Pixels[i] = 0xA815
+		}
+	}
+
+	// a round ball, the corners are transparent
+	for y := 0; y < ballSize; y++ {
+		for x := 0; x < ballSize; x++ {
+			dx, dy := 2*x-ballSize+1, 2*y-ballSize+1
+			if dx*dx+dy*dy <= ballSize*ballSize {
+				ball.Pixels[y*ballSize+x] = 0xF800
+			}
+		}
+	}
+
+	engine := sprite.New(display, sprite.Config{StripHeight: 16})
+	w, h := engine.Size()
+
+	cols, rows := w/tileSize, h/tileSize
+	cells := make([]uint16, int(cols)*int(rows))
+	for i := range cells {
+		cells[i] = uint16((i%int(cols) + i/int(cols)) % 2)
+	}
+	engine.SetTileMap(&sprite.TileMap{Columns: cols, Map: cells, Tileset: tiles})
+
+	type mover struct {
+		s      *sprite.Sprite
+		vx, vy int16
+	}
+	movers := []mover{
+		{sprite.NewSprite(ball, 10, 20), 2, 1},
+		{sprite.NewSprite(ball, 100, 50), -1, 2},
+		{sprite.NewSprite(ball, 200, 150), 3, -2},
+	}
+	for i := range movers {
+		movers[i].s.Z = int8(i)
+		engine.AddSprite(movers[i].s)
+	}
+
+	for {
+		for i := range movers {
+			m := &movers[i]
+			x, y := m.s.X+m.vx, m.s.Y+m.vy
+			if x < 0 || x+ballSize > w {
+				m.vx = -m.vx
+				x = m.s.X
+			}
+			if y < 0 || y+ballSize > h {
+				m.vy = -m.vy
+				y = m.s.Y
+			}
+			m.s.MoveTo(x, y)
+		}
+		if err := engine.Update(); err != nil {
+			println(err.Error())
+		}
+	}
+}
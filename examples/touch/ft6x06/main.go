@@ -0,0 +1,33 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	"tinygo.org/x/drivers/touch/ft6x06"
+)
+
+func main() {
+
+	machine.I2C0.Configure(machine.I2CConfig{})
+
+	// the INT pin of the controller on D7
+	irq := machine.D7
+	irq.Configure(machine.PinConfig{Mode: machine.PinInputPullup})
+
+	touchscreen := ft6x06.New(machine.I2C0)
+	if !touchscreen.Connected() {
+		println("FT6x06 not detected")
+		return
+	}
+	touchscreen.Configure(ft6x06.Config{Interrupt: irq})
+
+	var touches [ft6x06.MaxTouches]ft6x06.Touch
+	for {
+		n, _ := touchscreen.ReadTouches(touches[:])
+		for _, t := range touches[:n] {
+			println("touch", t.ID, ":", t.X, t.Y)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
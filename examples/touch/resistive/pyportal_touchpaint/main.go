@@ -3,7 +3,6 @@ package main
 import (
 	"image/color"
 	"machine"
-	"math"
 
 	"tinygo.org/x/drivers/ili9341"
 	"tinygo.org/x/drivers/touch"
@@ -38,11 +37,12 @@ var (
 const (
 	penRadius = 3
 	boxSize   = 30
+)
 
-	Xmin = 750
-	Xmax = 325
-	Ymin = 840
-	Ymax = 240
+// calibration maps the corners of the display to the raw touchscreen readings
+var calibration, _ = touch.NewCalibration(
+	[3]touch.Point{{X: 0, Y: 0}, {X: 240, Y: 0}, {X: 0, Y: 320}},
+	[3]touch.Point{{X: 750 << 6, Y: 840 << 6}, {X: 325 << 6, Y: 840 << 6}, {X: 750 << 6, Y: 240 << 6}},
 )
 
 func main() {
@@ -82,51 +82,24 @@ func main() {
 	currentColor = red
 	display.DrawRectangle(0, 0, boxSize, boxSize, white)
 
-	last := touch.Point{}
+	// filter the touches, with the readings mapped to display coordinates
+	touchscreen := touch.NewFilter(resistiveTouch, touch.FilterConfig{
+		Calibration:      &calibration,
+		Width:            int(width),
+		Height:           int(height),
+		PressThreshold:   100 << 6,
+		ReleaseThreshold: 80 << 6,
+		Jitter:           4 << 6,
+	})
 
-	// loop and poll for touches, including performing debouncing
-	debounce := 0
+	// loop and poll for touches
 	for {
-
-		point := resistiveTouch.ReadTouchPoint()
-		touch := touch.Point{}
-		if point.Z>>6 > 100 {
-			rawX := mapval(point.X>>6, Xmin, Xmax, 0, 240)
-			rawY := mapval(point.Y>>6, Ymin, Ymax, 0, 320)
-			touch.X = rawX
-			touch.Y = rawY
-			touch.Z = 1
-		} else {
-			touch.X = 0
-			touch.Y = 0
-			touch.Z = 0
-		}
-
-		if last.Z != touch.Z {
-			debounce = 0
-			last = touch
-		} else if math.Abs(float64(touch.X-last.X)) > 4 ||
-			math.Abs(float64(touch.Y-last.Y)) > 4 {
-			debounce = 0
-			last = touch
-		} else if debounce > 1 {
-			debounce = 0
-			HandleTouch(last)
-		} else if touch.Z > 0 {
-			debounce++
-		} else {
-			last = touch
-			debounce = 0
+		if point := touchscreen.ReadTouchPoint(); point.Z > 0 {
+			HandleTouch(point)
 		}
-
 	}
 }
 
-// based on Arduino's "map" function
-func mapval(x int, inMin int, inMax int, outMin int, outMax int) int {
-	return (x-inMin)*(outMax-outMin)/(inMax-inMin) + outMin
-}
-
 func HandleTouch(touch touch.Point) {
 
 	if int16(touch.Y) < boxSize {
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	"tinygo.org/x/drivers/touch/xpt2046"
+)
+
+func main() {
+
+	machine.SPI0.Configure(machine.SPIConfig{
+		Frequency: 2000000,
+	})
+
+	// chip select on D6 and PENIRQ on D7
+	touchscreen := xpt2046.New(machine.SPI0, machine.D6, machine.D7)
+	touchscreen.Configure()
+
+	for {
+		point := touchscreen.ReadTouchPoint()
+		if point.Z > 0 {
+			println("touch point:", point.X, point.Y, point.Z)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
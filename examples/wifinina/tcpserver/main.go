@@ -0,0 +1,112 @@
+// This example listens for TCP connections using a device with WiFiNINA
+// firmware, and echoes back the data sent by each client.
+//
+// You can connect to this program using:
+//
+// nc <ip address of the device> 8080
+//
+package main
+
+import (
+	"machine"
+	"time"
+
+	"tinygo.org/x/drivers/net"
+	"tinygo.org/x/drivers/wifinina"
+)
+
+// access point info
+const ssid = ""
+const pass = ""
+
+var (
+
+	// these are the default pins for the Arduino Nano33 IoT.
+	spi = machine.NINA_SPI
+
+	// this is the ESP chip that has the WIFININA firmware flashed on it
+	adaptor = &wifinina.Device{
+		SPI:   spi,
+		CS:    machine.NINA_CS,
+		ACK:   machine.NINA_ACK,
+		GPIO0: machine.NINA_GPIO0,
+		RESET: machine.NINA_RESETN,
+	}
+)
+
+func main() {
+
+	// Configure SPI for 8Mhz, Mode 0, MSB First
+	spi.Configure(machine.SPIConfig{
+		Frequency: 8 * 1e6,
+		SDO:       machine.NINA_SDO,
+		SDI:       machine.NINA_SDI,
+		SCK:       machine.NINA_SCK,
+	})
+
+	adaptor.Configure()
+
+	connectToAP()
+
+	message("Listening on port 8080")
+	l, err := net.Listen("tcp", ":8080")
+	for ; err != nil; l, err = net.Listen("tcp", ":8080") {
+		message(err.Error())
+		time.Sleep(5 * time.Second)
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			message(err.Error())
+			continue
+		}
+		message("Accepted connection")
+		go echo(conn)
+	}
+}
+
+// echo writes back everything read from the connection.
+func echo(conn net.Conn) {
+	var buf [64]byte
+	for {
+		n, err := conn.Read(buf[:])
+		if err != nil {
+			message(err.Error())
+			break
+		}
+		if n == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		if _, err := conn.Write(buf[:n]); err != nil {
+			message(err.Error())
+			break
+		}
+	}
+	conn.Close()
+}
+
+// connect to access point
+func connectToAP() {
+	time.Sleep(2 * time.Second)
+	message("Connecting to " + ssid)
+	adaptor.SetPassphrase(ssid, pass)
+	for st, _ := adaptor.GetConnectionStatus(); st != wifinina.StatusConnected; {
+		message("Connection status: " + st.String())
+		time.Sleep(1 * time.Second)
+		st, _ = adaptor.GetConnectionStatus()
+	}
+	message("Connected.")
+	time.Sleep(2 * time.Second)
+	ip, _, _, err := adaptor.GetIP()
+	for ; err != nil; ip, _, _, err = adaptor.GetIP() {
+		message(err.Error())
+		time.Sleep(1 * time.Second)
+	}
+	message(ip.String())
+}
+
+func message(msg string) {
+	println(msg, "\r")
+}
@@ -1,5 +1,5 @@
-// This example opens a TCP connection using a device with WiFiNINA firmware
-// and sends a HTTP request to retrieve a webpage, based on the following
+// This example sends a HTTP request using a device with WiFiNINA firmware
+// to retrieve a webpage, based on the following
 // Arduino example:
 //
 // https://github.com/arduino-libraries/WiFiNINA/blob/master/examples/WiFiWebClientRepeating/
@@ -7,11 +7,11 @@
 package main
 
 import (
-	"fmt"
+	"io"
 	"machine"
 	"time"
 
-	"tinygo.org/x/drivers/net"
+	"tinygo.org/x/drivers/net/http"
 	"tinygo.org/x/drivers/wifinina"
 )
 
@@ -19,7 +19,7 @@ import (
 const ssid = ""
 const pass = ""
 
-// IP address of the server aka "hub". Replace with your own info.
+// host name of the web server. Replace with your own info.
 const server = "tinygo.org"
 
 // these are the default pins for the Arduino Nano33 IoT.
@@ -46,8 +46,9 @@ var (
 
 var buf [256]byte
 
-var lastRequestTime time.Time
-var conn net.Conn
+// the connection may not be reported closed by the server, so the requests
+// time out after the response.
+var client = &http.Client{Timeout: 10 * time.Second}
 
 func main() {
 
@@ -66,66 +67,40 @@ func main() {
 	connectToAP()
 
 	for {
-		loop()
-	}
-	println("Done.")
-}
-
-func loop() {
-	if conn != nil {
-		for n, err := conn.Read(buf[:]); n > 0; n, err = conn.Read(buf[:]) {
-			if err != nil {
-				println("Read error: " + err.Error())
-			} else {
-				print(string(buf[0:n]))
-			}
-		}
-	}
-	if time.Now().Sub(lastRequestTime).Milliseconds() >= 10000 {
 		makeHTTPRequest()
+		time.Sleep(10 * time.Second)
 	}
+	println("Done.")
 }
 
 func makeHTTPRequest() {
-
-	var err error
-	if conn != nil {
-		conn.Close()
+	message("\r\n---------------\r\nSending HTTP request...")
+	resp, err := client.Get("http://" + server + "/")
+	if err != nil {
+		message("request failed: " + err.Error())
+		return
 	}
+	defer resp.Body.Close()
 
-	// make TCP connection
-	ip := net.ParseIP(server)
-	raddr := &net.TCPAddr{IP: ip, Port: 80}
-	laddr := &net.TCPAddr{Port: 8080}
-
-	message("\r\n---------------\r\nDialing TCP connection")
-	conn, err = net.DialTCP("tcp", laddr, raddr)
-	for ; err != nil; conn, err = net.DialTCP("tcp", laddr, raddr) {
-		message("connection failed: " + err.Error())
-		time.Sleep(5 * time.Second)
+	message(resp.Proto + " " + resp.Status)
+	for key, values := range resp.Header {
+		for _, v := range values {
+			message(key + ": " + v)
+		}
 	}
-	println("Connected!\r")
-
-	print("Sending HTTP request...")
-	fmt.Fprintln(conn, "GET / HTTP/1.1")
-	fmt.Fprintln(conn, "Host:", server)
-	fmt.Fprintln(conn, "User-Agent: TinyGo/0.10.0")
-	fmt.Fprintln(conn, "Connection: close")
-	fmt.Fprintln(conn)
-	println("Sent!\r\n\r")
+	message("")
 
-	lastRequestTime = time.Now()
-}
-
-func readLine(conn *net.TCPSerialConn) string {
-	println("Attempting to read...\r")
-	b := buf[:]
-	for expiry := time.Now().Unix() + 10; time.Now().Unix() > expiry; {
-		if n, err := conn.Read(b); n > 0 && err == nil {
-			return string(b[0:n])
+	// stream the body
+	for {
+		n, err := resp.Body.Read(buf[:])
+		print(string(buf[:n]))
+		if err != nil {
+			if err != io.EOF {
+				message("\r\nRead error: " + err.Error())
+			}
+			break
 		}
 	}
-	return ""
 }
 
 // connect to access point
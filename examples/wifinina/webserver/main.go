@@ -0,0 +1,168 @@
+// This example provisions the Wi-Fi credentials of a device with WiFiNINA
+// firmware through a configuration page, based on the following Arduino
+// example:
+//
+// https://github.com/arduino-libraries/WiFiNINA/blob/master/examples/AP_SimpleWebServer/
+//
+// The device starts an access point; join it, and browse to
+// http://192.168.4.1/ to enter the SSID and passphrase of your network. The
+// device then connects to your network, and keeps serving its status page and
+// a small JSON API at /api/status on its new IP address.
+//
+package main
+
+import (
+	"io"
+	"machine"
+	"strconv"
+	"time"
+
+	"tinygo.org/x/drivers/net"
+	"tinygo.org/x/drivers/net/http"
+	"tinygo.org/x/drivers/wifinina"
+)
+
+// access point info
+const apSSID = "tinygo-setup"
+const apPass = "tinygo123"
+
+var (
+
+	// these are the default pins for the Arduino Nano33 IoT.
+	spi = machine.NINA_SPI
+
+	// this is the ESP chip that has the WIFININA firmware flashed on it
+	adaptor = &wifinina.Device{
+		SPI:   spi,
+		CS:    machine.NINA_CS,
+		ACK:   machine.NINA_ACK,
+		GPIO0: machine.NINA_GPIO0,
+		RESET: machine.NINA_RESETN,
+	}
+)
+
+// the credentials entered on the configuration page
+var ssid, pass string
+
+const setupPage = `<!DOCTYPE html>
+<html><head><title>TinyGo setup</title></head><body>
+<h1>Wi-Fi setup</h1>
+<form method="POST" action="/connect">
+<p>Network: <input name="ssid"></p>
+<p>Passphrase: <input name="pass" type="password"></p>
+<p><input type="submit" value="Connect"></p>
+</form>
+</body></html>
+`
+
+const connectingPage = `<!DOCTYPE html>
+<html><body><h1>Connecting...</h1>
+<p>Join your network again to reach the device.</p>
+</body></html>
+`
+
+func main() {
+
+	// Configure SPI for 8Mhz, Mode 0, MSB First
+	spi.Configure(machine.SPIConfig{
+		Frequency: 8 * 1e6,
+		SDO:       machine.NINA_SDO,
+		SDI:       machine.NINA_SDI,
+		SCK:       machine.NINA_SCK,
+	})
+
+	adaptor.Configure()
+
+	startAP()
+
+	// serve the configuration page until credentials are entered
+	setup := http.NewServeMux()
+	setup.Handle("/", http.StaticHandler("text/html", setupPage))
+	setup.HandleFunc("/connect", handleConnect)
+	setup.HandleFunc("/api/status", handleStatus)
+	srv := &http.Server{Handler: setup, ReadTimeout: 5 * time.Second}
+
+	l, err := net.Listen("tcp", ":80")
+	for ; err != nil; l, err = net.Listen("tcp", ":80") {
+		message(err.Error())
+		time.Sleep(5 * time.Second)
+	}
+	for ssid == "" {
+		// stop serving every second to check for the credentials
+		l.(*net.TCPListener).SetDeadline(time.Now().Add(time.Second))
+		srv.Serve(l)
+	}
+	l.Close()
+
+	connectToAP()
+
+	// keep serving the status
+	status := http.NewServeMux()
+	status.HandleFunc("/", handleStatus)
+	srv.Handler = status
+	for {
+		message(srv.ListenAndServe().Error())
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func handleConnect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.PostFormValue("ssid") == "" {
+		http.Error(w, "missing network", http.StatusBadRequest)
+		return
+	}
+	ssid, pass = r.PostFormValue("ssid"), r.PostFormValue("pass")
+	w.Header().Set("Content-Type", "text/html")
+	io.WriteString(w, connectingPage)
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	st, _ := adaptor.GetConnectionStatus()
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, `{"status":`+strconv.Quote(st.String())+`,"ssid":`+strconv.Quote(ssid)+`}`)
+}
+
+// start the access point
+func startAP() {
+	time.Sleep(2 * time.Second)
+	message("Starting access point " + apSSID)
+	adaptor.SetPassphraseForAP(apSSID, apPass)
+	for st, _ := adaptor.GetConnectionStatus(); st != wifinina.StatusAPListening; {
+		message("Connection status: " + st.String())
+		time.Sleep(1 * time.Second)
+		st, _ = adaptor.GetConnectionStatus()
+	}
+	message("Browse to http://192.168.4.1/")
+}
+
+// connect to access point
+func connectToAP() {
+	time.Sleep(2 * time.Second)
+	message("Connecting to " + ssid)
+	adaptor.SetPassphrase(ssid, pass)
+	for st, _ := adaptor.GetConnectionStatus(); st != wifinina.StatusConnected; {
+		message("Connection status: " + st.String())
+		time.Sleep(1 * time.Second)
+		st, _ = adaptor.GetConnectionStatus()
+	}
+	message("Connected.")
+	time.Sleep(2 * time.Second)
+	ip, _, _, err := adaptor.GetIP()
+	for ; err != nil; ip, _, _, err = adaptor.GetIP() {
+		message(err.Error())
+		time.Sleep(1 * time.Second)
+	}
+	message("Browse to http://" + ip.String() + "/")
+}
+
+func message(msg string) {
+	println(msg, "\r")
+}
@@ -0,0 +1,261 @@
+// Package charset translates UTF-8 text to the character codes of the HD44780
+// character ROMs.
+//
+// The HD44780 comes with one of two character ROMs: A00 (Japanese, with
+// katakana and some Greek letters) and A02 (European, with the Latin-1
+// letters). Characters missing from the ROM are created in the 8 custom
+// character slots of the CGRAM when a glyph is known for them, reusing the
+// least recently used slot when they are all taken. Other characters are
+// replaced by a substitute character.
+//
+// Note that a custom character slot holds a single glyph: when a slot is
+// reused, the characters already shown with it change as well.
+package charset // import "tinygo.org/x/drivers/hd44780/charset"
+
+import "unicode/utf8"
+
+// ROM is the character ROM of a HD44780 controller.
+type ROM uint8
+
+const (
+	// A00 is the Japanese character ROM, the most common one.
+	A00 ROM = iota
+
+	// A02 is the European character ROM.
+	A02
+)
+
+// Slots is the number of custom characters of the CGRAM.
+const Slots = 8
+
+// Glyph is a custom character of 5x8 pixels: one byte per row, with the
+// leftmost pixel in bit 4.
+type Glyph [8]uint8
+
+// glyph is the glyph of a character.
+type glyph struct {
+	r rune
+	g Glyph
+}
+
+// Translator translates text to character codes.
+type Translator struct {
+	// Substitute is the character code used for the characters that cannot
+	// be shown, '?' by default.
+	Substitute byte
+
+	rom    ROM
+	create func(slot uint8, data []byte)
+	glyphs []glyph       // glyphs added by Define
+	slots  [Slots]rune   // character in each slot, 0 for a free slot, -1 if reserved
+	used   [Slots]uint32 // time of the last use of each slot
+	clock  uint32
+}
+
+// New returns a translator for the given ROM. The create function is called
+// to store a glyph in a custom character slot, from 0 to 7, for instance with
+// the CreateCharacter method of a driver.
+func New(rom ROM, create func(slot uint8, data []byte)) *Translator {
+	return &Translator{
+		Substitute: '?',
+		rom:        rom,
+		create:     create,
+	}
+}
+
+// Define adds a glyph for a character missing from the ROM, or replaces a
+// built-in one.
+func (t *Translator) Define(r rune, g Glyph) {
+	found := false
+	for i := range t.glyphs {
+		if t.glyphs[i].r == r {
+			t.glyphs[i].g = g
+			found = true
+		}
+	}
+	if !found {
+		t.glyphs = append(t.glyphs, glyph{r, g})
+	}
+	// a slot holding the old glyph is out of date
+	for i, s := range t.slots {
+		if s == r {
+			t.slots[i] = 0
+		}
+	}
+}
+
+// Reserve keeps a custom character slot for a character created by the
+// application, so that it is not reused for other characters.
+func (t *Translator) Reserve(slot uint8) {
+	if slot < Slots {
+		t.slots[slot] = -1
+	}
+}
+
+// Translate appends the character codes of the UTF-8 text to dst and returns
+// the extended buffer. Control characters, including the custom character
+// codes 0 to 7, and bytes that are not valid UTF-8 are passed through
+// unchanged, so raw character codes can still be used.
+//
+// The custom characters used by the text are kept in the CGRAM until the end
+// of the text: if the text needs more than 8 of them, the others are replaced
+// by the substitute character.
+func (t *Translator) Translate(dst []byte, text []byte) []byte {
+	t.clock++
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		if r == utf8.RuneError && size <= 1 {
+			// not UTF-8, keep the raw byte
+			dst = append(dst, text[0])
+			text = text[1:]
+			continue
+		}
+		text = text[size:]
+		dst = append(dst, t.code(r))
+	}
+	return dst
+}
+
+// code returns the character code of r.
+func (t *Translator) code(r rune) byte {
+	if r < 0x20 {
+		return byte(r)
+	}
+	if c, ok := romCode(t.rom, r); ok {
+		return c
+	}
+	g := t.glyph(r)
+	if g == nil {
+		return t.Substitute
+	}
+
+	// look for the character in the CGRAM, or for the least recently used
+	// slot that is not needed by the current text
+	lru := -1
+	for i, s := range t.slots {
+		if s == r {
+			t.used[i] = t.clock
+			return byte(i)
+		}
+		if s >= 0 && t.used[i] != t.clock && (lru < 0 || t.used[i] < t.used[lru]) {
+			lru = i
+		}
+	}
+	if lru < 0 {
+		return t.Substitute
+	}
+	t.slots[lru] = r
+	t.used[lru] = t.clock
+	if t.create != nil {
+		t.create(uint8(lru), g[:])
+	}
+	return byte(lru)
+}
+
+// glyph returns the glyph defined for r, or the built-in one.
+func (t *Translator) glyph(r rune) *Glyph {
+	for i := range t.glyphs {
+		if t.glyphs[i].r == r {
+			return &t.glyphs[i].g
+		}
+	}
+	for i := range builtinGlyphs {
+		if builtinGlyphs[i].r == r {
+			return &builtinGlyphs[i].g
+		}
+	}
+	return nil
+}
+
+// romCode returns the code of r in the ROM, if it has one.
+func romCode(rom ROM, r rune) (byte, bool) {
+	switch rom {
+	case A02:
+		switch {
+		case r < 0x80:
+			return byte(r), true
+		case r >= 0xa0 && r <= 0xff:
+			// the upper half of the ROM follows the Latin-1 supplement
+			return byte(r), true
+		case r == '⌂':
+			return 0x7f, true
+		}
+	default:
+		switch {
+		case r == '\\' || r == '~':
+			// replaced by ¥ and → in the ROM
+			return 0, false
+		case r < 0x80:
+			return byte(r), true
+		case r >= 0xff61 && r <= 0xff9f:
+			// half-width katakana are in JIS X 0201 order
+			return byte(r - 0xff61 + 0xa1), true
+		}
+		for _, c := range a00Codes {
+			if c.r == r {
+				return c.code, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// a00Codes holds the codes of the A00 ROM for characters outside of ASCII
+// and half-width katakana.
+var a00Codes = [...]struct {
+	r    rune
+	code byte
+}{
+	{'¥', 0x5c},
+	{'→', 0x7e},
+	{'←', 0x7f},
+	{'。', 0xa1},
+	{'「', 0xa2},
+	{'」', 0xa3},
+	{'、', 0xa4},
+	{'・', 0xa5},
+	{'°', 0xdf},
+	{'α', 0xe0},
+	{'ä', 0xe1},
+	{'β', 0xe2},
+	{'ß', 0xe2},
+	{'ε', 0xe3},
+	{'µ', 0xe4}, // micro sign
+	{'μ', 0xe4}, // Greek mu
+	{'σ', 0xe5},
+	{'ρ', 0xe6},
+	{'√', 0xe8},
+	{'¢', 0xec},
+	{'ñ', 0xee},
+	{'ö', 0xef},
+	{'θ', 0xf2},
+	{'∞', 0xf3},
+	{'Ω', 0xf4},
+	{'ü', 0xf5},
+	{'Σ', 0xf6},
+	{'π', 0xf7},
+	{'÷', 0xfd},
+	{'█', 0xff},
+}
+
+// builtinGlyphs holds glyphs for common characters missing from one of the
+// ROMs.
+var builtinGlyphs = [...]glyph{
+	{'\\', Glyph{0x00, 0x10, 0x08, 0x04, 0x02, 0x01, 0x00, 0x00}},
+	{'~', Glyph{0x00, 0x00, 0x08, 0x15, 0x02, 0x00, 0x00, 0x00}},
+	{'°', Glyph{0x0c, 0x12, 0x12, 0x0c, 0x00, 0x00, 0x00, 0x00}},
+	{'Ä', Glyph{0x0a, 0x00, 0x0e, 0x11, 0x1f, 0x11, 0x11, 0x00}},
+	{'Ö', Glyph{0x0a, 0x00, 0x0e, 0x11, 0x11, 0x11, 0x0e, 0x00}},
+	{'Ü', Glyph{0x0a, 0x00, 0x11, 0x11, 0x11, 0x11, 0x0e, 0x00}},
+	{'à', Glyph{0x08, 0x04, 0x0e, 0x01, 0x0f, 0x11, 0x0f, 0x00}},
+	{'ç', Glyph{0x00, 0x0e, 0x10, 0x10, 0x11, 0x0e, 0x04, 0x0c}},
+	{'è', Glyph{0x08, 0x04, 0x0e, 0x11, 0x1f, 0x10, 0x0e, 0x00}},
+	{'é', Glyph{0x02, 0x04, 0x0e, 0x11, 0x1f, 0x10, 0x0e, 0x00}},
+	{'€', Glyph{0x06, 0x09, 0x1c, 0x08, 0x1c, 0x09, 0x06, 0x00}},
+	{'←', Glyph{0x00, 0x04, 0x08, 0x1f, 0x08, 0x04, 0x00, 0x00}},
+	{'↑', Glyph{0x04, 0x0e, 0x15, 0x04, 0x04, 0x04, 0x04, 0x00}},
+	{'→', Glyph{0x00, 0x04, 0x02, 0x1f, 0x02, 0x04, 0x00, 0x00}},
+	{'↓', Glyph{0x04, 0x04, 0x04, 0x04, 0x15, 0x0e, 0x04, 0x00}},
+	{'≤', Glyph{0x02, 0x04, 0x08, 0x04, 0x02, 0x00, 0x0e, 0x00}},
+	{'≥', Glyph{0x08, 0x04, 0x02, 0x04, 0x08, 0x00, 0x0e, 0x00}},
+}
@@ -0,0 +1,78 @@
+package charset
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+type created struct {
+	slot uint8
+	data []byte
+}
+
+func newTranslator(rom ROM) (*Translator, *[]created) {
+	var log []created
+	t := New(rom, func(slot uint8, data []byte) {
+		log = append(log, created{slot, append([]byte(nil), data...)})
+	})
+	return t, &log
+}
+
+func TestROM(t *testing.T) {
+	c := qt.New(t)
+	tr, log := newTranslator(A00)
+	c.Assert(tr.Translate(nil, []byte("25°C 3µs ä¥")), qt.DeepEquals, []byte("25\xdfC 3\xe4s \xe1\x5c"))
+	c.Assert(tr.Translate(nil, []byte("ｱｲｳ")), qt.DeepEquals, []byte{0xb1, 0xb2, 0xb3})
+	c.Assert(*log, qt.HasLen, 0)
+
+	tr, log = newTranslator(A02)
+	c.Assert(tr.Translate(nil, []byte("25°C 3µs ä\\")), qt.DeepEquals, []byte("25\xb0C 3\xb5s \xe4\\"))
+	c.Assert(*log, qt.HasLen, 0)
+}
+
+func TestRawBytes(t *testing.T) {
+	c := qt.New(t)
+	tr, _ := newTranslator(A00)
+	c.Assert(tr.Translate(nil, []byte{0x00, 0x07, '\n', 0xdf, 'a'}), qt.DeepEquals, []byte{0x00, 0x07, '\n', 0xdf, 'a'})
+}
+
+func TestCustomCharacters(t *testing.T) {
+	c := qt.New(t)
+	tr, log := newTranslator(A00)
+	c.Assert(tr.Translate(nil, []byte("é€é")), qt.DeepEquals, []byte{0, 1, 0})
+	c.Assert(*log, qt.HasLen, 2)
+	c.Assert((*log)[0].slot, qt.Equals, uint8(0))
+	c.Assert((*log)[0].data, qt.DeepEquals, []byte{0x02, 0x04, 0x0e, 0x11, 0x1f, 0x10, 0x0e, 0x00})
+
+	// already in the CGRAM
+	c.Assert(tr.Translate(nil, []byte("€")), qt.DeepEquals, []byte{1})
+	c.Assert(*log, qt.HasLen, 2)
+
+	// unknown characters are substituted
+	tr.Substitute = '*'
+	c.Assert(tr.Translate(nil, []byte("☃")), qt.DeepEquals, []byte("*"))
+}
+
+func TestLRU(t *testing.T) {
+	c := qt.New(t)
+	tr, log := newTranslator(A00)
+	tr.Reserve(0)
+	for i := rune(0); i < 7; i++ {
+		tr.Define(0x2460+i, Glyph{uint8(i)})
+	}
+	// slots 1 to 7 are taken, é was used last
+	c.Assert(tr.Translate(nil, []byte("①②③④⑤⑥")), qt.DeepEquals, []byte{1, 2, 3, 4, 5, 6})
+	c.Assert(tr.Translate(nil, []byte("é")), qt.DeepEquals, []byte{7})
+	c.Assert(tr.Translate(nil, []byte("②③④⑤⑥")), qt.DeepEquals, []byte{2, 3, 4, 5, 6})
+
+	// ⑦ replaces ①, the least recently used
+	*log = nil
+	c.Assert(tr.Translate(nil, []byte("⑦")), qt.DeepEquals, []byte{1})
+	c.Assert(*log, qt.HasLen, 1)
+	c.Assert((*log)[0].slot, qt.Equals, uint8(1))
+	c.Assert((*log)[0].data, qt.DeepEquals, []byte{6, 0, 0, 0, 0, 0, 0, 0})
+
+	// more than 7 characters in a single text
+	c.Assert(tr.Translate(nil, []byte("①②③④⑤⑥⑦é")), qt.DeepEquals, []byte{7, 2, 3, 4, 5, 6, 1, '?'})
+}
@@ -9,6 +9,8 @@ import (
 	"io"
 	"machine"
 	"time"
+
+	"tinygo.org/x/drivers/hd44780/charset"
 )
 
 type Buser interface {
@@ -27,7 +29,9 @@ type Device struct {
 	datalength uint8
 
 	cursor     cursor
+	control    uint8 // Display on/off, cursor and blinking flags
 	busyStatus []byte
+	charset    *charset.Translator
 }
 
 type cursor struct {
@@ -40,6 +44,20 @@ type Config struct {
 	CursorBlink bool
 	CursorOnOff bool
 	Font        uint8
+
+	// CharacterROM is the character ROM of the controller, used to translate
+	// the UTF-8 text given to Write. Defaults to charset.A00.
+	CharacterROM charset.ROM
+}
+
+// New returns a HD44780 driver using bus, for example an I/O expander. The data
+// length is DATA_LENGTH_4BIT or DATA_LENGTH_8BIT, depending on the number of
+// data lines used by bus.
+func New(bus Buser, dataLength uint8) Device {
+	return Device{
+		bus:        bus,
+		datalength: dataLength,
+	}
 }
 
 // NewGPIO4Bit returns 4bit data length HD44780 driver. Datapins are LCD DB pins starting from DB4 to DB7
@@ -74,14 +92,16 @@ func (d *Device) Configure(cfg Config) error {
 	}
 	d.setRowOffsets()
 	d.ClearBuffer()
+	d.charset = charset.New(cfg.CharacterROM, func(slot uint8, data []byte) {
+		d.createCharacter(slot<<3, data)
+	})
 
-	cursor := CURSOR_OFF
+	d.control = DISPLAY_ON | CURSOR_OFF | CURSOR_BLINK_OFF
 	if cfg.CursorOnOff {
-		cursor = CURSOR_ON
+		d.control |= CURSOR_ON
 	}
-	cursorBlink := CURSOR_BLINK_OFF
 	if cfg.CursorBlink {
-		cursorBlink = CURSOR_BLINK_ON
+		d.control |= CURSOR_BLINK_ON
 	}
 	if !(cfg.Font == FONT_5X8 || cfg.Font == FONT_5X10) {
 		cfg.Font = FONT_5X8
@@ -109,23 +129,30 @@ func (d *Device) Configure(cfg Config) error {
 	d.SendCommand(DISPLAY_OFF)
 	d.SendCommand(DISPLAY_CLEAR)
 	d.SendCommand(ENTRY_MODE | CURSOR_INCREASE | DISPLAY_NO_SHIFT)
-	d.SendCommand(DISPLAY_ON | uint8(cursor) | uint8(cursorBlink))
+	d.SendCommand(d.control)
 	return nil
 }
 
-// Write writes data to internal buffer
+// Write writes data to internal buffer. The data is UTF-8 text, translated to
+// the character codes of the character ROM, see package charset.
 func (d *Device) Write(data []byte) (n int, err error) {
-	size := len(data)
+	// The translated text is appended in place to the buffer until it is
+	// full, the rest is dropped.
+	text := d.charset.Translate(d.buffer[:0], data)
+	size := len(text)
 	if size > len(d.buffer) {
 		size = len(d.buffer)
 	}
 	d.bufferLength = uint8(size)
-	for i := uint8(0); i < d.bufferLength; i++ {
-		d.buffer[i] = data[i]
-	}
 	return size, nil
 }
 
+// Charset returns the translator used by Write, to define glyphs for more
+// characters or change the substitute character.
+func (d *Device) Charset() *charset.Translator {
+	return d.charset
+}
+
 // Display sends the whole buffer to the screen at cursor position
 func (d *Device) Display() error {
 
@@ -157,10 +184,27 @@ func (d *Device) Display() error {
 }
 
 // SetCursor moves cursor to position x,y, where (0,0) is top left corner and (width-1, height-1) bottom right
+//
+// If y is larger than the last row, the cursor is moved to the first row.
 func (d *Device) SetCursor(x, y uint8) {
+	if y >= d.height {
+		y = 0
+	}
 	d.cursor.x = x
 	d.cursor.y = y
-	d.SendCommand(DDRAM_SET | (x + (d.rowOffset[y] * y)))
+	d.SendCommand(DDRAM_SET | (x + d.rowOffset[y]))
+}
+
+// Cursor returns the cursor position, which is after the last character sent
+// by Display.
+func (d *Device) Cursor() (x, y uint8) {
+	return d.cursor.x, d.cursor.y
+}
+
+// Home moves the cursor to the top left corner.
+func (d *Device) Home() {
+	d.SendCommand(CURSOR_HOME)
+	d.cursor = cursor{}
 }
 
 // SetRowOffsets sets initial memory addresses coresponding to the display rows
@@ -170,9 +214,9 @@ func (d *Device) SetCursor(x, y uint8) {
 func (d *Device) setRowOffsets() {
 	switch d.height {
 	case 1:
-		d.rowOffset = []uint8{}
+		d.rowOffset = []uint8{0x0}
 	case 2:
-		d.rowOffset = []uint8{0x0, 0x40, 0x0, 0x40}
+		d.rowOffset = []uint8{0x0, 0x40}
 	case 4:
 		d.rowOffset = []uint8{0x0, 0x40, d.width, 0x40 + d.width}
 	default:
@@ -186,7 +230,11 @@ func (d *Device) SendCommand(command byte) {
 	d.bus.SetCommandMode(true)
 	d.bus.Write([]byte{command})
 
-	for d.Busy() {
+	// Clear and home take much longer than the other instructions
+	if command == DISPLAY_CLEAR || command == CURSOR_HOME {
+		d.wait(1600 * time.Microsecond)
+	} else {
+		d.wait(50 * time.Microsecond)
 	}
 }
 
@@ -195,12 +243,34 @@ func (d *Device) sendData(data byte) {
 	d.bus.SetCommandMode(false)
 	d.bus.Write([]byte{data})
 
-	for d.Busy() {
+	d.wait(50 * time.Microsecond)
+}
+
+// wait waits until the display is ready for the next instruction. Buses which
+// can't read the busy flag wait for the execution time of the instruction.
+func (d *Device) wait(delay time.Duration) {
+	d.bus.SetCommandMode(true)
+	if _, err := d.bus.Read(d.busyStatus); err != nil {
+		time.Sleep(delay)
+		return
+	}
+	for d.busyStatus[0]&BUSY != 0 {
+		d.bus.Read(d.busyStatus)
 	}
 }
 
 // CreateCharacter crates characters using data and stores it under cgram Addr in CGRAM
+//
+// The custom character slot is not used anymore for the characters missing
+// from the character ROM.
 func (d *Device) CreateCharacter(cgramAddr uint8, data []byte) {
+	if d.charset != nil {
+		d.charset.Reserve(cgramAddr >> 3)
+	}
+	d.createCharacter(cgramAddr, data)
+}
+
+func (d *Device) createCharacter(cgramAddr uint8, data []byte) {
 	d.SendCommand(CGRAM_SET | cgramAddr)
 	for _, dd := range data {
 		d.sendData(dd)
@@ -219,12 +289,38 @@ func (d *Device) Size() (w, h int16) {
 	return int16(d.width), int16(d.height)
 }
 
-// ClearDisplay clears displayed content and buffer
+// ClearDisplay clears displayed content and buffer and moves the cursor to the
+// top left corner.
 func (d *Device) ClearDisplay() {
 	d.SendCommand(DISPLAY_CLEAR)
+	d.cursor = cursor{}
 	d.ClearBuffer()
 }
 
+// DisplayOn turns on/off the display.
+func (d *Device) DisplayOn(option bool) {
+	d.setControl(DISPLAY_ON, option)
+}
+
+// CursorOn displays/hides the cursor.
+func (d *Device) CursorOn(option bool) {
+	d.setControl(CURSOR_ON, option)
+}
+
+// CursorBlink turns on/off the blinking cursor mode.
+func (d *Device) CursorBlink(option bool) {
+	d.setControl(CURSOR_BLINK_ON, option)
+}
+
+func (d *Device) setControl(flag uint8, option bool) {
+	if option {
+		d.control |= flag
+	} else {
+		d.control &^= flag &^ DISPLAY_ON_OFF
+	}
+	d.SendCommand(d.control)
+}
+
 // ClearBuffer clears internal buffer
 func (d *Device) ClearBuffer() {
 	d.buffer = make([]uint8, d.width*d.height)
@@ -0,0 +1,201 @@
+// Package widget draws large numerals, bar graphs and sparklines on HD44780
+// character displays.
+//
+// The widgets are drawn with custom characters, which are defined as glyphs
+// of the display character set (see package charset) and created in the CGRAM
+// when shown. The widgets share their glyphs where possible:
+//
+//	large numerals   3 custom characters
+//	horizontal bars  4 custom characters
+//	vertical bars    7 custom characters, shared with the sparklines
+//
+// The full block comes from the character ROM with charset.A00, or takes one
+// more custom character otherwise. As there are only 8 custom characters,
+// showing different kinds of widgets at the same time is limited to large
+// numerals with horizontal bars.
+package widget // import "tinygo.org/x/drivers/hd44780/widget"
+
+import (
+	"unicode/utf8"
+
+	"tinygo.org/x/drivers/hd44780/charset"
+)
+
+// Display is a character display, such as hd44780.Device and
+// hd44780i2c.Device.
+type Display interface {
+	SetCursor(x, y uint8)
+	Write(data []byte) (n int, err error)
+	Display() error
+	Charset() *charset.Translator
+}
+
+// Characters of the glyphs, in the Unicode private use area.
+const (
+	block = '█'
+
+	hbar  = 0xE100 // + number of columns, 1 to 4
+	vbar  = 0xE110 // + number of rows, 1 to 7
+	upper = 0xE120 // upper bar of the large numerals
+	lower = 0xE121 // lower bar
+	both  = 0xE122 // upper and lower bar
+)
+
+// bigDigits are the large numerals, 3 characters wide and 2 rows high.
+var bigDigits = [10][2][3]rune{
+	{{block, upper, block}, {block, lower, block}},
+	{{upper, block, ' '}, {lower, block, lower}},
+	{{both, both, block}, {block, lower, lower}},
+	{{both, both, block}, {lower, lower, block}},
+	{{block, lower, block}, {' ', ' ', block}},
+	{{block, both, both}, {lower, lower, block}},
+	{{block, both, both}, {block, lower, block}},
+	{{upper, upper, block}, {' ', ' ', block}},
+	{{block, both, block}, {block, lower, block}},
+	{{block, both, block}, {lower, lower, block}},
+}
+
+// Screen draws widgets on a display.
+type Screen struct {
+	d    Display
+	text []byte
+}
+
+// New returns a screen drawing on d, defining the glyphs of the widgets in
+// the character set of d.
+func New(d Display) *Screen {
+	t := d.Charset()
+	t.Define(block, charset.Glyph{0x1f, 0x1f, 0x1f, 0x1f, 0x1f, 0x1f, 0x1f, 0x1f})
+	for n := 1; n < 5; n++ {
+		columns := uint8(0x1f<<uint(5-n)) & 0x1f
+		t.Define(rune(hbar+n), charset.Glyph{columns, columns, columns, columns, columns, columns, columns, columns})
+	}
+	for n := 1; n < 8; n++ {
+		var g charset.Glyph
+		for i := 8 - n; i < 8; i++ {
+			g[i] = 0x1f
+		}
+		t.Define(rune(vbar+n), g)
+	}
+	t.Define(upper, charset.Glyph{0x1f, 0x1f, 0x1f})
+	t.Define(lower, charset.Glyph{5: 0x1f, 6: 0x1f, 7: 0x1f})
+	t.Define(both, charset.Glyph{0x1f, 0x1f, 0x1f, 0, 0, 0x1f, 0x1f, 0x1f})
+	return &Screen{d: d}
+}
+
+// BigText draws text with large numerals, 2 rows high, with the top left
+// corner at (x, y). The text may hold digits, spaces, '-', '.' and ':', other
+// characters are skipped. It returns the width of the text in characters.
+func (s *Screen) BigText(x, y uint8, text string) uint8 {
+	var rows [2][]rune
+	for _, c := range text {
+		switch {
+		case c >= '0' && c <= '9':
+			d := bigDigits[c-'0']
+			rows[0] = append(rows[0], d[0][:]...)
+			rows[1] = append(rows[1], d[1][:]...)
+		case c == ' ':
+			rows[0] = append(rows[0], ' ', ' ', ' ')
+			rows[1] = append(rows[1], ' ', ' ', ' ')
+		case c == '-':
+			rows[0] = append(rows[0], lower, lower)
+			rows[1] = append(rows[1], ' ', ' ')
+		case c == '.':
+			rows[0] = append(rows[0], ' ')
+			rows[1] = append(rows[1], '.')
+		case c == ':':
+			rows[0] = append(rows[0], '.')
+			rows[1] = append(rows[1], '.')
+		default:
+			continue
+		}
+		// a space between characters
+		rows[0] = append(rows[0], ' ')
+		rows[1] = append(rows[1], ' ')
+	}
+	if len(rows[0]) == 0 {
+		return 0
+	}
+	for i := range rows {
+		s.draw(x, y+uint8(i), rows[i][:len(rows[i])-1]...)
+	}
+	return uint8(len(rows[0]) - 1)
+}
+
+// HBar draws a horizontal bar graph of width characters at (x, y), filled
+// from the left for value in 0 to max, with a resolution of 5 steps per
+// character.
+func (s *Screen) HBar(x, y, width uint8, value, max int) {
+	n := level(value, 0, max, int(width)*5)
+	bar := make([]rune, width)
+	for i := range bar {
+		switch {
+		case n >= 5:
+			bar[i] = block
+		case n > 0:
+			bar[i] = rune(hbar + n)
+		default:
+			bar[i] = ' '
+		}
+		n -= 5
+	}
+	s.draw(x, y, bar...)
+}
+
+// VBar draws a vertical bar graph of height rows with its top at (x, y),
+// filled from the bottom for value in 0 to max, with a resolution of 8 steps
+// per row.
+func (s *Screen) VBar(x, y, height uint8, value, max int) {
+	n := level(value, 0, max, int(height)*8)
+	for i := int(height) - 1; i >= 0; i-- {
+		s.draw(x, y+uint8(i), vbarRune(n))
+		n -= 8
+	}
+}
+
+// Sparkline draws values as a row of single character bars starting at
+// (x, y), scaled from min to max. The lowest values are shown as a one pixel
+// high bar.
+func (s *Screen) Sparkline(x, y uint8, values []int, min, max int) {
+	line := make([]rune, len(values))
+	for i, v := range values {
+		line[i] = vbarRune(1 + level(v, min, max, 7))
+	}
+	s.draw(x, y, line...)
+}
+
+// vbarRune returns the character of a vertical bar of n rows of pixels.
+func vbarRune(n int) rune {
+	switch {
+	case n >= 8:
+		return block
+	case n > 0:
+		return rune(vbar + n)
+	default:
+		return ' '
+	}
+}
+
+// level scales value from min to max to 0 to steps, rounded down.
+func level(value, min, max, steps int) int {
+	if max <= min || value <= min {
+		return 0
+	}
+	if value >= max {
+		return steps
+	}
+	return (value - min) * steps / (max - min)
+}
+
+// draw shows the characters at (x, y).
+func (s *Screen) draw(x, y uint8, chars ...rune) {
+	var b [utf8.UTFMax]byte
+	s.text = s.text[:0]
+	for _, c := range chars {
+		n := utf8.EncodeRune(b[:], c)
+		s.text = append(s.text, b[:n]...)
+	}
+	s.d.SetCursor(x, y)
+	s.d.Write(s.text)
+	s.d.Display()
+}
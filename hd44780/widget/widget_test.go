@@ -0,0 +1,120 @@
+package widget
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"tinygo.org/x/drivers/hd44780/charset"
+)
+
+// display is a 20x4 display keeping the glyphs of the custom characters.
+type display struct {
+	rows   [4][20]byte
+	cgram  [charset.Slots][]byte
+	x, y   uint8
+	text   []byte
+	chrset *charset.Translator
+}
+
+func newDisplay() *display {
+	d := &display{}
+	for i := range d.rows {
+		for j := range d.rows[i] {
+			d.rows[i][j] = ' '
+		}
+	}
+	d.chrset = charset.New(charset.A00, func(slot uint8, data []byte) {
+		d.cgram[slot] = append([]byte(nil), data...)
+	})
+	return d
+}
+
+func (d *display) SetCursor(x, y uint8) { d.x, d.y = x, y }
+
+func (d *display) Write(data []byte) (int, error) {
+	d.text = d.chrset.Translate(d.text[:0], data)
+	return len(d.text), nil
+}
+
+func (d *display) Display() error {
+	for _, c := range d.text {
+		d.rows[d.y][d.x] = c
+		d.x++
+	}
+	return nil
+}
+
+func (d *display) Charset() *charset.Translator { return d.chrset }
+
+// pixels returns the glyph shown at (x, y) as rows of '#' and '.'.
+func (d *display) pixels(x, y int) []string {
+	c := d.rows[y][x]
+	var g []byte
+	switch {
+	case c == 0xff:
+		g = []byte{0x1f, 0x1f, 0x1f, 0x1f, 0x1f, 0x1f, 0x1f, 0x1f}
+	case c < charset.Slots:
+		g = d.cgram[c]
+	default:
+		g = make([]byte, 8)
+	}
+	rows := make([]string, 8)
+	for i, b := range g {
+		for m := byte(0x10); m != 0; m >>= 1 {
+			if b&m != 0 {
+				rows[i] += "#"
+			} else {
+				rows[i] += "."
+			}
+		}
+	}
+	return rows
+}
+
+func TestBigText(t *testing.T) {
+	c := qt.New(t)
+	d := newDisplay()
+	s := New(d)
+	c.Assert(s.BigText(1, 2, "4.2"), qt.Equals, uint8(9))
+	c.Assert(string(d.rows[2][:11]), qt.Equals, " \xff\x00\xff   \x01\x01\xff ")
+	c.Assert(string(d.rows[3][:11]), qt.Equals, "   \xff . \xff\x00\x00 ")
+	c.Assert(d.pixels(2, 2), qt.DeepEquals, []string{".....", ".....", ".....", ".....", ".....", "#####", "#####", "#####"})
+	c.Assert(d.pixels(7, 2), qt.DeepEquals, []string{"#####", "#####", "#####", ".....", ".....", "#####", "#####", "#####"})
+	c.Assert(s.BigText(0, 0, "?"), qt.Equals, uint8(0))
+}
+
+func TestHBar(t *testing.T) {
+	c := qt.New(t)
+	d := newDisplay()
+	s := New(d)
+	s.HBar(0, 0, 4, 13, 20) // 13 of 20 pixels
+	c.Assert(string(d.rows[0][:5]), qt.Equals, "\xff\xff\x00  ")
+	c.Assert(d.pixels(2, 0)[0], qt.Equals, "###..")
+
+	s.HBar(0, 1, 4, 25, 20)
+	c.Assert(string(d.rows[1][:5]), qt.Equals, "\xff\xff\xff\xff ")
+	s.HBar(0, 1, 4, -1, 20)
+	c.Assert(string(d.rows[1][:5]), qt.Equals, "     ")
+}
+
+func TestVBar(t *testing.T) {
+	c := qt.New(t)
+	d := newDisplay()
+	s := New(d)
+	s.VBar(3, 0, 4, 50, 100) // 16 of 32 pixels
+	c.Assert([]byte{d.rows[0][3], d.rows[1][3], d.rows[2][3], d.rows[3][3]}, qt.DeepEquals, []byte("  \xff\xff"))
+	s.VBar(3, 0, 4, 60, 100) // 19 of 32 pixels
+	c.Assert(d.rows[1][3], qt.Equals, byte(0))
+	c.Assert(d.pixels(3, 1), qt.DeepEquals, []string{".....", ".....", ".....", ".....", ".....", "#####", "#####", "#####"})
+}
+
+func TestSparkline(t *testing.T) {
+	c := qt.New(t)
+	d := newDisplay()
+	s := New(d)
+	s.Sparkline(0, 3, []int{10, 20, 30, 10, 0}, 10, 30)
+	c.Assert(string(d.rows[3][:6]), qt.Equals, "\x00\x01\xff\x00\x00 ")
+	c.Assert(d.pixels(0, 3)[6:], qt.DeepEquals, []string{".....", "#####"})
+	c.Assert(d.pixels(1, 3)[3:5], qt.DeepEquals, []string{".....", "#####"})
+}
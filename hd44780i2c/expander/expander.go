@@ -0,0 +1,227 @@
+// Package expander implements the bus of the HD44780 I2C backpacks, which
+// drive the LCD in 4 bit mode through an I/O expander: a PCF8574 or a
+// MCP23008, with configurable pin maps. It is used by package hd44780i2c.
+package expander // import "tinygo.org/x/drivers/hd44780i2c/expander"
+
+import (
+	"errors"
+
+	"tinygo.org/x/drivers"
+)
+
+// Chip is the I/O expander chip of an I2C backpack.
+type Chip uint8
+
+const (
+	// PCF8574 is the expander of the common backpacks, also PCF8574A.
+	PCF8574 Chip = iota
+
+	// MCP23008 is the expander of the Adafruit I2C / SPI backpack.
+	MCP23008
+)
+
+// NoPin is the bit of a pin which is not connected to the expander.
+const NoPin = 0xff
+
+// PinMap maps the LCD pins to the bits of the expander port. If RW is NoPin,
+// the busy flag can't be read and the driver waits for the execution time of
+// the instructions instead.
+type PinMap struct {
+	RS        uint8
+	RW        uint8
+	E         uint8
+	Backlight uint8
+	Data      [4]uint8 // D4 to D7
+}
+
+var (
+	// PCF8574Pins is the pin map of the common PCF8574 backpacks.
+	PCF8574Pins = PinMap{RS: 0, RW: 1, E: 2, Backlight: 3, Data: [4]uint8{4, 5, 6, 7}}
+
+	// MCP23008Pins is the pin map of the Adafruit I2C / SPI backpack.
+	MCP23008Pins = PinMap{RS: 1, RW: NoPin, E: 2, Backlight: 7, Data: [4]uint8{3, 4, 5, 6}}
+)
+
+// MCP23008 registers
+const (
+	mcpIODIR = 0x00
+	mcpIOCON = 0x05
+	mcpGPIO  = 0x09
+
+	mcpSEQOP = 0x20 // IOCON: no address pointer increment
+)
+
+var errNoRW = errors.New("hd44780i2c: RW pin is not connected")
+
+// Bus drives the LCD in 4 bit mode through an I/O expander. It implements
+// hd44780.Buser.
+type Bus struct {
+	bus  drivers.I2C
+	addr uint8
+	chip Chip
+
+	rs, rw, e, backlight uint8 // bit masks of the pins
+	data                 [4]uint8
+	dataMask             uint8
+
+	state uint8 // RS, RW and backlight bits of the port
+	buf   [4]byte
+	rbuf  [1]byte
+}
+
+// NewBus returns a bus using the expander at addr. The I2C bus must already be
+// configured. If addr is 0, the default address of the expander is used.
+func NewBus(bus drivers.I2C, addr uint8, chip Chip, pins PinMap) *Bus {
+	if addr == 0 {
+		addr = 0x27
+		if chip == MCP23008 {
+			addr = 0x20
+		}
+	}
+	b := &Bus{
+		bus:       bus,
+		addr:      addr,
+		chip:      chip,
+		rs:        mask(pins.RS),
+		rw:        mask(pins.RW),
+		e:         mask(pins.E),
+		backlight: mask(pins.Backlight),
+	}
+	for i, p := range pins.Data {
+		b.data[i] = mask(p)
+		b.dataMask |= b.data[i]
+	}
+	b.state = b.backlight
+
+	if chip == MCP23008 {
+		b.bus.WriteRegister(b.addr, mcpIOCON, []byte{mcpSEQOP})
+		b.bus.WriteRegister(b.addr, mcpIODIR, []byte{0})
+	}
+	b.setPort(b.state)
+	return b
+}
+
+func mask(pin uint8) uint8 {
+	if pin > 7 {
+		return 0
+	}
+	return 1 << pin
+}
+
+// SetBacklight turns on/off the backlight.
+func (b *Bus) SetBacklight(on bool) {
+	if on {
+		b.state |= b.backlight
+	} else {
+		b.state &^= b.backlight
+	}
+	b.setPort(b.state)
+}
+
+// SetCommandMode sets command/instruction mode
+func (b *Bus) SetCommandMode(set bool) {
+	state := b.state | b.rs
+	if set {
+		state &^= b.rs
+	}
+	if state != b.state {
+		b.state = state
+		b.setPort(b.state)
+	}
+}
+
+// Write writes len(data) bytes from data to display driver, 4 bits at a time.
+// The data is latched on the falling edge of E.
+func (b *Bus) Write(data []byte) (n int, err error) {
+	for _, d := range data {
+		hi := b.state | b.nibble(d>>4)
+		lo := b.state | b.nibble(d)
+		b.buf = [4]byte{hi | b.e, hi, lo | b.e, lo}
+		if err = b.writePort(b.buf[:]); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Read reads len(data) bytes from the display, which is the busy flag and
+// address counter in command mode.
+func (b *Bus) Read(data []byte) (n int, err error) {
+	if b.rw == 0 {
+		return 0, errNoRW
+	}
+	if len(data) == 0 {
+		return 0, errors.New("length greater than 0 is required")
+	}
+
+	// The data pins of the PCF8574 are inputs when they are high.
+	state := b.state | b.rw
+	if b.chip == MCP23008 {
+		b.bus.WriteRegister(b.addr, mcpIODIR, []byte{b.dataMask})
+	} else {
+		state |= b.dataMask
+	}
+	for ; n < len(data); n++ {
+		var d, port uint8
+		for i := 0; i < 2 && err == nil; i++ {
+			b.setPort(state | b.e)
+			port, err = b.readPort()
+			b.setPort(state)
+			d = d<<4 | b.bits(port)
+		}
+		if err != nil {
+			break
+		}
+		data[n] = d
+	}
+	if b.chip == MCP23008 {
+		b.bus.WriteRegister(b.addr, mcpIODIR, []byte{0})
+	}
+	b.setPort(b.state)
+	return n, err
+}
+
+// nibble returns the port bits of the 4 low bits of d.
+func (b *Bus) nibble(d uint8) (port uint8) {
+	for i, m := range b.data {
+		if d&(1<<i) != 0 {
+			port |= m
+		}
+	}
+	return port
+}
+
+// bits returns the 4 bits of the data pins in port.
+func (b *Bus) bits(port uint8) (d uint8) {
+	for i, m := range b.data {
+		if port&m != 0 {
+			d |= 1 << i
+		}
+	}
+	return d
+}
+
+func (b *Bus) setPort(value uint8) error {
+	b.buf[0] = value
+	return b.writePort(b.buf[:1])
+}
+
+// writePort writes the values to the expander port, one after the other.
+func (b *Bus) writePort(values []uint8) error {
+	if b.chip == MCP23008 {
+		// The GPIO register is written again for every byte, see mcpSEQOP.
+		return b.bus.WriteRegister(b.addr, mcpGPIO, values)
+	}
+	return b.bus.Tx(uint16(b.addr), values, nil)
+}
+
+func (b *Bus) readPort() (uint8, error) {
+	var err error
+	if b.chip == MCP23008 {
+		err = b.bus.ReadRegister(b.addr, mcpGPIO, b.rbuf[:])
+	} else {
+		err = b.bus.Tx(uint16(b.addr), nil, b.rbuf[:])
+	}
+	return b.rbuf[0], err
+}
@@ -0,0 +1,109 @@
+package expander
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/tester"
+)
+
+// bits of the PCF8574Pins
+const (
+	rs        = 0x01
+	e         = 0x04
+	backlight = 0x08
+)
+
+// pcfBus records the bytes written to a PCF8574, which is not register based.
+type pcfBus struct {
+	*tester.I2CBus
+	addr   uint16
+	writes []byte
+	port   byte
+}
+
+func (b *pcfBus) Tx(addr uint16, w, r []byte) error {
+	b.addr = addr
+	b.writes = append(b.writes, w...)
+	for i := range r {
+		r[i] = b.port
+	}
+	return nil
+}
+
+func TestPCF8574(t *testing.T) {
+	c := qt.New(t)
+	i2c := &pcfBus{I2CBus: tester.NewI2CBus(c)}
+	b := NewBus(i2c, 0, PCF8574, PCF8574Pins)
+	c.Assert(i2c.addr, qt.Equals, uint16(0x27))
+	c.Assert(i2c.writes, qt.DeepEquals, []byte{backlight})
+
+	// data mode, then 0xA5 high nibble first, latched by E going low
+	i2c.writes = nil
+	b.SetCommandMode(false)
+	n, err := b.Write([]byte{0xA5})
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 1)
+	c.Assert(i2c.writes, qt.DeepEquals, []byte{
+		0x09,
+		0xA0 | backlight | e | rs, 0xA0 | backlight | rs,
+		0x50 | backlight | e | rs, 0x50 | backlight | rs,
+	})
+
+	i2c.writes = nil
+	b.SetBacklight(false)
+	c.Assert(i2c.writes, qt.DeepEquals, []byte{rs})
+}
+
+func TestPCF8574Read(t *testing.T) {
+	c := qt.New(t)
+	i2c := &pcfBus{I2CBus: tester.NewI2CBus(c)}
+	b := NewBus(i2c, 0x3f, PCF8574, PCF8574Pins)
+	b.SetCommandMode(true)
+
+	// both nibbles read 0x8: busy flag set, address 0x08
+	i2c.port = 0x80
+	var data [1]byte
+	n, err := b.Read(data[:])
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 1)
+	c.Assert(data[0], qt.Equals, uint8(0x88))
+	c.Assert(i2c.addr, qt.Equals, uint16(0x3f))
+}
+
+func TestMCP23008(t *testing.T) {
+	c := qt.New(t)
+	i2c := tester.NewI2CBus(c)
+	fake := tester.NewI2CDevice(c, 0x20)
+	fake.SetupRegister(mcpIODIR, 0xff)
+	i2c.AddDevice(fake)
+
+	b := NewBus(i2c, 0, MCP23008, MCP23008Pins)
+	regs := make([]byte, 4)
+	fake.ReadRegister(mcpIODIR, regs[:1])
+	c.Assert(regs[0], qt.Equals, uint8(0)) // all outputs
+	fake.ReadRegister(mcpIOCON, regs[:1])
+	c.Assert(regs[0], qt.Equals, uint8(mcpSEQOP))
+	fake.ReadRegister(mcpGPIO, regs[:1])
+	c.Assert(regs[0], qt.Equals, uint8(0x80)) // backlight on GP7
+
+	// The port values of a byte are written in a single transfer, which
+	// the fake stores in the registers following GPIO. D4-D7 are on GP3-GP6
+	// and E on GP2.
+	_, err := b.Write([]byte{0xA5})
+	c.Assert(err, qt.IsNil)
+	fake.ReadRegister(mcpGPIO, regs)
+	c.Assert(regs, qt.DeepEquals, []byte{0xD4, 0xD0, 0xAC, 0xA8})
+
+	b.SetCommandMode(false)
+	fake.ReadRegister(mcpGPIO, regs[:1])
+	c.Assert(regs[0], qt.Equals, uint8(0x82)) // RS on GP1
+
+	b.SetBacklight(false)
+	fake.ReadRegister(mcpGPIO, regs[:1])
+	c.Assert(regs[0], qt.Equals, uint8(0x02))
+
+	// no RW pin on this backpack
+	_, err = b.Read(regs[:1])
+	c.Assert(err, qt.Equals, errNoRW)
+}
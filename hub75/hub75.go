@@ -6,18 +6,47 @@
 package hub75 // import "tinygo.org/x/drivers/hub75"
 
 import (
+	"errors"
 	"image/color"
 	"machine"
 	"time"
+
+	"tinygo.org/x/drivers/hub75/matrix"
 )
 
+// bitTime is the time in microseconds the least significant bitplane is shown
+// at full brightness. Every following bitplane is shown twice as long.
+const bitTime = 4
+
+// MaxColorDepth is the largest color depth. A frame is shown in
+// RowPattern*bitTime*(2^ColorDepth-1) microseconds, plus the time to shift
+// the data: for a 1/16 scan panel, about 16ms (60Hz) at 8 bits, and 65ms
+// (15Hz) at 10 bits. Every extra bit would halve the refresh rate again.
+const MaxColorDepth = 10
+
+var ErrInvalidDepth = errors.New("hub75: color depth must not exceed 10 bits")
+
 type Config struct {
-	Width      int16
-	Height     int16
+	Width  int16
+	Height int16
+	// ColorDepth is the number of bits of each color component, up to
+	// MaxColorDepth, shown with binary code modulation. The buffer takes
+	// ColorDepth bitplanes. Every bit halves the refresh rate, see
+	// MaxColorDepth.
 	ColorDepth uint16
 	RowPattern int16
 	Brightness uint8
 	FastUpdate bool
+
+	// Gamma is the gamma correction applied to the colors, its tables must
+	// have ColorDepth bits. Defaults to a gamma of 2.2 for all components,
+	// use an empty matrix.Gamma for a linear response.
+	Gamma *matrix.Gamma
+
+	// Layout describes chained panels and outdoor panels with a low scan
+	// rate. Its size is set from Width and Height, and its scan from
+	// RowPattern.
+	Layout matrix.Config
 }
 
 type Device struct {
@@ -33,10 +62,8 @@ type Device struct {
 	brightness        uint8
 	fastUpdate        bool
 	colorDepth        uint16
-	colorStep         uint16
-	colorHalfStep     uint16
-	colorThirdStep    uint16
-	colorTwoThirdStep uint16
+	gamma             *matrix.Gamma
+	layout            matrix.Layout
 	rowPattern        int16
 	rowsPerBuffer     int16
 	panelWidth        int16
@@ -48,7 +75,6 @@ type Device struct {
 	sendBufferSize    uint16
 	rowOffset         []uint32
 	buffer            [][]uint8 // [ColorDepth][(width * height * 3(rgb)) / 8]uint8
-	displayColor      uint16
 }
 
 // New returns a new HUB75 driver. Pass in a fully configured SPI bus.
@@ -71,33 +97,52 @@ func New(b machine.SPI, latPin, oePin, aPin, bPin, cPin, dPin machine.Pin) Devic
 	}
 }
 
-// Configure sets up the device.
-func (d *Device) Configure(cfg Config) {
-	if cfg.Width != 0 {
-		d.width = cfg.Width
-	} else {
-		d.width = 64
+// Configure sets up the device. An error is returned if the layout, the color
+// depth or the gamma tables are not valid.
+func (d *Device) Configure(cfg Config) error {
+	if cfg.Width == 0 {
+		cfg.Width = 64
 	}
-	if cfg.Height != 0 {
-		d.height = cfg.Height
-	} else {
-		d.height = 32
+	if cfg.Height == 0 {
+		cfg.Height = 32
+	}
+	if cfg.ColorDepth > MaxColorDepth {
+		return ErrInvalidDepth
+	}
+	if cfg.Gamma != nil {
+		if err := cfg.Gamma.Validate(); err != nil {
+			return err
+		}
 	}
 	if cfg.ColorDepth != 0 {
 		d.colorDepth = cfg.ColorDepth
 	} else {
 		d.colorDepth = 8
 	}
-	if cfg.RowPattern != 0 {
-		d.rowPattern = cfg.RowPattern
-	} else {
-		d.rowPattern = 16
-	}
 	if cfg.Brightness != 0 {
 		d.brightness = cfg.Brightness
 	} else {
 		d.brightness = 255
 	}
+	if cfg.Gamma != nil {
+		d.gamma = cfg.Gamma
+	} else {
+		d.gamma = matrix.NewGamma(2.2, uint8(d.colorDepth))
+	}
+
+	cfg.Layout.Width = cfg.Width
+	cfg.Layout.Height = cfg.Height
+	if cfg.Layout.Scan == 0 {
+		cfg.Layout.Scan = cfg.RowPattern
+	}
+	layout, err := matrix.New(cfg.Layout)
+	if err != nil {
+		return err
+	}
+	d.layout = layout
+	// the buffer holds the pixels in the order of the chain
+	d.width, d.height = layout.ChainSize()
+	d.rowPattern = d.height / 2
 
 	d.fastUpdate = cfg.FastUpdate
 	d.rowsPerBuffer = d.height / 2
@@ -107,19 +152,11 @@ func (d *Device) Configure(cfg Config) {
 	d.patternColorBytes = uint8((d.height / d.rowPattern) * (d.width / 8))
 	d.rowSetsPerBuffer = uint8(d.rowsPerBuffer / d.rowPattern)
 	d.sendBufferSize = uint16(d.patternColorBytes) * 3
-	d.colorStep = 256 / d.colorDepth
-	d.colorHalfStep = d.colorStep / 2
-	d.colorThirdStep = d.colorStep / 3
-	d.colorTwoThirdStep = 2 * d.colorThirdStep
 	d.buffer = make([][]uint8, d.colorDepth)
 	for i := range d.buffer {
 		d.buffer[i] = make([]uint8, (d.width*d.height*3)/8)
 	}
 
-	d.colorHalfStep = d.colorStep / 2
-	d.colorThirdStep = d.colorStep / 3
-	d.colorTwoThirdStep = 2 * d.colorThirdStep
-
 	d.a.Low()
 	d.b.Low()
 	d.c.Low()
@@ -130,15 +167,22 @@ func (d *Device) Configure(cfg Config) {
 	for i = 0; i < uint32(d.height); i++ {
 		d.rowOffset[i] = (i%uint32(d.rowPattern))*uint32(d.sendBufferSize) + uint32(d.sendBufferSize) - 1
 	}
+	return nil
 }
 
 // SetPixel modifies the internal buffer in a single pixel.
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
-	d.fillMatrixBuffer(x, y, c.R, c.G, c.B)
+	x, y, ok := d.layout.Map(x, y)
+	if !ok {
+		return
+	}
+	r, g, b := d.gamma.Apply(c, uint8(d.colorDepth))
+	d.fillMatrixBuffer(x, y, r, g, b)
 }
 
-// fillMatrixBuffer modifies a pixel in the internal buffer given position and RGB values
-func (d *Device) fillMatrixBuffer(x int16, y int16, r uint8, g uint8, b uint8) {
+// fillMatrixBuffer modifies a pixel in the internal buffer given its position
+// in the chain and its RGB intensities
+func (d *Device) fillMatrixBuffer(x int16, y int16, r uint16, g uint16, b uint16) {
 	if x < 0 || x >= d.width || y < 0 || y >= d.height {
 		return
 	}
@@ -161,55 +205,60 @@ func (d *Device) fillMatrixBuffer(x int16, y int16, r uint8, g uint8, b uint8) {
 
 	bitSelect := uint8(x % 8)
 
+	// bitplane c holds bit c of every intensity
 	for c := uint16(0); c < d.colorDepth; c++ {
-		colorTresh := uint8(c*d.colorStep + d.colorHalfStep)
-		if r > colorTresh {
+		if r&(1<<c) != 0 {
 			d.buffer[c][offsetR] |= 1 << bitSelect
 		} else {
-			d.buffer[c][offsetR] = d.buffer[c][offsetR] &^ 1 << bitSelect
+			d.buffer[c][offsetR] &^= 1 << bitSelect
 		}
-		if g > colorTresh {
-			d.buffer[(c+d.colorThirdStep)%d.colorDepth][offsetG] |= 1 << bitSelect
+		if g&(1<<c) != 0 {
+			d.buffer[c][offsetG] |= 1 << bitSelect
 		} else {
-			d.buffer[(c+d.colorThirdStep)%d.colorDepth][offsetG] &^= 1 << bitSelect
+			d.buffer[c][offsetG] &^= 1 << bitSelect
 		}
-		if b > colorTresh {
-			d.buffer[(c+d.colorTwoThirdStep)%d.colorDepth][offsetB] |= 1 << bitSelect
+		if b&(1<<c) != 0 {
+			d.buffer[c][offsetB] |= 1 << bitSelect
 		} else {
-			d.buffer[(c+d.colorTwoThirdStep)%d.colorDepth][offsetB] &^= 1 << bitSelect
+			d.buffer[c][offsetB] &^= 1 << bitSelect
 		}
 	}
 }
 
-// Display sends the buffer (if any) to the screen.
+// Display shows one frame of the buffer on the screen. It has to be called
+// continuously to keep the screen lit.
+//
+// Every row is shown once per bitplane, each bitplane twice as long as the
+// previous one, so that the intensities are shown with binary code modulation.
+// See MaxColorDepth for the time a frame takes.
 func (d *Device) Display() error {
 	rp := uint16(d.rowPattern)
 	for i := uint16(0); i < rp; i++ {
-		// FAST UPDATES (only if brightness = 255)
-		if d.fastUpdate && d.brightness == 255 {
-			d.setMux((i + rp - 1) % rp)
-			d.lat.High()
-			d.oe.Low()
-			d.lat.Low()
-			time.Sleep(1 * time.Microsecond)
-			d.bus.Tx(d.buffer[d.displayColor][i*d.sendBufferSize:(i+1)*d.sendBufferSize], nil)
-			time.Sleep(10 * time.Microsecond)
-			d.oe.High()
+		for c := uint16(0); c < d.colorDepth; c++ {
+			showTime := (bitTime << c) * uint32(d.brightness) / 255
+			// FAST UPDATES (only if brightness = 255)
+			if d.fastUpdate && d.brightness == 255 {
+				// the previous bitplane stays lit while shifting this one
+				d.bus.Tx(d.buffer[c][i*d.sendBufferSize:(i+1)*d.sendBufferSize], nil)
+				d.oe.High()
+				d.setMux(i)
+				d.lat.High()
+				d.lat.Low()
+				d.oe.Low()
+				time.Sleep(time.Duration(showTime) * time.Microsecond)
 
-		} else { // NO FAST UPDATES
-			d.setMux(i)
-			d.bus.Tx(d.buffer[d.displayColor][i*d.sendBufferSize:(i+1)*d.sendBufferSize], nil)
-			d.latch((255 * uint16(d.brightness)) / 255)
+			} else { // NO FAST UPDATES
+				d.bus.Tx(d.buffer[c][i*d.sendBufferSize:(i+1)*d.sendBufferSize], nil)
+				d.setMux(i)
+				d.latch(showTime)
+			}
 		}
 	}
-	d.displayColor++
-	if d.displayColor >= d.colorDepth {
-		d.displayColor = 0
-	}
+	d.oe.High()
 	return nil
 }
 
-func (d *Device) latch(showTime uint16) {
+func (d *Device) latch(showTime uint32) {
 	d.lat.High()
 	d.lat.Low()
 	d.oe.Low()
@@ -265,5 +314,5 @@ func (d *Device) ClearDisplay() {
 
 // Size returns the current size of the display.
 func (d *Device) Size() (w, h int16) {
-	return d.width, d.height
+	return d.layout.Size()
 }
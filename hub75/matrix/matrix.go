@@ -0,0 +1,203 @@
+// Package matrix implements the parts of the HUB75 LED matrix drivers that do
+// not depend on the hardware: mapping the coordinates of chained panels and
+// outdoor panels with a low scan rate, and gamma correction.
+//
+// It is shared by the hub75 and rgb75 drivers.
+package matrix // import "tinygo.org/x/drivers/hub75/matrix"
+
+import (
+	"errors"
+	"image/color"
+	"math"
+)
+
+var (
+	ErrInvalidLayout = errors.New("matrix: invalid panel layout")
+	ErrInvalidGamma  = errors.New("matrix: gamma tables must have 256 entries")
+)
+
+// Config describes how the panels of a display are arranged.
+//
+// The panels are driven as a single chain: the drivers see one long panel,
+// as high as a single panel and as wide as all of them. The panels are
+// numbered in chain order, starting at x 0 of the chain as seen by the
+// driver, from left to right and top to bottom of the display.
+type Config struct {
+	// Width and Height are the size of the whole display, in pixels.
+	Width  int16
+	Height int16
+
+	// Columns and Rows are the number of panels horizontally and vertically.
+	// They default to 1.
+	Columns int16
+	Rows    int16
+
+	// Serpentine is set when the chain goes back in the opposite direction on
+	// every other row of panels, which are then mounted upside down.
+	Serpentine bool
+
+	// Scan is the number of row addresses of a panel, for instance 8 for a
+	// 1/8 scan panel. It defaults to half the panel height, which is the case
+	// of most indoor panels. Outdoor panels with a lower scan rate drive
+	// several rows per address and have longer shift registers.
+	Scan int16
+
+	// ScanChunk is the number of consecutive pixels of a row in the shift
+	// registers of low scan rate panels, before switching to the next row
+	// driven by the same address. Defaults to 8.
+	ScanChunk int16
+
+	// ScanReversed is set when the chunks of the last row driven by an
+	// address come first in the shift registers.
+	ScanReversed bool
+}
+
+// Layout maps the coordinates of the display onto the coordinates of the
+// chain driven by the HUB75 drivers.
+type Layout struct {
+	width        int16
+	height       int16
+	panelWidth   int16
+	panelHeight  int16
+	columns      int16
+	serpentine   bool
+	scan         int16
+	chunk        int16
+	bands        int16
+	scanReversed bool
+}
+
+// New returns the layout for the given configuration.
+func New(cfg Config) (Layout, error) {
+	if cfg.Columns <= 0 {
+		cfg.Columns = 1
+	}
+	if cfg.Rows <= 0 {
+		cfg.Rows = 1
+	}
+	if cfg.ScanChunk <= 0 {
+		cfg.ScanChunk = 8
+	}
+	l := Layout{
+		width:        cfg.Width,
+		height:       cfg.Height,
+		panelWidth:   cfg.Width / cfg.Columns,
+		panelHeight:  cfg.Height / cfg.Rows,
+		columns:      cfg.Columns,
+		serpentine:   cfg.Serpentine,
+		scan:         cfg.Scan,
+		chunk:        cfg.ScanChunk,
+		scanReversed: cfg.ScanReversed,
+	}
+	if l.scan <= 0 {
+		l.scan = l.panelHeight / 2
+	}
+	if l.panelWidth <= 0 || l.panelHeight < 2 || l.scan <= 0 ||
+		l.panelWidth*cfg.Columns != cfg.Width ||
+		l.panelHeight*cfg.Rows != cfg.Height ||
+		l.panelHeight%(2*l.scan) != 0 {
+		return Layout{}, ErrInvalidLayout
+	}
+	l.bands = l.panelHeight / (2 * l.scan)
+	if l.bands > 1 && l.panelWidth%l.chunk != 0 {
+		return Layout{}, ErrInvalidLayout
+	}
+	return l, nil
+}
+
+// Size returns the size of the display.
+func (l *Layout) Size() (w, h int16) {
+	return l.width, l.height
+}
+
+// ChainSize returns the size of the chain as seen by the drivers: its height
+// is twice the number of row addresses.
+func (l *Layout) ChainSize() (w, h int16) {
+	panels := (l.width / l.panelWidth) * (l.height / l.panelHeight)
+	return panels * l.panelWidth * l.bands, 2 * l.scan
+}
+
+// Map returns the chain coordinates of the pixel at x, y of the display. It
+// returns false if the pixel is outside of the display.
+func (l *Layout) Map(x, y int16) (cx, cy int16, ok bool) {
+	if x < 0 || x >= l.width || y < 0 || y >= l.height {
+		return 0, 0, false
+	}
+	col, px := x/l.panelWidth, x%l.panelWidth
+	row, py := y/l.panelHeight, y%l.panelHeight
+	if l.serpentine && row%2 == 1 {
+		col = l.columns - 1 - col
+		px = l.panelWidth - 1 - px
+		py = l.panelHeight - 1 - py
+	}
+	panel := row*l.columns + col
+
+	// every address drives a row in each half of the panel, and low scan rate
+	// panels interleave the rows driven by the same address chunk by chunk
+	half := l.panelHeight / 2
+	band := (py % half) / l.scan
+	if l.scanReversed {
+		band = l.bands - 1 - band
+	}
+	cx = (px/l.chunk)*l.chunk*l.bands + band*l.chunk + px%l.chunk
+	cy = (py/half)*l.scan + py%l.scan
+	return panel*l.panelWidth*l.bands + cx, cy, true
+}
+
+// GammaTable maps the 8 bit color components to intensities with a given
+// bit depth. It has 256 entries.
+type GammaTable []uint16
+
+// NewGammaTable returns the table for the given gamma, usually around 2.2,
+// with intensities of the given number of bits, up to 16.
+func NewGammaTable(gamma float32, bits uint8) GammaTable {
+	if bits > 16 {
+		bits = 16
+	}
+	max := float64(uint32(1)<<bits - 1)
+	t := make(GammaTable, 256)
+	for i := range t {
+		t[i] = uint16(math.Pow(float64(i)/255, float64(gamma))*max + 0.5)
+	}
+	return t
+}
+
+// Gamma holds the gamma correction tables of each color component. A nil
+// table means a linear response.
+type Gamma struct {
+	R GammaTable
+	G GammaTable
+	B GammaTable
+}
+
+// NewGamma returns the gamma correction using the same table for all color
+// components.
+func NewGamma(gamma float32, bits uint8) *Gamma {
+	t := NewGammaTable(gamma, bits)
+	return &Gamma{R: t, G: t, B: t}
+}
+
+// Validate returns ErrInvalidGamma if a table is neither empty nor has 256
+// entries.
+func (g *Gamma) Validate() error {
+	for _, t := range []GammaTable{g.R, g.G, g.B} {
+		if len(t) != 0 && len(t) != 256 {
+			return ErrInvalidGamma
+		}
+	}
+	return nil
+}
+
+// Apply returns the intensities of the color components of c, with the given
+// number of bits. The tables must have been created with the same number of
+// bits.
+func (g *Gamma) Apply(c color.RGBA, bits uint8) (r, gg, b uint16) {
+	return lookup(g.R, c.R, bits), lookup(g.G, c.G, bits), lookup(g.B, c.B, bits)
+}
+
+func lookup(t GammaTable, v uint8, bits uint8) uint16 {
+	if len(t) == 256 {
+		return t[v]
+	}
+	return uint16((uint32(v)*(uint32(1)<<bits-1) + 127) / 255)
+}
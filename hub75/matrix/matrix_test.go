@@ -0,0 +1,104 @@
+package matrix
+
+import (
+	"image/color"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestSinglePanel(t *testing.T) {
+	c := qt.New(t)
+	l, err := New(Config{Width: 64, Height: 32})
+	c.Assert(err, qt.IsNil)
+	w, h := l.ChainSize()
+	c.Assert([]int16{w, h}, qt.DeepEquals, []int16{64, 32})
+	for _, p := range [][2]int16{{0, 0}, {63, 0}, {10, 20}, {63, 31}} {
+		x, y, ok := l.Map(p[0], p[1])
+		c.Assert(ok, qt.IsTrue)
+		c.Assert([2]int16{x, y}, qt.Equals, p)
+	}
+	_, _, ok := l.Map(64, 0)
+	c.Assert(ok, qt.IsFalse)
+	_, _, ok = l.Map(0, -1)
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestChainedPanels(t *testing.T) {
+	c := qt.New(t)
+	l, err := New(Config{Width: 64, Height: 64, Columns: 2, Rows: 2})
+	c.Assert(err, qt.IsNil)
+	w, h := l.ChainSize()
+	c.Assert([]int16{w, h}, qt.DeepEquals, []int16{128, 32})
+
+	x, y, _ := l.Map(40, 5)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{40, 5})
+	x, y, _ = l.Map(3, 40)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{67, 8})
+
+	// the second row of panels is upside down and in reverse order
+	l, err = New(Config{Width: 64, Height: 64, Columns: 2, Rows: 2, Serpentine: true})
+	c.Assert(err, qt.IsNil)
+	x, y, _ = l.Map(3, 40)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{124, 23})
+	x, y, _ = l.Map(63, 63)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{64, 0})
+}
+
+func TestLowScanPanel(t *testing.T) {
+	c := qt.New(t)
+	// 32x16 panel with a 1/4 scan: every address drives 2 rows per half
+	l, err := New(Config{Width: 32, Height: 16, Scan: 4})
+	c.Assert(err, qt.IsNil)
+	w, h := l.ChainSize()
+	c.Assert([]int16{w, h}, qt.DeepEquals, []int16{64, 8})
+
+	x, y, _ := l.Map(0, 0)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{0, 0})
+	x, y, _ = l.Map(0, 4)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{8, 0})
+	x, y, _ = l.Map(9, 5)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{25, 1})
+	x, y, _ = l.Map(9, 13)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{25, 5})
+
+	l, err = New(Config{Width: 32, Height: 16, Scan: 4, ScanReversed: true})
+	c.Assert(err, qt.IsNil)
+	x, y, _ = l.Map(9, 5)
+	c.Assert([]int16{x, y}, qt.DeepEquals, []int16{17, 1})
+
+	_, err = New(Config{Width: 32, Height: 16, Scan: 3})
+	c.Assert(err, qt.Equals, ErrInvalidLayout)
+	_, err = New(Config{Width: 30, Height: 16, Scan: 4})
+	c.Assert(err, qt.Equals, ErrInvalidLayout)
+	_, err = New(Config{Width: 64, Height: 32, Columns: 3})
+	c.Assert(err, qt.Equals, ErrInvalidLayout)
+}
+
+func TestGamma(t *testing.T) {
+	c := qt.New(t)
+	g := NewGamma(2.2, 8)
+	r, gg, b := g.Apply(color.RGBA{0, 128, 255, 255}, 8)
+	c.Assert([]uint16{r, gg, b}, qt.DeepEquals, []uint16{0, 56, 255})
+
+	// dark tones get darker
+	c.Assert(g.R[32] < 32, qt.IsTrue)
+
+	g = NewGamma(2.2, 12)
+	_, _, b = g.Apply(color.RGBA{B: 255}, 12)
+	c.Assert(b, qt.Equals, uint16(4095))
+
+	// no tables is linear
+	var linear Gamma
+	r, gg, b = linear.Apply(color.RGBA{0, 128, 255, 255}, 4)
+	c.Assert([]uint16{r, gg, b}, qt.DeepEquals, []uint16{0, 8, 15})
+	c.Assert(linear.Validate(), qt.IsNil)
+	c.Assert(g.Validate(), qt.IsNil)
+
+	// depths above 16 bits are clamped
+	g = NewGamma(2.2, 32)
+	c.Assert(g.B[255], qt.Equals, uint16(0xffff))
+
+	short := &Gamma{G: make(GammaTable, 16)}
+	c.Assert(short.Validate(), qt.Equals, ErrInvalidGamma)
+}
@@ -7,6 +7,10 @@ import (
 	"time"
 )
 
+// ErrNoReadPin is returned by the read functions when the display was set up
+// without a RD pin, so the bus cannot read from it.
+var ErrNoReadPin = errors.New("ili9341: no RD pin to read from the display")
+
 type Config struct {
 	Width    int16
 	Height   int16
@@ -160,6 +164,80 @@ func (d *Device) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
 	return nil
 }
 
+// ReadPixel reads back the color of a single pixel from display memory.
+func (d *Device) ReadPixel(x, y int16) (color.RGBA, error) {
+	var buf [1]uint16
+	if err := d.ReadRect(x, y, 1, 1, buf[:]); err != nil {
+		return color.RGBA{}, err
+	}
+	return RGB565ToRGBA(buf[0]), nil
+}
+
+// ReadRect reads back a rectangle of display memory into data as RGB565
+// pixels, using the same layout as DrawRGBBitmap. The display always returns
+// 18-bit (RGB666) pixels on read; they are truncated to RGB565.
+//
+// Note that most ILI9341 panels cannot be read at the SPI clock rates used
+// for writing. Lower the bus frequency (around 6MHz) before reading.
+func (d *Device) ReadRect(x, y, w, h int16, data []uint16) error {
+	k, i := d.Size()
+	if x < 0 || y < 0 || w <= 0 || h <= 0 ||
+		x >= k || (x+w) > k || y >= i || (y+h) > i {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	if int(w)*int(h) > len(data) {
+		return errors.New("buffer too small for rectangle size")
+	}
+	d.setAddrWindow(x, y, w, h)
+	d.startWrite()
+	defer d.endWrite()
+	d.dc.Low()
+	d.driver.write8(RAMRD)
+	d.dc.High()
+	// memory reads are preceded by a full dummy byte on both interfaces
+	var rgb [48]byte
+	dummy := uint8(8)
+	for j, c := 0, int(w)*int(h); j < c; {
+		n := c - j
+		if n > len(rgb)/3 {
+			n = len(rgb) / 3
+		}
+		if err := d.driver.read8sl(rgb[:n*3], dummy); err != nil {
+			return err
+		}
+		dummy = 0
+		for k := 0; k < n; k++ {
+			data[j+k] = uint16(rgb[k*3]&0xF8)<<8 |
+				uint16(rgb[k*3+1]&0xFC)<<3 |
+				uint16(rgb[k*3+2])>>3
+		}
+		j += n
+	}
+	return nil
+}
+
+// ReadDisplayID returns the 24-bit display identification: manufacturer ID,
+// module/driver version ID and module/driver ID, from the most to the least
+// significant byte.
+func (d *Device) ReadDisplayID() (uint32, error) {
+	var data [3]byte
+	if err := d.readCommand(RDDID, data[:], 1); err != nil {
+		return 0, err
+	}
+	return uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2]), nil
+}
+
+// ReadDisplayStatus returns the 32-bit display status as described for the
+// RDDST command in the datasheet (booster, orientation, pixel format, sleep,
+// display on/off, tearing effect, etc.).
+func (d *Device) ReadDisplayStatus() (uint32, error) {
+	var data [4]byte
+	if err := d.readCommand(RDDST, data[:], 1); err != nil {
+		return 0, err
+	}
+	return uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3]), nil
+}
+
 // FillRectangle fills a rectangle at given coordinates with a color
 func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
 	k, i := d.Size()
@@ -262,6 +340,12 @@ func (d *Device) StopScroll() {
 
 // setWindow prepares the screen to be modified at a given rectangle
 func (d *Device) setWindow(x, y, w, h int16) {
+	d.setAddrWindow(x, y, w, h)
+	d.sendCommand(RAMWR, nil)
+}
+
+// setAddrWindow sets the column and page address of the given rectangle
+func (d *Device) setAddrWindow(x, y, w, h int16) {
 	//x += d.columnOffset
 	//y += d.rowOffset
 	x1 := x + w - 1
@@ -278,7 +362,6 @@ func (d *Device) setWindow(x, y, w, h int16) {
 		})
 		d.y0, d.y1 = y, y1
 	}
-	d.sendCommand(RAMWR, nil)
 }
 
 //go:inline
@@ -304,6 +387,18 @@ func (d *Device) sendCommand(cmd byte, data []byte) {
 	d.endWrite()
 }
 
+// readCommand sends cmd and reads its parameters into data. The dummy
+// argument is the number of dummy clock cycles the serial interface inserts
+// before the data; parallel interfaces perform a single dummy read instead.
+func (d *Device) readCommand(cmd byte, data []byte, dummy uint8) error {
+	d.startWrite()
+	defer d.endWrite()
+	d.dc.Low()
+	d.driver.write8(cmd)
+	d.dc.High()
+	return d.driver.read8sl(data, dummy)
+}
+
 type driver interface {
 	configure(config *Config)
 	write8(b byte)
@@ -312,6 +407,10 @@ type driver interface {
 	write16(data uint16)
 	write16n(data uint16, n int)
 	write16sl(data []uint16)
+	// read8sl reads len(b) bytes after skipping dummy clock cycles (serial)
+	// or a single dummy read (parallel) if dummy is non-zero. It returns
+	// ErrNoReadPin if the bus cannot read.
+	read8sl(b []byte, dummy uint8) error
 }
 
 func delay(m int) {
@@ -320,6 +419,14 @@ func delay(m int) {
 	}
 }
 
+// RGB565ToRGBA converts a uint16 used in the display to a color.RGBA
+func RGB565ToRGBA(c uint16) color.RGBA {
+	r := uint8(c>>8) & 0xF8
+	g := uint8(c>>3) & 0xFC
+	b := uint8(c<<3) & 0xF8
+	return color.RGBA{r | r>>5, g | g>>6, b | b>>5, 0xFF}
+}
+
 // RGBATo565 converts a color.RGBA to uint16 used in the display
 func RGBATo565(c color.RGBA) uint16 {
 	r, g, b, _ := c.RGBA()
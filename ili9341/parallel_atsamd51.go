@@ -11,8 +11,10 @@ import (
 type parallelDriver struct {
 	d0 machine.Pin
 	wr machine.Pin
+	rd machine.Pin
 
 	setPort *uint8
+	inPort  *uint8
 
 	clrPort *uint32
 	clrMask uint32
@@ -33,6 +35,7 @@ func NewParallel(d0, wr, dc, cs, rst, rd machine.Pin) *Device {
 		driver: &parallelDriver{
 			d0: d0,
 			wr: wr,
+			rd: rd,
 		},
 	}
 }
@@ -53,6 +56,10 @@ func (pd *parallelDriver) configure(config *Config) {
 	setMask := uint32(pd.d0) & 0x1f
 	pd.setPort = (*uint8)(unsafe.Pointer(uintptr(unsafe.Pointer(setPort)) - uintptr(8) + uintptr(setMask/8)))
 
+	// The IN register follows at offset 0x20, so the same byte lane of it is
+	// 0x10 past the one in the OUT register.
+	pd.inPort = (*uint8)(unsafe.Pointer(uintptr(unsafe.Pointer(pd.setPort)) + uintptr(0x10)))
+
 	pd.clrPort, _ = (pd.d0).PortMaskClear()
 	pd.clrMask = 0xFF << uint32(pd.d0)
 
@@ -107,3 +114,38 @@ func (pd *parallelDriver) write16sl(data []uint16) {
 		pd.write8(byte(data[i]))
 	}
 }
+
+func (pd *parallelDriver) read8sl(b []byte, dummy uint8) error {
+	if pd.rd == machine.NoPin {
+		return ErrNoReadPin
+	}
+	input := machine.PinConfig{machine.PinInput}
+	for pin := pd.d0; pin < pd.d0+8; pin++ {
+		pin.Configure(input)
+	}
+
+	if dummy != 0 {
+		pd.read8()
+	}
+	for i := range b {
+		b[i] = pd.read8()
+	}
+
+	output := machine.PinConfig{machine.PinOutput}
+	for pin := pd.d0; pin < pd.d0+8; pin++ {
+		pin.Configure(output)
+	}
+	return nil
+}
+
+//go:inline
+func (pd *parallelDriver) read8() byte {
+	pd.rd.Low()
+	// memory reads need RDX low for at least 355ns before the data is valid
+	for i := 0; i < 16; i++ {
+		volatile.LoadUint8(pd.inPort)
+	}
+	b := volatile.LoadUint8(pd.inPort)
+	pd.rd.High()
+	return b
+}
@@ -114,3 +114,38 @@ func (pd *spiDriver) write16sl(data []uint16) {
 	for pd.bus.Bus.SYNCBUSY.HasBits(sam.SERCOM_SPI_SYNCBUSY_CTRLB) {
 	}
 }
+
+func (pd *spiDriver) read8() byte {
+	for !pd.bus.Bus.INTFLAG.HasBits(sam.SERCOM_SPI_INTFLAG_DRE) {
+	}
+	pd.bus.Bus.DATA.Set(0xFF)
+	for !pd.bus.Bus.INTFLAG.HasBits(sam.SERCOM_SPI_INTFLAG_RXC) {
+	}
+	return byte(pd.bus.Bus.DATA.Get())
+}
+
+func (pd *spiDriver) read8sl(b []byte, dummy uint8) error {
+	// wait for pending writes and drop anything received meanwhile
+	for !pd.bus.Bus.INTFLAG.HasBits(sam.SERCOM_SPI_INTFLAG_TXC) {
+	}
+	for pd.bus.Bus.INTFLAG.HasBits(sam.SERCOM_SPI_INTFLAG_RXC) {
+		pd.bus.Bus.DATA.Get()
+	}
+
+	if dummy == 0 {
+		for i := range b {
+			b[i] = pd.read8()
+		}
+		return nil
+	}
+
+	// the data is shifted by the dummy cycles, so each byte is assembled
+	// from two consecutive bytes on the wire
+	prev := pd.read8()
+	for i := range b {
+		next := pd.read8()
+		b[i] = prev<<dummy | next>>(8-dummy)
+		prev = next
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package microbitmatrix
+
+// font is a 5x5 font for the printable ASCII characters, from ' ' to '~'.
+// Every character has 5 rows, the most significant of the 5 bits of a row is
+// the leftmost pixel.
+var font = [95][5]uint8{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x04, 0x04, 0x04, 0x00, 0x04}, // '!'
+	{0x0a, 0x0a, 0x00, 0x00, 0x00}, // '"'
+	{0x0a, 0x1f, 0x0a, 0x1f, 0x0a}, // '#'
+	{0x0f, 0x14, 0x0e, 0x05, 0x1e}, // '$'
+	{0x19, 0x1a, 0x04, 0x0b, 0x13}, // '%'
+	{0x0c, 0x12, 0x0c, 0x12, 0x0d}, // '&'
+	{0x04, 0x04, 0x00, 0x00, 0x00}, // '\''
+	{0x02, 0x04, 0x04, 0x04, 0x02}, // '('
+	{0x08, 0x04, 0x04, 0x04, 0x08}, // ')'
+	{0x00, 0x0a, 0x04, 0x0a, 0x00}, // '*'
+	{0x00, 0x04, 0x0e, 0x04, 0x00}, // '+'
+	{0x00, 0x00, 0x00, 0x04, 0x08}, // ','
+	{0x00, 0x00, 0x0e, 0x00, 0x00}, // '-'
+	{0x00, 0x00, 0x00, 0x00, 0x04}, // '.'
+	{0x01, 0x02, 0x04, 0x08, 0x10}, // '/'
+	{0x0c, 0x12, 0x12, 0x12, 0x0c}, // '0'
+	{0x04, 0x0c, 0x04, 0x04, 0x0e}, // '1'
+	{0x1c, 0x02, 0x0c, 0x10, 0x1e}, // '2'
+	{0x1e, 0x02, 0x04, 0x12, 0x0c}, // '3'
+	{0x06, 0x0a, 0x12, 0x1f, 0x02}, // '4'
+	{0x1f, 0x10, 0x1e, 0x01, 0x1e}, // '5'
+	{0x02, 0x04, 0x0e, 0x11, 0x0e}, // '6'
+	{0x1f, 0x02, 0x04, 0x08, 0x10}, // '7'
+	{0x0e, 0x11, 0x0e, 0x11, 0x0e}, // '8'
+	{0x0e, 0x11, 0x0e, 0x04, 0x08}, // '9'
+	{0x00, 0x08, 0x00, 0x08, 0x00}, // ':'
+	{0x00, 0x04, 0x00, 0x04, 0x08}, // ';'
+	{0x02, 0x04, 0x08, 0x04, 0x02}, // '<'
+	{0x00, 0x0e, 0x00, 0x0e, 0x00}, // '='
+	{0x08, 0x04, 0x02, 0x04, 0x08}, // '>'
+	{0x0e, 0x01, 0x06, 0x00, 0x04}, // '?'
+	{0x0e, 0x11, 0x15, 0x16, 0x0c}, // '@'
+	{0x0c, 0x12, 0x1e, 0x12, 0x12}, // 'A'
+	{0x1c, 0x12, 0x1c, 0x12, 0x1c}, // 'B'
+	{0x0e, 0x10, 0x10, 0x10, 0x0e}, // 'C'
+	{0x1c, 0x12, 0x12, 0x12, 0x1c}, // 'D'
+	{0x1e, 0x10, 0x1c, 0x10, 0x1e}, // 'E'
+	{0x1e, 0x10, 0x1c, 0x10, 0x10}, // 'F'
+	{0x0e, 0x10, 0x13, 0x11, 0x0e}, // 'G'
+	{0x12, 0x12, 0x1e, 0x12, 0x12}, // 'H'
+	{0x1c, 0x08, 0x08, 0x08, 0x1c}, // 'I'
+	{0x1f, 0x02, 0x02, 0x12, 0x0c}, // 'J'
+	{0x12, 0x14, 0x18, 0x14, 0x12}, // 'K'
+	{0x10, 0x10, 0x10, 0x10, 0x1e}, // 'L'
+	{0x11, 0x1b, 0x15, 0x11, 0x11}, // 'M'
+	{0x11, 0x19, 0x15, 0x13, 0x11}, // 'N'
+	{0x0c, 0x12, 0x12, 0x12, 0x0c}, // 'O'
+	{0x1c, 0x12, 0x1c, 0x10, 0x10}, // 'P'
+	{0x0c, 0x12, 0x12, 0x0c, 0x02}, // 'Q'
+	{0x1c, 0x12, 0x1c, 0x14, 0x12}, // 'R'
+	{0x0e, 0x10, 0x0c, 0x02, 0x1c}, // 'S'
+	{0x1f, 0x04, 0x04, 0x04, 0x04}, // 'T'
+	{0x12, 0x12, 0x12, 0x12, 0x0c}, // 'U'
+	{0x11, 0x11, 0x11, 0x0a, 0x04}, // 'V'
+	{0x11, 0x11, 0x15, 0x1b, 0x11}, // 'W'
+	{0x12, 0x12, 0x0c, 0x12, 0x12}, // 'X'
+	{0x11, 0x0a, 0x04, 0x04, 0x04}, // 'Y'
+	{0x1e, 0x04, 0x08, 0x10, 0x1e}, // 'Z'
+	{0x0e, 0x08, 0x08, 0x08, 0x0e}, // '['
+	{0x10, 0x08, 0x04, 0x02, 0x01}, // '\\'
+	{0x0e, 0x02, 0x02, 0x02, 0x0e}, // ']'
+	{0x04, 0x0a, 0x00, 0x00, 0x00}, // '^'
+	{0x00, 0x00, 0x00, 0x00, 0x1f}, // '_'
+	{0x08, 0x04, 0x00, 0x00, 0x00}, // '`'
+	{0x00, 0x0e, 0x12, 0x12, 0x0e}, // 'a'
+	{0x10, 0x10, 0x1c, 0x12, 0x1c}, // 'b'
+	{0x00, 0x0e, 0x10, 0x10, 0x0e}, // 'c'
+	{0x02, 0x02, 0x0e, 0x12, 0x0e}, // 'd'
+	{0x0c, 0x12, 0x1c, 0x10, 0x0e}, // 'e'
+	{0x06, 0x08, 0x1c, 0x08, 0x08}, // 'f'
+	{0x0e, 0x12, 0x0e, 0x02, 0x0c}, // 'g'
+	{0x10, 0x10, 0x1c, 0x12, 0x12}, // 'h'
+	{0x08, 0x00, 0x08, 0x08, 0x08}, // 'i'
+	{0x02, 0x00, 0x02, 0x12, 0x0c}, // 'j'
+	{0x10, 0x14, 0x18, 0x14, 0x12}, // 'k'
+	{0x08, 0x08, 0x08, 0x08, 0x04}, // 'l'
+	{0x00, 0x1a, 0x15, 0x15, 0x11}, // 'm'
+	{0x00, 0x1c, 0x12, 0x12, 0x12}, // 'n'
+	{0x00, 0x0c, 0x12, 0x12, 0x0c}, // 'o'
+	{0x00, 0x1c, 0x12, 0x1c, 0x10}, // 'p'
+	{0x00, 0x0e, 0x12, 0x0e, 0x02}, // 'q'
+	{0x00, 0x0e, 0x10, 0x10, 0x10}, // 'r'
+	{0x00, 0x06, 0x08, 0x04, 0x18}, // 's'
+	{0x08, 0x1c, 0x08, 0x08, 0x06}, // 't'
+	{0x00, 0x12, 0x12, 0x12, 0x0e}, // 'u'
+	{0x00, 0x11, 0x11, 0x0a, 0x04}, // 'v'
+	{0x00, 0x11, 0x15, 0x15, 0x0a}, // 'w'
+	{0x00, 0x12, 0x0c, 0x0c, 0x12}, // 'x'
+	{0x00, 0x11, 0x0a, 0x04, 0x18}, // 'y'
+	{0x00, 0x1e, 0x04, 0x08, 0x1e}, // 'z'
+	{0x06, 0x04, 0x08, 0x04, 0x06}, // '{'
+	{0x04, 0x04, 0x04, 0x04, 0x04}, // '|'
+	{0x18, 0x04, 0x02, 0x04, 0x18}, // '}'
+	{0x00, 0x00, 0x0d, 0x12, 0x00}, // '~'
+}
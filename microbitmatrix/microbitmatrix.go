@@ -41,14 +41,54 @@ var matrixRotations = [4][5][5][2]uint8{
 	},
 }
 
+// Brightness levels of the pixels, like MicroPython's display.
+const (
+	BrightnessOff = 0
+	BrightnessMax = 9
+)
+
+// tick is the period of the RTC used by time.Sleep and time.Now on the
+// micro:bit. The brightness levels are whole numbers of ticks apart, as
+// shorter steps would collapse.
+const tick = time.Second / 32768
+
+// rowTime is the time each of the 3 rows of the matrix is lit during a scan.
+const rowTime = 64 * tick
+
+// busyWait is the end of a wait that is busy waiting instead of sleeping, as
+// time.Sleep may oversleep by a tick.
+const busyWait = 4 * tick
+
+// onTimes holds the time each brightness level is lit during the rowTime of
+// its row. The steps grow with the level, as the eye is more sensitive to
+// changes of dim lights.
+var onTimes = [BrightnessMax + 1]time.Duration{
+	0,
+	1 * tick,
+	2 * tick,
+	4 * tick,
+	7 * tick,
+	12 * tick,
+	20 * tick,
+	32 * tick,
+	48 * tick,
+	rowTime,
+}
+
 type Config struct {
 	Rotation uint8
+
+	// Background refreshes the matrix from a goroutine, so that the
+	// program does not need to call Display in a loop. The goroutine runs
+	// whenever the other goroutines sleep.
+	Background bool
 }
 
 type Device struct {
-	pin      [12]machine.Pin
-	buffer   [3][9]bool
-	rotation uint8
+	pin        [12]machine.Pin
+	buffer     [3][9]uint8 // brightness level of each LED
+	rotation   uint8
+	background bool
 }
 
 // New returns a new microbitmatrix driver.
@@ -66,6 +106,11 @@ func (d *Device) Configure(cfg Config) {
 	}
 	d.ClearDisplay()
 	d.DisableAll()
+
+	if cfg.Background && !d.background {
+		d.background = true
+		go d.refresh()
+	}
 }
 
 // SetRotation changes the rotation of the LED matrix
@@ -73,48 +118,165 @@ func (d *Device) SetRotation(rotation uint8) {
 	d.rotation = rotation % 4
 }
 
-// SetPixel modifies the internal buffer in a single pixel.
+// SetPixel modifies the internal buffer in a single pixel. The brightness is
+// taken from the brightest color component.
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
+	v := c.R
+	if c.G > v {
+		v = c.G
+	}
+	if c.B > v {
+		v = c.B
+	}
+	d.SetBrightness(x, y, uint8((uint16(v)*BrightnessMax+254)/255))
+}
+
+// SetBrightness sets the brightness level of a single pixel, from
+// BrightnessOff to BrightnessMax.
+func (d *Device) SetBrightness(x int16, y int16, level uint8) {
 	if x < 0 || x >= 5 || y < 0 || y >= 5 {
 		return
 	}
-	if c.R != 0 || c.G != 0 || c.B != 0 {
-		d.buffer[matrixRotations[d.rotation][x][y][0]][matrixRotations[d.rotation][x][y][1]] = true
-	} else {
-		d.buffer[matrixRotations[d.rotation][x][y][0]][matrixRotations[d.rotation][x][y][1]] = false
+	if level > BrightnessMax {
+		level = BrightnessMax
 	}
+	d.buffer[matrixRotations[d.rotation][x][y][0]][matrixRotations[d.rotation][x][y][1]] = level
 }
 
 // GetPixel returns if the specific pixels is enabled
 func (d *Device) GetPixel(x int16, y int16) bool {
+	return d.GetBrightness(x, y) != BrightnessOff
+}
+
+// GetBrightness returns the brightness level of a single pixel.
+func (d *Device) GetBrightness(x int16, y int16) uint8 {
 	if x < 0 || x >= 5 || y < 0 || y >= 5 {
-		return false
+		return BrightnessOff
 	}
 	return d.buffer[matrixRotations[d.rotation][x][y][0]][matrixRotations[d.rotation][x][y][1]]
 }
 
-// Display sends the buffer (if any) to the screen.
+// Display sends the buffer (if any) to the screen. It does nothing when the
+// matrix is refreshed in the background.
 func (d *Device) Display() error {
+	if d.background {
+		return nil
+	}
+	d.scan()
+	return nil
+}
+
+// scan shows every row once. The LEDs of a row are all switched on at the
+// start of the row, then switched off in turn when the on time of their
+// brightness level is over.
+func (d *Device) scan() {
 	for row := 0; row < 3; row++ {
 		d.DisableAll()
 		d.pin[9+row].High()
 
 		for col := 0; col < 9; col++ {
-			if d.buffer[row][col] {
+			if d.buffer[row][col] != BrightnessOff {
 				d.pin[col].Low()
 			}
+		}
+		start := time.Now()
+		for level := 1; level <= BrightnessMax; level++ {
+			waitUntil(start, onTimes[level])
+			for col := 0; col < 9; col++ {
+				if d.buffer[row][col] == uint8(level) {
+					d.pin[col].High()
+				}
+			}
+		}
+	}
+	d.DisableAll()
+}
 
+// waitUntil waits until d has elapsed since start. The waits are measured
+// from the start of the row so that the rounding to ticks does not add up.
+func waitUntil(start time.Time, d time.Duration) {
+	for {
+		left := d - time.Since(start)
+		if left <= 0 {
+			return
+		}
+		if left > busyWait {
+			time.Sleep(left - busyWait)
 		}
-		time.Sleep(time.Millisecond * 2)
 	}
-	return nil
+}
+
+// refresh scans the matrix forever, see Config.Background.
+func (d *Device) refresh() {
+	for {
+		d.scan()
+	}
+}
+
+// wait waits for the given duration, refreshing the matrix if it is not
+// refreshed in the background.
+func (d *Device) wait(duration time.Duration) {
+	if d.background {
+		time.Sleep(duration)
+		return
+	}
+	start := time.Now()
+	for time.Since(start) < duration {
+		d.scan()
+	}
+}
+
+// DrawChar draws a character of the built-in 5x5 font with its left column at
+// x, at the given brightness level. The parts outside of the matrix are
+// clipped, and unknown characters are drawn as '?'.
+func (d *Device) DrawChar(x int16, c rune, level uint8) {
+	if c < ' ' || c > '~' {
+		c = '?'
+	}
+	glyph := &font[c-' ']
+	for y := int16(0); y < 5; y++ {
+		for col := int16(0); col < 5; col++ {
+			if glyph[y]&(0x10>>col) != 0 {
+				d.SetBrightness(x+col, y, level)
+			}
+		}
+	}
+}
+
+// Show clears the matrix and shows a single character of the built-in font
+// at full brightness.
+func (d *Device) Show(c rune) {
+	d.ClearDisplay()
+	d.DrawChar(0, c, BrightnessMax)
+}
+
+// ScrollText scrolls the text from right to left at full brightness, moving
+// one column every delay, and returns once it is gone. Without background
+// refresh, the matrix is refreshed while waiting.
+func (d *Device) ScrollText(text string, delay time.Duration) {
+	// every character takes 5 columns plus one column of spacing
+	var chars int16
+	for range text {
+		chars++
+	}
+	for shift := int16(0); shift <= chars*6+5; shift++ {
+		d.ClearDisplay()
+		x := 5 - shift
+		for _, c := range text {
+			if x > -6 && x < 5 {
+				d.DrawChar(x, c, BrightnessMax)
+			}
+			x += 6
+		}
+		d.wait(delay)
+	}
 }
 
 // ClearDisplay erases the internal buffer
 func (d *Device) ClearDisplay() {
 	for row := 0; row < 3; row++ {
 		for col := 0; col < 9; col++ {
-			d.buffer[row][col] = false
+			d.buffer[row][col] = BrightnessOff
 		}
 	}
 }
@@ -0,0 +1,38 @@
+package net
+
+import "time"
+
+// pollInterval is how long the blocking operations wait between polls of
+// the device.
+const pollInterval = 10 * time.Millisecond
+
+// now and sleep are the clock used by the deadlines, replaced in tests.
+var (
+	now   = time.Now
+	sleep = time.Sleep
+)
+
+// Error represents a network error.
+// This interface is from the Go standard library.
+type Error interface {
+	error
+	Timeout() bool   // Is the error a timeout?
+	Temporary() bool // Is the error temporary?
+}
+
+// ErrDeadlineExceeded is returned by the operations of connections and
+// listeners once their deadline has passed. It is a net.Error with
+// Timeout() == true.
+var ErrDeadlineExceeded error = &timeoutError{}
+
+type timeoutError struct{}
+
+func (e *timeoutError) Error() string   { return "i/o timeout" }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+// expired returns whether the deadline has passed. A zero deadline never
+// expires.
+func expired(deadline time.Time) bool {
+	return !deadline.IsZero() && !now().Before(deadline)
+}
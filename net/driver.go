@@ -1,19 +1,32 @@
 package net
 
+import "errors"
+
+// Socket is the handle of a socket opened by a DeviceDriver. Drivers
+// supporting several sockets at once return a different handle for each of
+// them.
+type Socket int
+
+var (
+	// ErrNoSocket is returned by the drivers when all their sockets are in use.
+	ErrNoSocket = errors.New("no socket available")
+
+	// ErrNoConnection is returned by AcceptSocket when no client is waiting
+	// to be accepted.
+	ErrNoConnection = errors.New("no connection pending")
+)
+
 type DeviceDriver interface {
 	GetDNS(domain string) (string, error)
-	ConnectTCPSocket(addr, port string) error
-	ConnectSSLSocket(addr, port string) error
-	ConnectUDPSocket(addr, sendport, listenport string) error
-	DisconnectSocket() error
-	StartSocketSend(size int) error
-	Write(b []byte) (n int, err error)
-	ReadSocket(b []byte) (n int, err error)
-	IsSocketDataAvailable() bool
-
-	// FIXME: this is really specific to espat, and maybe shouldn't be part
-	// of the driver interface
-	Response(timeout int) ([]byte, error)
+	ConnectTCPSocket(addr, port string) (Socket, error)
+	ConnectSSLSocket(addr, port string) (Socket, error)
+	ConnectUDPSocket(addr, sendport, listenport string) (Socket, error)
+	ListenTCPSocket(port string) (Socket, error)
+	AcceptSocket(listener Socket) (Socket, error)
+	DisconnectSocket(sock Socket) error
+	WriteSocket(sock Socket, b []byte) (n int, err error)
+	ReadSocket(sock Socket, b []byte) (n int, err error)
+	IsSocketDataAvailable(sock Socket) bool
 }
 
 var ActiveDevice DeviceDriver
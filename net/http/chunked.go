@@ -0,0 +1,108 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// chunkedReader decodes a body with the chunked transfer encoding.
+type chunkedReader struct {
+	r    *bufio.Reader
+	n    uint64 // bytes left in the current chunk
+	err  error
+	more bool // whether a chunk has been started
+}
+
+func newChunkedReader(r *bufio.Reader) *chunkedReader {
+	return &chunkedReader{r: r}
+}
+
+func (cr *chunkedReader) Read(b []byte) (int, error) {
+	if cr.err != nil {
+		return 0, cr.err
+	}
+	if cr.n == 0 {
+		cr.err = cr.beginChunk()
+		if cr.err != nil {
+			return 0, cr.err
+		}
+	}
+	if uint64(len(b)) > cr.n {
+		b = b[:cr.n]
+	}
+	n, err := cr.r.Read(b)
+	cr.n -= uint64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	cr.err = err
+	return n, err
+}
+
+// beginChunk reads the size of the next chunk, after the end of the current
+// one. It returns io.EOF after the last chunk and the trailer.
+func (cr *chunkedReader) beginChunk() error {
+	if cr.more {
+		// the CRLF ending the data of the previous chunk
+		if line, err := readLine(cr.r); err != nil {
+			return err
+		} else if line != "" {
+			return ErrMalformedChunk
+		}
+	}
+	cr.more = true
+
+	line, err := readLine(cr.r)
+	if err != nil {
+		return err
+	}
+	// ignore the chunk extensions
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	cr.n, err = strconv.ParseUint(strings.TrimSpace(line), 16, 63)
+	if err != nil {
+		return ErrMalformedChunk
+	}
+	if cr.n > 0 {
+		return nil
+	}
+
+	// the last chunk is followed by the trailer, which is discarded
+	for {
+		line, err := readLine(cr.r)
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			return io.EOF
+		}
+	}
+}
+
+// chunkedWriter encodes a body with the chunked transfer encoding. Each
+// call to Write writes a chunk, and Close writes the last chunk.
+type chunkedWriter struct {
+	w *bufio.Writer
+}
+
+func (cw chunkedWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	cw.w.WriteString(strconv.FormatInt(int64(len(b)), 16))
+	cw.w.WriteString("\r\n")
+	cw.w.Write(b)
+	_, err := cw.w.WriteString("\r\n")
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (cw chunkedWriter) Close() error {
+	_, err := cw.w.WriteString("0\r\n\r\n")
+	return err
+}
@@ -0,0 +1,261 @@
+// Package http is intended to provide a minimal set of compatible interfaces
+// with the Go standard library's net/http package.
+//
+// The client sends HTTP/1.1 requests over the connections of the net and
+// net/tls packages, one connection per request, and streams the responses
+// with fixed size buffers. The server handles the requests of the
+// connections accepted by a net.Listener in the same way.
+//
+package http // import "tinygo.org/x/drivers/net/http"
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tinygo.org/x/drivers/net"
+	"tinygo.org/x/drivers/net/tls"
+)
+
+const (
+	// bufferSize is the size of the buffers used to read and write the
+	// connections, which is also the maximum length of a header line.
+	bufferSize = 512
+
+	// maxHeaderLines is the maximum number of lines of a header.
+	maxHeaderLines = 64
+
+	// maxRedirects is the number of redirects followed by default.
+	maxRedirects = 10
+
+	// pollInterval is how long reads wait between polls of the connection.
+	pollInterval = 10 * time.Millisecond
+
+	userAgent = "TinyGo"
+)
+
+var (
+	ErrUnsupportedScheme = errors.New("http: unsupported protocol scheme")
+	ErrLineTooLong       = errors.New("http: line too long")
+	ErrHeaderTooLong     = errors.New("http: too many header lines")
+	ErrMalformedHeader   = errors.New("http: malformed header")
+	ErrMalformedStatus   = errors.New("http: malformed status line")
+	ErrMalformedRequest  = errors.New("http: malformed request line")
+	ErrMalformedChunk    = errors.New("http: malformed chunked encoding")
+	ErrContentLength     = errors.New("http: body length does not match ContentLength")
+	ErrFormTooLarge      = errors.New("http: form too large")
+	ErrTooManyRedirects  = errors.New("http: stopped after " + strconv.Itoa(maxRedirects) + " redirects")
+
+	// ErrUseLastResponse can be returned by Client.CheckRedirect hooks to
+	// control how redirects are processed. If returned, the next request
+	// is not sent and the most recent response is returned with its body
+	// unclosed.
+	ErrUseLastResponse = errors.New("http: use last response")
+)
+
+// A Client is an HTTP client.
+type Client struct {
+	// Timeout specifies a time limit for requests made by this Client,
+	// including connection time, any redirects, and reading the response
+	// body. A Timeout of zero means no timeout.
+	//
+	// As the connections of some devices are never reported closed by the
+	// peer, responses without a length may only end with the timeout.
+	Timeout time.Duration
+
+	// CheckRedirect specifies the policy for handling redirects. If
+	// CheckRedirect is nil, the Client stops after 10 consecutive requests.
+	CheckRedirect func(req *Request, via []*Request) error
+}
+
+// DefaultClient is the default Client and is used by Get and Post.
+var DefaultClient = &Client{}
+
+// Get issues a GET to the specified URL with the DefaultClient.
+func Get(url string) (*Response, error) {
+	return DefaultClient.Get(url)
+}
+
+// Post issues a POST to the specified URL with the DefaultClient.
+func Post(url, contentType string, body io.Reader) (*Response, error) {
+	return DefaultClient.Post(url, contentType, body)
+}
+
+// Get issues a GET to the specified URL, following redirects.
+func (c *Client) Get(url string) (*Response, error) {
+	req, err := NewRequest(MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Post issues a POST to the specified URL. Redirects are followed with GET
+// requests as with Do.
+func (c *Client) Post(url, contentType string, body io.Reader) (*Response, error) {
+	req, err := NewRequest(MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// Do sends an HTTP request and returns an HTTP response, following
+// redirects: 301, 302 and 303 redirects are followed with a GET request,
+// and 307 and 308 redirects are followed with the same request if it has no
+// body.
+//
+// The caller must close the body of the response.
+func (c *Client) Do(req *Request) (*Response, error) {
+	var deadline time.Time
+	if c.Timeout > 0 {
+		deadline = time.Now().Add(c.Timeout)
+	}
+	var via []*Request
+	for {
+		resp, err := c.send(req, deadline)
+		if err != nil {
+			return nil, err
+		}
+
+		next := redirect(req, resp)
+		if next == nil {
+			return resp, nil
+		}
+		via = append(via, req)
+		if err := c.checkRedirect(next, via); err != nil {
+			if err == ErrUseLastResponse {
+				return resp, nil
+			}
+			resp.Body.Close()
+			return nil, err
+		}
+		resp.Body.Close()
+		req = next
+	}
+}
+
+func (c *Client) checkRedirect(req *Request, via []*Request) error {
+	if c.CheckRedirect != nil {
+		return c.CheckRedirect(req, via)
+	}
+	if len(via) >= maxRedirects {
+		return ErrTooManyRedirects
+	}
+	return nil
+}
+
+// redirect returns the request following a redirect response, or nil if the
+// response is not to be followed.
+func redirect(req *Request, resp *Response) *Request {
+	method := req.Method
+	switch resp.StatusCode {
+	case StatusMovedPermanently, StatusFound, StatusSeeOther:
+		if method != MethodGet && method != MethodHead {
+			method = MethodGet
+		}
+	case StatusTemporaryRedirect, StatusPermanentRedirect:
+		if req.Body != nil {
+			// the body can not be sent again
+			return nil
+		}
+	default:
+		return nil
+	}
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		return nil
+	}
+	u, err := req.URL.Parse(loc)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	next := &Request{Method: method, URL: u, Header: req.Header.Clone()}
+	if next.Header == nil {
+		next.Header = make(Header)
+	}
+	if method != req.Method {
+		// the body is not sent again
+		next.Header.Del("Content-Type")
+	}
+	if u.Host == req.URL.Host {
+		next.Host = req.Host
+	} else {
+		// do not leak the credentials to another host
+		for _, key := range sensitiveHeaders {
+			next.Header.Del(key)
+		}
+	}
+	return next
+}
+
+// sensitiveHeaders are the headers not forwarded on redirects to another
+// host.
+var sensitiveHeaders = []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"}
+
+// send sends a single request over a new connection.
+func (c *Client) send(req *Request, deadline time.Time) (*Response, error) {
+	conn, err := dial(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(deadline)
+
+	if err := req.Write(bufio.NewWriterSize(conn, bufferSize)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := ReadResponse(bufio.NewReaderSize(connReader{conn}, bufferSize), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body = &body{Reader: resp.Body, conn: conn}
+	return resp, nil
+}
+
+// dial connects to the host of the URL.
+func dial(u *url.URL) (net.Conn, error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = strings.TrimSuffix(host, ":")
+		if u.Scheme == "https" {
+			host += ":443"
+		} else {
+			host += ":80"
+		}
+	}
+	switch u.Scheme {
+	case "http":
+		return net.Dial("tcp", host)
+	case "https":
+		conn, err := tls.Dial("tcp", host, nil)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	default:
+		return nil, ErrUnsupportedScheme
+	}
+}
+
+// connReader waits for the data of the connection, as the reads of the
+// connections return as soon as no data has been received yet.
+type connReader struct {
+	conn net.Conn
+}
+
+func (r connReader) Read(b []byte) (int, error) {
+	for {
+		n, err := r.conn.Read(b)
+		if n > 0 || err != nil || len(b) == 0 {
+			return n, err
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// maxFormSize is the maximum size of the form data of a request body.
+const maxFormSize = 1024
+
+// ParseForm populates r.Form and r.PostForm.
+//
+// For all requests, ParseForm parses the raw query from the URL and updates
+// r.Form. For POST, PUT, and PATCH requests with a
+// application/x-www-form-urlencoded body, it also reads the body, up to
+// 1024 bytes, parses it as a form and puts the results into both r.PostForm
+// and r.Form. Request body parameters take precedence over URL query string
+// values in r.Form.
+func (r *Request) ParseForm() error {
+	if r.PostForm == nil {
+		r.PostForm = make(url.Values)
+		if r.Method == MethodPost || r.Method == MethodPut || r.Method == MethodPatch {
+			if err := r.parsePostForm(); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Form == nil {
+		var err error
+		r.Form, err = url.ParseQuery(r.URL.RawQuery)
+		if err != nil {
+			return err
+		}
+		for k, v := range r.PostForm {
+			r.Form[k] = append(v, r.Form[k]...)
+		}
+	}
+	return nil
+}
+
+func (r *Request) parsePostForm() error {
+	if r.Body == nil {
+		return nil
+	}
+	ct, _, _ := cut(r.Header.Get("Content-Type"), ';')
+	if !strings.EqualFold(strings.TrimSpace(ct), "application/x-www-form-urlencoded") {
+		return nil
+	}
+	if r.ContentLength > maxFormSize {
+		return ErrFormTooLarge
+	}
+	// the data is read on the heap, as goroutine stacks are small
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxFormSize+1))
+	if err != nil {
+		return err
+	}
+	if len(b) > maxFormSize {
+		return ErrFormTooLarge
+	}
+	r.PostForm, err = url.ParseQuery(string(b))
+	return err
+}
+
+// FormValue returns the first value for the named component of the query or
+// form data, calling ParseForm if necessary. It returns the empty string if
+// there is no such component.
+func (r *Request) FormValue(key string) string {
+	if r.Form == nil {
+		r.ParseForm()
+	}
+	return r.Form.Get(key)
+}
+
+// PostFormValue returns the first value for the named component of the form
+// data of the body, calling ParseForm if necessary.
+func (r *Request) PostFormValue(key string) string {
+	if r.PostForm == nil {
+		r.ParseForm()
+	}
+	return r.PostForm.Get(key)
+}
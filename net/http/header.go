@@ -0,0 +1,136 @@
+package http
+
+import (
+	"bufio"
+	"strings"
+)
+
+// A Header represents the key-value pairs in an HTTP header.
+// The keys should be in canonical form, as returned by CanonicalHeaderKey.
+type Header map[string][]string
+
+// Add adds the key, value pair to the header.
+// It appends to any existing values associated with key.
+func (h Header) Add(key, value string) {
+	key = CanonicalHeaderKey(key)
+	h[key] = append(h[key], value)
+}
+
+// Set sets the header entries associated with key to the single element
+// value. It replaces any existing values associated with key.
+func (h Header) Set(key, value string) {
+	h[CanonicalHeaderKey(key)] = []string{value}
+}
+
+// Get gets the first value associated with the given key. If there are no
+// values associated with the key, Get returns "".
+func (h Header) Get(key string) string {
+	if v := h[CanonicalHeaderKey(key)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Values returns all values associated with the given key.
+func (h Header) Values(key string) []string {
+	return h[CanonicalHeaderKey(key)]
+}
+
+// Del deletes the values associated with key.
+func (h Header) Del(key string) {
+	delete(h, CanonicalHeaderKey(key))
+}
+
+// Clone returns a copy of h or nil if h is nil.
+func (h Header) Clone() Header {
+	if h == nil {
+		return nil
+	}
+	h2 := make(Header, len(h))
+	for k, v := range h {
+		h2[k] = append([]string(nil), v...)
+	}
+	return h2
+}
+
+// write writes the header lines, without the final empty line.
+func (h Header) write(w *bufio.Writer) {
+	for key, values := range h {
+		for _, v := range values {
+			w.WriteString(key)
+			w.WriteString(": ")
+			w.WriteString(v)
+			w.WriteString("\r\n")
+		}
+	}
+}
+
+// CanonicalHeaderKey returns the canonical format of the header key s.
+// The canonicalization converts the first letter and any letter following a
+// hyphen to upper case; the rest are converted to lowercase. For example,
+// the canonical key for "accept-encoding" is "Accept-Encoding".
+func CanonicalHeaderKey(s string) string {
+	upper := true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if upper && 'a' <= c && c <= 'z' || !upper && 'A' <= c && c <= 'Z' {
+			return canonicalHeaderKey(s)
+		}
+		upper = c == '-'
+	}
+	return s
+}
+
+func canonicalHeaderKey(s string) string {
+	b := []byte(s)
+	upper := true
+	for i, c := range b {
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		} else if !upper && 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b[i] = c
+		upper = c == '-'
+	}
+	return string(b)
+}
+
+// readHeader reads the header lines up to the empty line ending them.
+func readHeader(r *bufio.Reader) (Header, error) {
+	h := make(Header)
+	for i := 0; ; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			return h, nil
+		}
+		if i == maxHeaderLines {
+			return nil, ErrHeaderTooLong
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon <= 0 {
+			return nil, ErrMalformedHeader
+		}
+		h.Add(strings.TrimSpace(line[:colon]), strings.TrimSpace(line[colon+1:]))
+	}
+}
+
+// readLine reads a line ending with "\r\n" or "\n", up to the size of the
+// buffer of r.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", ErrLineTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return string(line), nil
+}
@@ -0,0 +1,358 @@
+package http_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/net"
+	"tinygo.org/x/drivers/net/http"
+	"tinygo.org/x/drivers/net/loopback"
+)
+
+func newServer(c *qt.C, h stdhttp.HandlerFunc) *httptest.Server {
+	c.Patch(&net.ActiveDevice, net.DeviceDriver(loopback.New()))
+	srv := httptest.NewServer(h)
+	c.Defer(srv.Close)
+	return srv
+}
+
+func readBody(c *qt.C, resp *http.Response) string {
+	b, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, qt.IsNil)
+	c.Assert(resp.Body.Close(), qt.IsNil)
+	return string(b)
+}
+
+func TestGet(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		c.Check(r.Method, qt.Equals, "GET")
+		c.Check(r.URL.String(), qt.Equals, "/path?q=1")
+		c.Check(r.Header.Get("User-Agent"), qt.Equals, "TinyGo")
+		w.Header().Set("Content-Length", "5")
+		w.Header().Set("X-Test", "yes")
+		io.WriteString(w, "hello")
+	})
+
+	resp, err := http.Get(srv.URL + "/path?q=1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(resp.StatusCode, qt.Equals, 200)
+	c.Assert(resp.Status, qt.Equals, "200 OK")
+	c.Assert(resp.Proto, qt.Equals, "HTTP/1.1")
+	c.Assert(resp.ContentLength, qt.Equals, int64(5))
+	c.Assert(resp.Header.Get("x-test"), qt.Equals, "yes")
+	c.Assert(readBody(c, resp), qt.Equals, "hello")
+}
+
+func TestGetTLS(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	d := loopback.New()
+	c.Patch(&net.ActiveDevice, net.DeviceDriver(d))
+	srv := httptest.NewTLSServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		io.WriteString(w, "secure")
+	}))
+	defer srv.Close()
+	d.TLSConfig = srv.Client().Transport.(*stdhttp.Transport).TLSClientConfig
+
+	c.Assert(strings.HasPrefix(srv.URL, "https://"), qt.IsTrue)
+	resp, err := http.Get(srv.URL)
+	c.Assert(err, qt.IsNil)
+	c.Assert(readBody(c, resp), qt.Equals, "secure")
+}
+
+func TestChunkedResponse(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	part := strings.Repeat("0123456789", 100)
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, part)
+			w.(stdhttp.Flusher).Flush()
+		}
+	})
+
+	resp, err := http.Get(srv.URL)
+	c.Assert(err, qt.IsNil)
+	c.Assert(resp.TransferEncoding, qt.Equals, "chunked")
+	c.Assert(resp.ContentLength, qt.Equals, int64(-1))
+	c.Assert(readBody(c, resp), qt.Equals, part+part+part)
+}
+
+func TestPost(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, r.Header.Get("Content-Type")+" "+strings.Join(r.TransferEncoding, ",")+" "+string(b))
+	})
+
+	// known length
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{"a":1}`))
+	c.Assert(err, qt.IsNil)
+	c.Assert(readBody(c, resp), qt.Equals, `application/json  {"a":1}`)
+
+	// unknown length
+	resp, err = http.Post(srv.URL, "text/plain", io.MultiReader(strings.NewReader("one "), strings.NewReader("two")))
+	c.Assert(err, qt.IsNil)
+	c.Assert(readBody(c, resp), qt.Equals, "text/plain chunked one two")
+}
+
+func TestDoHeaders(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header()["Set-Cookie"] = []string{"a=1", "b=2"}
+		w.WriteHeader(stdhttp.StatusAccepted)
+		io.WriteString(w, r.Method+" "+r.Host+" "+r.Header.Get("Authorization"))
+	})
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/x", bytes.NewBufferString("data"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(req.ContentLength, qt.Equals, int64(4))
+	req.Header.Set("authorization", "Bearer token")
+	req.Host = "example.com"
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, qt.IsNil)
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusAccepted)
+	c.Assert(resp.Header.Values("Set-Cookie"), qt.DeepEquals, []string{"a=1", "b=2"})
+	c.Assert(readBody(c, resp), qt.Equals, "PUT example.com Bearer token")
+}
+
+func TestRedirect(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch r.URL.Path {
+		case "/old":
+			stdhttp.Redirect(w, r, "/new", stdhttp.StatusFound)
+		case "/loop":
+			stdhttp.Redirect(w, r, "/loop", stdhttp.StatusTemporaryRedirect)
+		default:
+			io.WriteString(w, r.Method+" "+r.URL.Path)
+		}
+	})
+
+	// POST redirected with a GET
+	resp, err := http.Post(srv.URL+"/old", "text/plain", strings.NewReader("x"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(resp.Request.URL.Path, qt.Equals, "/new")
+	c.Assert(readBody(c, resp), qt.Equals, "GET /new")
+
+	_, err = http.Get(srv.URL + "/loop")
+	c.Assert(err, qt.Equals, http.ErrTooManyRedirects)
+
+	cl := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = cl.Get(srv.URL + "/old")
+	c.Assert(err, qt.IsNil)
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusFound)
+	c.Assert(resp.Header.Get("Location"), qt.Equals, "/new")
+	resp.Body.Close()
+}
+
+func TestRedirectHeaders(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	echo := func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		io.WriteString(w, r.Method+" ["+r.Header.Get("Authorization")+"] ["+
+			r.Header.Get("Cookie")+"] ["+r.Header.Get("Content-Type")+"] ["+
+			r.Header.Get("X-Test")+"]")
+	}
+	other := newServer(c, echo)
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch r.URL.Path {
+		case "/same":
+			stdhttp.Redirect(w, r, "/echo", stdhttp.StatusSeeOther)
+		case "/other":
+			stdhttp.Redirect(w, r, other.URL+"/echo", stdhttp.StatusFound)
+		default:
+			echo(w, r)
+		}
+	})
+
+	for _, test := range []struct {
+		path string
+		want string
+	}{
+		{"/same", "GET [secret] [a=1] [] [yes]"},
+		{"/other", "GET [] [] [] [yes]"},
+	} {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+test.path, strings.NewReader("x"))
+		c.Assert(err, qt.IsNil)
+		req.Header.Set("Authorization", "secret")
+		req.Header.Set("Cookie", "a=1")
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("X-Test", "yes")
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, qt.IsNil)
+		c.Assert(readBody(c, resp), qt.Equals, test.want)
+
+		// the header of the original request is left as is
+		c.Assert(req.Header.Get("Content-Type"), qt.Equals, "text/plain")
+		c.Assert(req.Header.Get("Authorization"), qt.Equals, "secret")
+	}
+}
+
+func TestTruncatedBody(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Length", "10")
+		io.WriteString(w, "hello")
+		// the connection is closed before the end of the body
+	})
+
+	resp, err := http.Get(srv.URL)
+	c.Assert(err, qt.IsNil)
+	b, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, qt.Equals, io.ErrUnexpectedEOF)
+	c.Assert(string(b), qt.Equals, "hello")
+	resp.Body.Close()
+}
+
+func TestBodyUntilClose(t *testing.T) {
+	c := qt.New(t)
+	for _, noEOF := range []bool{false, true} {
+		name := "EOF"
+		if noEOF {
+			name = "NoEOF"
+		}
+		c.Run(name, func(c *qt.C) {
+			defer c.Done()
+			srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				// no length, the body ends when the connection is closed
+				conn, rw, err := w.(stdhttp.Hijacker).Hijack()
+				c.Check(err, qt.IsNil)
+				rw.WriteString("HTTP/1.1 200 OK\r\nConnection: close\r\n\r\nhello")
+				rw.Flush()
+				conn.Close()
+			})
+			net.ActiveDevice.(*loopback.Driver).NoEOF = noEOF
+
+			cl := &http.Client{Timeout: time.Second}
+			resp, err := cl.Get(srv.URL)
+			c.Assert(err, qt.IsNil)
+			c.Assert(resp.ContentLength, qt.Equals, int64(-1))
+			b, err := ioutil.ReadAll(resp.Body)
+			if noEOF {
+				// as with the devices, only the timeout ends the body
+				c.Assert(err, qt.Equals, net.ErrDeadlineExceeded)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+			c.Assert(string(b), qt.Equals, "hello")
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestHead(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Length", "100")
+	})
+
+	req, err := http.NewRequest(http.MethodHead, srv.URL, nil)
+	c.Assert(err, qt.IsNil)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, qt.IsNil)
+	c.Assert(readBody(c, resp), qt.Equals, "")
+}
+
+func TestTimeout(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	done := make(chan struct{})
+	srv := newServer(c, func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		<-done
+	})
+	defer close(done)
+
+	cl := &http.Client{Timeout: 100 * time.Millisecond}
+	_, err := cl.Get(srv.URL)
+	c.Assert(err, qt.Equals, net.ErrDeadlineExceeded)
+}
+
+func TestUnsupportedScheme(t *testing.T) {
+	c := qt.New(t)
+	_, err := http.Get("ftp://example.com/")
+	c.Assert(err, qt.Equals, http.ErrUnsupportedScheme)
+}
+
+func TestReadResponse(t *testing.T) {
+	c := qt.New(t)
+	tests := []struct {
+		about string
+		raw   string
+		body  string
+		err   error
+	}{{
+		about: "chunked with extensions and trailer",
+		raw:   "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n3;ext=1\r\nabc\r\n2\r\nde\r\n0\r\nTrailer: x\r\n\r\n",
+		body:  "abcde",
+	}, {
+		about: "bare line feeds, body up to the end",
+		raw:   "HTTP/1.0 200 OK\nServer: test\n\nthe end",
+		body:  "the end",
+	}, {
+		about: "informational response skipped",
+		raw:   "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 201 Created\r\nContent-Length: 2\r\n\r\nokEXTRA",
+		body:  "ok",
+	}, {
+		about: "header line too long",
+		raw:   "HTTP/1.1 200 OK\r\nX-Long: " + strings.Repeat("x", 600) + "\r\n\r\n",
+		err:   http.ErrLineTooLong,
+	}, {
+		about: "too many header lines",
+		raw:   "HTTP/1.1 200 OK\r\n" + strings.Repeat("X: y\r\n", 65) + "\r\n",
+		err:   http.ErrHeaderTooLong,
+	}, {
+		about: "malformed status",
+		raw:   "HTTP/1.1 OK\r\n\r\n",
+		err:   http.ErrMalformedStatus,
+	}, {
+		about: "malformed header",
+		raw:   "HTTP/1.1 200 OK\r\nno colon\r\n\r\n",
+		err:   http.ErrMalformedHeader,
+	}}
+	for _, test := range tests {
+		c.Run(test.about, func(c *qt.C) {
+			r := bufio.NewReaderSize(strings.NewReader(test.raw), 512)
+			resp, err := http.ReadResponse(r, nil)
+			if test.err != nil {
+				c.Assert(err, qt.Equals, test.err)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(readBody(c, resp), qt.Equals, test.body)
+		})
+	}
+}
+
+func TestMalformedChunk(t *testing.T) {
+	c := qt.New(t)
+	raw := "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\n"
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(raw)), nil)
+	c.Assert(err, qt.IsNil)
+	_, err = ioutil.ReadAll(resp.Body)
+	c.Assert(err, qt.Equals, http.ErrMalformedChunk)
+}
+
+func TestCanonicalHeaderKey(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(http.CanonicalHeaderKey("content-TYPE"), qt.Equals, "Content-Type")
+	c.Assert(http.CanonicalHeaderKey("X-Already-Canonical"), qt.Equals, "X-Already-Canonical")
+}
@@ -0,0 +1,232 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// A Request represents an HTTP request sent by a client or received by a
+// server.
+type Request struct {
+	// Method specifies the HTTP method (GET, POST, PUT, etc.).
+	// An empty string means GET.
+	Method string
+
+	// URL specifies the URI being requested.
+	URL *url.URL
+
+	// Header contains the request header fields.
+	Header Header
+
+	// Body is the request's body, or nil for none.
+	Body io.Reader
+
+	// ContentLength records the length of Body. For client requests, if it
+	// is 0 with a non-nil Body, the length is unknown and the body is sent
+	// with the chunked transfer encoding. For server requests, it is -1
+	// if the length is unknown.
+	ContentLength int64
+
+	// Host optionally overrides the Host header to send. If empty, the
+	// host of the URL is used. For server requests, it is the value of the
+	// Host header.
+	Host string
+
+	// The following fields are only set for server requests.
+
+	// Proto is the protocol version of the request, e.g. "HTTP/1.1".
+	Proto string
+
+	// RequestURI is the unmodified request target of the request line.
+	RequestURI string
+
+	// RemoteAddr is the network address of the client, if known.
+	RemoteAddr string
+
+	// Form contains the parsed query parameters and form data, after
+	// ParseForm is called.
+	Form url.Values
+
+	// PostForm contains the parsed form data of POST, PATCH and PUT
+	// requests, after ParseForm is called.
+	PostForm url.Values
+}
+
+// NewRequest returns a new Request given a method, URL, and optional body.
+//
+// If body is of type *bytes.Buffer, *bytes.Reader, or *strings.Reader, the
+// returned request's ContentLength is set to its exact value.
+func NewRequest(method, rawurl string, body io.Reader) (*Request, error) {
+	if method == "" {
+		method = MethodGet
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrUnsupportedScheme
+	}
+	req := &Request{
+		Method: method,
+		URL:    u,
+		Header: make(Header),
+		Body:   body,
+	}
+	switch b := body.(type) {
+	case *bytes.Buffer:
+		req.ContentLength = int64(b.Len())
+	case *bytes.Reader:
+		req.ContentLength = int64(b.Len())
+	case *strings.Reader:
+		req.ContentLength = int64(b.Len())
+	}
+	if req.ContentLength == 0 && body != nil {
+		switch body.(type) {
+		case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+			req.Body = nil
+		}
+	}
+	return req, nil
+}
+
+// host returns the host to send in the Host header.
+func (r *Request) host() string {
+	if r.Host != "" {
+		return r.Host
+	}
+	return r.URL.Host
+}
+
+// Write writes an HTTP/1.1 request, which is the header and body, in wire
+// format. The connection is closed by the server after the response.
+func (r *Request) Write(w io.Writer) error {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriterSize(w, bufferSize)
+	}
+
+	method := r.Method
+	if method == "" {
+		method = MethodGet
+	}
+	bw.WriteString(method)
+	bw.WriteString(" ")
+	bw.WriteString(r.URL.RequestURI())
+	bw.WriteString(" HTTP/1.1\r\nHost: ")
+	bw.WriteString(r.host())
+	bw.WriteString("\r\n")
+	if r.Header.Get("User-Agent") == "" {
+		bw.WriteString("User-Agent: " + userAgent + "\r\n")
+	}
+	bw.WriteString("Connection: close\r\n")
+	chunked := r.Body != nil && r.ContentLength == 0
+	if chunked {
+		bw.WriteString("Transfer-Encoding: chunked\r\n")
+	} else if r.Body != nil || method == MethodPost || method == MethodPut || method == MethodPatch {
+		bw.WriteString("Content-Length: ")
+		bw.WriteString(strconv.FormatInt(r.ContentLength, 10))
+		bw.WriteString("\r\n")
+	}
+	r.Header.write(bw)
+	bw.WriteString("\r\n")
+
+	if r.Body != nil {
+		if chunked {
+			cw := chunkedWriter{bw}
+			if err := copyBody(cw, r.Body); err != nil {
+				return err
+			}
+			cw.Close()
+		} else {
+			n, err := io.Copy(bw, io.LimitReader(r.Body, r.ContentLength))
+			if err != nil {
+				return err
+			}
+			if n != r.ContentLength {
+				return ErrContentLength
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// copyBody copies r to w with a small buffer.
+func copyBody(w io.Writer, r io.Reader) error {
+	var buf [128]byte
+	_, err := io.CopyBuffer(w, r, buf[:])
+	return err
+}
+
+// errHeaderLineTooLong is returned by readRequest when a header line does not
+// fit in the buffer, unlike the request line.
+var errHeaderLineTooLong = errors.New("http: header line too long")
+
+// ReadRequest reads and parses an incoming request from r. The request line
+// and the header lines must fit in the buffer of r.
+func ReadRequest(r *bufio.Reader) (*Request, error) {
+	req, err := readRequest(r)
+	if err == errHeaderLineTooLong {
+		err = ErrLineTooLong
+	}
+	return req, err
+}
+
+// readRequest is ReadRequest, telling the header lines too long apart from
+// the request line.
+func readRequest(r *bufio.Reader) (*Request, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	req := &Request{}
+	var ok bool
+	req.Method, line, ok = cut(line, ' ')
+	if !ok {
+		return nil, ErrMalformedRequest
+	}
+	req.RequestURI, req.Proto, ok = cut(line, ' ')
+	if !ok || !strings.HasPrefix(req.Proto, "HTTP/1.") {
+		return nil, ErrMalformedRequest
+	}
+	if req.URL, err = url.ParseRequestURI(req.RequestURI); err != nil {
+		return nil, ErrMalformedRequest
+	}
+
+	req.Header, err = readHeader(r)
+	if err == ErrLineTooLong {
+		return nil, errHeaderLineTooLong
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Host = req.Header.Get("Host")
+
+	switch {
+	case strings.Contains(strings.ToLower(req.Header.Get("Transfer-Encoding")), "chunked"):
+		req.ContentLength = -1
+		req.Body = newChunkedReader(r)
+	case req.Header.Get("Content-Length") != "":
+		req.ContentLength, err = strconv.ParseInt(req.Header.Get("Content-Length"), 10, 64)
+		if err != nil || req.ContentLength < 0 {
+			return nil, ErrMalformedHeader
+		}
+		if req.ContentLength > 0 {
+			req.Body = &lengthReader{r: r, n: req.ContentLength}
+		}
+	}
+	return req, nil
+}
+
+// cut slices s around the first instance of sep.
+func cut(s string, sep byte) (before, after string, found bool) {
+	if i := strings.IndexByte(s, sep); i >= 0 {
+		return s[:i], s[i+1:], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Response represents the response from an HTTP request.
+type Response struct {
+	Status     string // e.g. "200 OK"
+	StatusCode int    // e.g. 200
+	Proto      string // e.g. "HTTP/1.0"
+
+	// Header maps header keys to values.
+	Header Header
+
+	// Body represents the response body. It is streamed from the
+	// connection as it is read, and is never nil. The caller must close
+	// it, which closes the connection.
+	Body io.ReadCloser
+
+	// ContentLength records the length of the body, or -1 if it is unknown.
+	ContentLength int64
+
+	// TransferEncoding is "chunked" for a chunked body, or empty.
+	TransferEncoding string
+
+	// Request is the request that was sent to obtain this Response.
+	Request *Request
+}
+
+// ReadResponse reads and returns an HTTP response from r. The req parameter
+// optionally specifies the Request that corresponds to this Response.
+// Informational (1xx) responses are skipped.
+//
+// The body of the response reads from r, and closing it does nothing unless
+// the returned response is given another body.
+func ReadResponse(r *bufio.Reader, req *Request) (*Response, error) {
+	for {
+		resp, err := readResponse(r, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 200 {
+			return resp, nil
+		}
+	}
+}
+
+func readResponse(r *bufio.Reader, req *Request) (*Response, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	sp := strings.IndexByte(line, ' ')
+	if sp < 0 || !strings.HasPrefix(line, "HTTP/") {
+		return nil, ErrMalformedStatus
+	}
+	resp := &Response{Proto: line[:sp], Status: strings.TrimLeft(line[sp+1:], " "), Request: req}
+	if len(resp.Status) < 3 {
+		return nil, ErrMalformedStatus
+	}
+	resp.StatusCode, err = strconv.Atoi(resp.Status[:3])
+	if err != nil || resp.StatusCode < 100 {
+		return nil, ErrMalformedStatus
+	}
+
+	resp.Header, err = readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.ContentLength = -1
+	switch {
+	case resp.StatusCode < 200 || resp.StatusCode == StatusNoContent || resp.StatusCode == StatusNotModified ||
+		req != nil && req.Method == MethodHead:
+		resp.ContentLength = 0
+		resp.Body = nopCloser{eofReader{}}
+	case strings.Contains(strings.ToLower(resp.Header.Get("Transfer-Encoding")), "chunked"):
+		resp.TransferEncoding = "chunked"
+		resp.Body = nopCloser{newChunkedReader(r)}
+	case resp.Header.Get("Content-Length") != "":
+		resp.ContentLength, err = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+		if err != nil || resp.ContentLength < 0 {
+			return nil, ErrMalformedHeader
+		}
+		resp.Body = nopCloser{&lengthReader{r: r, n: resp.ContentLength}}
+	default:
+		// the body ends when the connection is closed
+		resp.Body = nopCloser{r}
+	}
+	return resp, nil
+}
+
+// lengthReader reads a body of n bytes. It returns io.ErrUnexpectedEOF when
+// the body is cut short.
+type lengthReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *lengthReader) Read(b []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > l.n {
+		b = b[:l.n]
+	}
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	if err == io.EOF && l.n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+type nopCloser struct {
+	io.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) { return 0, io.EOF }
+
+// body is the body of a response, closing the connection it is read from.
+type body struct {
+	io.Reader
+	conn io.Closer
+}
+
+func (b *body) Close() error {
+	return b.conn.Close()
+}
@@ -0,0 +1,373 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"tinygo.org/x/drivers/net"
+)
+
+// defaultReadTimeout is the time the server waits for a request, when
+// Server.ReadTimeout is not set.
+const defaultReadTimeout = 10 * time.Second
+
+// A Handler responds to an HTTP request.
+type Handler interface {
+	ServeHTTP(ResponseWriter, *Request)
+}
+
+// The HandlerFunc type is an adapter to allow the use of ordinary functions
+// as HTTP handlers.
+type HandlerFunc func(ResponseWriter, *Request)
+
+// ServeHTTP calls f(w, r).
+func (f HandlerFunc) ServeHTTP(w ResponseWriter, r *Request) {
+	f(w, r)
+}
+
+// A ResponseWriter interface is used by an HTTP handler to construct an HTTP
+// response.
+type ResponseWriter interface {
+	// Header returns the header map that will be sent by WriteHeader.
+	Header() Header
+
+	// Write writes the data to the connection as part of an HTTP reply,
+	// calling WriteHeader(StatusOK) first if needed.
+	Write([]byte) (int, error)
+
+	// WriteHeader sends an HTTP response header with the provided status
+	// code.
+	WriteHeader(statusCode int)
+}
+
+// ServeMux is an HTTP request multiplexer. It matches the path of each
+// incoming request against a list of registered patterns and calls the
+// handler for the pattern that most closely matches the path.
+//
+// Patterns name fixed, rooted paths, like "/favicon.ico", or rooted
+// subtrees, like "/images/" (note the trailing slash). Longer patterns take
+// precedence over shorter ones.
+type ServeMux struct {
+	entries []muxEntry
+}
+
+type muxEntry struct {
+	pattern string
+	h       Handler
+}
+
+// NewServeMux allocates and returns a new ServeMux.
+func NewServeMux() *ServeMux {
+	return &ServeMux{}
+}
+
+// DefaultServeMux is the default ServeMux used by Serve.
+var DefaultServeMux = NewServeMux()
+
+// Handle registers the handler for the given pattern, replacing any handler
+// already registered for it.
+func (mux *ServeMux) Handle(pattern string, handler Handler) {
+	if pattern == "" || pattern[0] != '/' {
+		panic("http: invalid pattern " + pattern)
+	}
+	for i := range mux.entries {
+		if mux.entries[i].pattern == pattern {
+			mux.entries[i].h = handler
+			return
+		}
+	}
+	mux.entries = append(mux.entries, muxEntry{pattern: pattern, h: handler})
+}
+
+// HandleFunc registers the handler function for the given pattern.
+func (mux *ServeMux) HandleFunc(pattern string, handler func(ResponseWriter, *Request)) {
+	mux.Handle(pattern, HandlerFunc(handler))
+}
+
+// Handler returns the handler to use for the given request, and the pattern
+// it is registered with. If there is none, it returns a handler replying
+// "404 page not found" and an empty pattern.
+func (mux *ServeMux) Handler(r *Request) (h Handler, pattern string) {
+	path := r.URL.Path
+	h = NotFoundHandler()
+	for _, e := range mux.entries {
+		if len(e.pattern) <= len(pattern) {
+			continue
+		}
+		if e.pattern == path || e.pattern[len(e.pattern)-1] == '/' && strings.HasPrefix(path, e.pattern) {
+			h, pattern = e.h, e.pattern
+		}
+	}
+	return h, pattern
+}
+
+// ServeHTTP dispatches the request to the handler whose pattern most closely
+// matches the request path.
+func (mux *ServeMux) ServeHTTP(w ResponseWriter, r *Request) {
+	h, _ := mux.Handler(r)
+	h.ServeHTTP(w, r)
+}
+
+// Handle registers the handler for the given pattern in the DefaultServeMux.
+func Handle(pattern string, handler Handler) {
+	DefaultServeMux.Handle(pattern, handler)
+}
+
+// HandleFunc registers the handler function for the given pattern in the
+// DefaultServeMux.
+func HandleFunc(pattern string, handler func(ResponseWriter, *Request)) {
+	DefaultServeMux.HandleFunc(pattern, handler)
+}
+
+// A Server defines parameters for running an HTTP server.
+//
+// The connections are served one after the other, and closed after each
+// response.
+type Server struct {
+	// Addr optionally specifies the TCP address for the server to listen
+	// on, in the form "host:port". If empty, ":http" (port 80) is used.
+	Addr string
+
+	// Handler to invoke, http.DefaultServeMux if nil.
+	Handler Handler
+
+	// ReadTimeout is the maximum duration for reading the entire request,
+	// including the body. A zero value means a timeout of 10 seconds, as a
+	// client not sending its request blocks the server. A negative value
+	// means no timeout.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the maximum duration before timing out writes of the
+	// response. A zero value means no timeout.
+	WriteTimeout time.Duration
+}
+
+// ListenAndServe listens on the TCP network address srv.Addr and then calls
+// Serve to handle requests on incoming connections.
+func (srv *Server) ListenAndServe() error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":80"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return srv.Serve(l)
+}
+
+// Serve accepts incoming connections on the Listener l, and handles their
+// request. Serve always returns a non-nil error.
+func (srv *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		srv.serve(conn)
+	}
+}
+
+// ListenAndServe listens on the TCP network address addr and then calls
+// Serve with handler to handle requests on incoming connections.
+// The handler is typically nil, in which case the DefaultServeMux is used.
+func ListenAndServe(addr string, handler Handler) error {
+	srv := &Server{Addr: addr, Handler: handler}
+	return srv.ListenAndServe()
+}
+
+// Serve accepts incoming HTTP connections on the listener l, and handles
+// their request with handler.
+// The handler is typically nil, in which case the DefaultServeMux is used.
+func Serve(l net.Listener, handler Handler) error {
+	srv := &Server{Handler: handler}
+	return srv.Serve(l)
+}
+
+// serve handles the request of a connection, and closes it.
+func (srv *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	readTimeout := srv.ReadTimeout
+	if readTimeout == 0 {
+		readTimeout = defaultReadTimeout
+	}
+	if readTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+	}
+	w := &response{w: bufio.NewWriterSize(conn, bufferSize), header: make(Header)}
+	req, err := readRequest(bufio.NewReaderSize(connReader{conn}, bufferSize))
+	if err != nil {
+		switch err {
+		case ErrLineTooLong:
+			// the request line
+			Error(w, "request URI too long", StatusRequestURITooLong)
+		case errHeaderLineTooLong, ErrHeaderTooLong:
+			Error(w, "request header too large", StatusRequestHeaderFieldsTooLarge)
+		case ErrMalformedRequest, ErrMalformedHeader:
+			Error(w, err.Error(), StatusBadRequest)
+		default:
+			// the connection failed
+			return
+		}
+	} else {
+		if addr := conn.RemoteAddr(); addr != nil {
+			req.RemoteAddr = addr.String()
+		}
+		w.req = req
+		if srv.WriteTimeout > 0 {
+			conn.SetWriteDeadline(time.Now().Add(srv.WriteTimeout))
+		}
+		h := srv.Handler
+		if h == nil {
+			h = DefaultServeMux
+		}
+		h.ServeHTTP(w, req)
+	}
+	w.finish()
+}
+
+// response is the ResponseWriter of the server. The body is buffered until
+// the buffer is full, so that short responses are sent with their length.
+// Longer responses end when the connection is closed.
+type response struct {
+	w      *bufio.Writer
+	req    *Request
+	header Header
+	status int
+
+	// the beginning of the body, when the header is not written yet
+	body [bufferSize]byte
+	n    int
+
+	wroteHeader bool // whether WriteHeader was called
+	sentHeader  bool // whether the header was written to w
+}
+
+func (w *response) Header() Header {
+	return w.header
+}
+
+func (w *response) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *response) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !w.bodyAllowed() {
+		return len(b), nil
+	}
+	if !w.sentHeader {
+		if w.n+len(b) <= len(w.body) {
+			w.n += copy(w.body[w.n:], b)
+			return len(b), nil
+		}
+		w.sendHeader()
+	}
+	return w.w.Write(b)
+}
+
+// WriteString writes the string without copying it to a byte slice, so that
+// constant strings are sent from flash.
+func (w *response) WriteString(s string) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !w.bodyAllowed() {
+		return len(s), nil
+	}
+	if !w.sentHeader {
+		if w.n+len(s) <= len(w.body) {
+			w.n += copy(w.body[w.n:], s)
+			return len(s), nil
+		}
+		w.sendHeader()
+	}
+	return w.w.WriteString(s)
+}
+
+func (w *response) bodyAllowed() bool {
+	return w.status != StatusNoContent && w.status != StatusNotModified &&
+		(w.req == nil || w.req.Method != MethodHead)
+}
+
+// sendHeader writes the header followed by the buffered beginning of the
+// body.
+func (w *response) sendHeader() {
+	w.sentHeader = true
+	w.w.WriteString("HTTP/1.1 ")
+	w.w.WriteString(strconv.Itoa(w.status))
+	w.w.WriteString(" ")
+	w.w.WriteString(StatusText(w.status))
+	w.w.WriteString("\r\nConnection: close\r\n")
+	if w.n > 0 && w.header.Get("Content-Type") == "" {
+		w.header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.header.write(w.w)
+	w.w.WriteString("\r\n")
+	w.w.Write(w.body[:w.n])
+}
+
+// finish ends the response after the handler returns.
+func (w *response) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !w.sentHeader {
+		if w.header.Get("Content-Length") == "" && w.bodyAllowed() {
+			w.header.Set("Content-Length", strconv.Itoa(w.n))
+		}
+		w.sendHeader()
+	}
+	w.w.Flush()
+}
+
+// Error replies to the request with the specified error message and HTTP
+// code. The error message should be plain text.
+func Error(w ResponseWriter, error string, code int) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	io.WriteString(w, error)
+	io.WriteString(w, "\n")
+}
+
+// NotFound replies to the request with an HTTP 404 not found error.
+func NotFound(w ResponseWriter, r *Request) {
+	Error(w, "404 page not found", StatusNotFound)
+}
+
+// NotFoundHandler returns a simple request handler that replies to each
+// request with a “404 page not found” reply.
+func NotFoundHandler() Handler {
+	return HandlerFunc(NotFound)
+}
+
+// Redirect replies to the request with a redirect to url, which may be a
+// path relative to the request path. The code should be in the 3xx range.
+func Redirect(w ResponseWriter, r *Request, url string, code int) {
+	w.Header().Set("Location", url)
+	w.WriteHeader(code)
+}
+
+// StaticHandler returns a handler replying to each request with the content,
+// which is typically a string constant, kept in flash by TinyGo and sent
+// without being copied to RAM.
+func StaticHandler(contentType, content string) Handler {
+	length := strconv.Itoa(len(content))
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", length)
+		io.WriteString(w, content)
+	})
+}
@@ -0,0 +1,220 @@
+package http_test
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	stdnet "net"
+	stdhttp "net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/net"
+	"tinygo.org/x/drivers/net/http"
+	"tinygo.org/x/drivers/net/loopback"
+)
+
+const page = "<html><body>config</body></html>"
+
+// serve serves handler through the loopback driver, and returns the base URL
+// of the server.
+func serve(c *qt.C, handler http.Handler) string {
+	c.Patch(&net.ActiveDevice, net.DeviceDriver(loopback.New()))
+
+	// find a free port
+	fl, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	addr := fl.Addr().String()
+	fl.Close()
+
+	l, err := net.Listen("tcp", addr)
+	c.Assert(err, qt.IsNil)
+	c.Defer(func() { l.Close() })
+	srv := &http.Server{Handler: handler, ReadTimeout: 5 * time.Second}
+	go srv.Serve(l)
+	return "http://" + addr
+}
+
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.StaticHandler("text/html", page))
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown API "+r.URL.Path, http.StatusNotFound)
+	})
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"name":"`+r.FormValue("name")+`"}`)
+	})
+	mux.HandleFunc("/form", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, r.PostFormValue("ssid")+" "+r.FormValue("pass")+" "+r.FormValue("q"))
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 100; i++ {
+			io.WriteString(w, "0123456789")
+		}
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func get(c *qt.C, url string) (*stdhttp.Response, string) {
+	resp, err := stdhttp.Get(url)
+	c.Assert(err, qt.IsNil)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, qt.IsNil)
+	return resp, string(b)
+}
+
+func TestServe(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	base := serve(c, newMux())
+
+	resp, body := get(c, base+"/")
+	c.Assert(resp.StatusCode, qt.Equals, 200)
+	c.Assert(resp.Header.Get("Content-Type"), qt.Equals, "text/html")
+	c.Assert(resp.ContentLength, qt.Equals, int64(len(page)))
+	c.Assert(body, qt.Equals, page)
+
+	resp, body = get(c, base+"/api/status?name=sensor%201")
+	c.Assert(resp.Header.Get("Content-Type"), qt.Equals, "application/json")
+	c.Assert(resp.ContentLength, qt.Equals, int64(len(body)))
+	c.Assert(body, qt.Equals, `{"name":"sensor 1"}`)
+
+	resp, body = get(c, base+"/api/other")
+	c.Assert(resp.StatusCode, qt.Equals, 404)
+	c.Assert(body, qt.Equals, "unknown API /api/other\n")
+
+	resp, body = get(c, base+"/large")
+	c.Assert(resp.ContentLength, qt.Equals, int64(-1))
+	c.Assert(body, qt.Equals, strings.Repeat("0123456789", 100))
+
+	resp, body = get(c, base+"/old")
+	c.Assert(resp.Request.URL.Path, qt.Equals, "/")
+	c.Assert(body, qt.Equals, page)
+
+	resp, body = get(c, base+"/empty")
+	c.Assert(resp.StatusCode, qt.Equals, 204)
+	c.Assert(body, qt.Equals, "")
+}
+
+func TestServeForm(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	base := serve(c, newMux())
+
+	resp, err := stdhttp.PostForm(base+"/form?q=query&pass=fromquery", url.Values{
+		"ssid": {"my network"},
+		"pass": {"s3cr3t&="},
+	})
+	c.Assert(err, qt.IsNil)
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, qt.Equals, 200)
+	c.Assert(string(b), qt.Equals, "my network s3cr3t&= query")
+
+	resp, err = stdhttp.PostForm(base+"/form", url.Values{"ssid": {strings.Repeat("x", 2000)}})
+	c.Assert(err, qt.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, qt.Equals, 400)
+
+	resp, _ = get(c, base+"/form")
+	c.Assert(resp.StatusCode, qt.Equals, 405)
+}
+
+func TestServeHead(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	base := serve(c, newMux())
+
+	resp, err := stdhttp.Head(base + "/")
+	c.Assert(err, qt.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, qt.Equals, 200)
+	c.Assert(resp.ContentLength, qt.Equals, int64(len(page)))
+}
+
+func TestServeMalformed(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	base := serve(c, newMux())
+
+	for _, test := range []struct {
+		request string
+		status  string
+	}{
+		{"NONSENSE\r\n\r\n", "HTTP/1.1 400 Bad Request"},
+		{"GET / HTTP/1.1\r\nno colon\r\n\r\n", "HTTP/1.1 400 Bad Request"},
+		{"GET /" + strings.Repeat("x", 1000) + " HTTP/1.1\r\n\r\n", "HTTP/1.1 414 Request URI Too Long"},
+		{"GET / HTTP/1.1\r\nX: " + strings.Repeat("x", 1000) + "\r\n\r\n", "HTTP/1.1 431 Request Header Fields Too Large"},
+		{"GET / HTTP/1.1\r\n" + strings.Repeat("X: x\r\n", 100) + "\r\n", "HTTP/1.1 431 Request Header Fields Too Large"},
+	} {
+		conn, err := stdnet.Dial("tcp", strings.TrimPrefix(base, "http://"))
+		c.Assert(err, qt.IsNil)
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		io.WriteString(conn, test.request)
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		conn.Close()
+		c.Assert(err, qt.IsNil)
+		c.Assert(line, qt.Equals, test.status+"\r\n")
+	}
+}
+
+func TestServeMux(t *testing.T) {
+	c := qt.New(t)
+	mux := newMux()
+	for _, test := range []struct {
+		path    string
+		pattern string
+	}{
+		{"/", "/"},
+		{"/index.html", "/"},
+		{"/api", "/"},
+		{"/api/", "/api/"},
+		{"/api/status", "/api/status"},
+		{"/api/status/more", "/api/"},
+		{"/form", "/form"},
+	} {
+		u, _ := url.Parse(test.path)
+		_, pattern := mux.Handler(&http.Request{URL: u})
+		c.Check(pattern, qt.Equals, test.pattern, qt.Commentf("%s", test.path))
+	}
+
+	u, _ := url.Parse("/x")
+	_, pattern := http.NewServeMux().Handler(&http.Request{URL: u})
+	c.Assert(pattern, qt.Equals, "")
+}
+
+func TestReadRequest(t *testing.T) {
+	c := qt.New(t)
+	raw := "POST /submit?x=1 HTTP/1.1\r\nHost: device.local\r\nTransfer-Encoding: chunked\r\n" +
+		"Content-Type: application/x-www-form-urlencoded\r\n\r\n4\r\na=b&\r\n3\r\nc=d\r\n0\r\n\r\n"
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw)))
+	c.Assert(err, qt.IsNil)
+	c.Assert(req.Method, qt.Equals, "POST")
+	c.Assert(req.RequestURI, qt.Equals, "/submit?x=1")
+	c.Assert(req.URL.Path, qt.Equals, "/submit")
+	c.Assert(req.Proto, qt.Equals, "HTTP/1.1")
+	c.Assert(req.Host, qt.Equals, "device.local")
+	c.Assert(req.ContentLength, qt.Equals, int64(-1))
+	c.Assert(req.ParseForm(), qt.IsNil)
+	c.Assert(req.Form, qt.DeepEquals, url.Values{"a": {"b"}, "c": {"d"}, "x": {"1"}})
+	c.Assert(req.PostForm, qt.DeepEquals, url.Values{"a": {"b"}, "c": {"d"}})
+}
@@ -0,0 +1,107 @@
+package http
+
+// HTTP methods.
+const (
+	MethodGet     = "GET"
+	MethodHead    = "HEAD"
+	MethodPost    = "POST"
+	MethodPut     = "PUT"
+	MethodPatch   = "PATCH"
+	MethodDelete  = "DELETE"
+	MethodOptions = "OPTIONS"
+)
+
+// HTTP status codes.
+const (
+	StatusContinue = 100
+
+	StatusOK        = 200
+	StatusCreated   = 201
+	StatusAccepted  = 202
+	StatusNoContent = 204
+
+	StatusMovedPermanently  = 301
+	StatusFound             = 302
+	StatusSeeOther          = 303
+	StatusNotModified       = 304
+	StatusTemporaryRedirect = 307
+	StatusPermanentRedirect = 308
+
+	StatusBadRequest            = 400
+	StatusUnauthorized          = 401
+	StatusForbidden             = 403
+	StatusNotFound              = 404
+	StatusMethodNotAllowed      = 405
+	StatusRequestTimeout        = 408
+	StatusLengthRequired        = 411
+	StatusRequestEntityTooLarge = 413
+	StatusRequestURITooLong     = 414
+	StatusUnsupportedMediaType  = 415
+
+	StatusRequestHeaderFieldsTooLarge = 431
+
+	StatusInternalServerError     = 500
+	StatusNotImplemented          = 501
+	StatusServiceUnavailable      = 503
+	StatusHTTPVersionNotSupported = 505
+)
+
+// StatusText returns a text for the HTTP status code. It returns the empty
+// string if the code is unknown.
+func StatusText(code int) string {
+	switch code {
+	case StatusContinue:
+		return "Continue"
+	case StatusOK:
+		return "OK"
+	case StatusCreated:
+		return "Created"
+	case StatusAccepted:
+		return "Accepted"
+	case StatusNoContent:
+		return "No Content"
+	case StatusMovedPermanently:
+		return "Moved Permanently"
+	case StatusFound:
+		return "Found"
+	case StatusSeeOther:
+		return "See Other"
+	case StatusNotModified:
+		return "Not Modified"
+	case StatusTemporaryRedirect:
+		return "Temporary Redirect"
+	case StatusPermanentRedirect:
+		return "Permanent Redirect"
+	case StatusBadRequest:
+		return "Bad Request"
+	case StatusUnauthorized:
+		return "Unauthorized"
+	case StatusForbidden:
+		return "Forbidden"
+	case StatusNotFound:
+		return "Not Found"
+	case StatusMethodNotAllowed:
+		return "Method Not Allowed"
+	case StatusRequestTimeout:
+		return "Request Timeout"
+	case StatusLengthRequired:
+		return "Length Required"
+	case StatusRequestEntityTooLarge:
+		return "Request Entity Too Large"
+	case StatusRequestURITooLong:
+		return "Request URI Too Long"
+	case StatusUnsupportedMediaType:
+		return "Unsupported Media Type"
+	case StatusRequestHeaderFieldsTooLarge:
+		return "Request Header Fields Too Large"
+	case StatusInternalServerError:
+		return "Internal Server Error"
+	case StatusNotImplemented:
+		return "Not Implemented"
+	case StatusServiceUnavailable:
+		return "Service Unavailable"
+	case StatusHTTPVersionNotSupported:
+		return "HTTP Version Not Supported"
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package net
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Listener is a generic network listener for stream-oriented protocols.
+// This interface is from the Go standard library.
+type Listener interface {
+	// Accept waits for and returns the next connection to the listener.
+	Accept() (Conn, error)
+
+	// Close closes the listener.
+	Close() error
+
+	// Addr returns the listener's network address.
+	Addr() Addr
+}
+
+// Listen announces on the local network address. It tries to provide a
+// mostly compatible interface to net.Listen().
+//
+// Only the "tcp" network is supported, and only the port of the address is
+// used, as in ":80".
+func Listen(network, address string) (Listener, error) {
+	switch network {
+	case "tcp":
+		i := strings.LastIndex(address, ":")
+		if i < 0 {
+			return nil, errors.New("missing port in address")
+		}
+		port, err := parsePort(address[i+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		l, e := ListenTCP(network, &TCPAddr{IP: IP(address[:i]), Port: port})
+		return l.opListener(), e
+	default:
+		return nil, errors.New("invalid network for listen")
+	}
+}
+
+// ListenTCP listens for TCP connections on the port listed in laddr.
+func ListenTCP(network string, laddr *TCPAddr) (*TCPListener, error) {
+	sock, err := ActiveDevice.ListenTCPSocket(strconv.Itoa(laddr.Port))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TCPListener{adaptor: ActiveDevice, sock: sock, laddr: laddr}, nil
+}
+
+// TCPListener is a loosely net.Listener compatible implementation, for
+// the TCP server sockets of a DeviceDriver.
+type TCPListener struct {
+	adaptor DeviceDriver
+	sock    Socket
+	laddr   *TCPAddr
+
+	deadline time.Time
+}
+
+// Accept waits for and returns the next connection to the listener.
+func (l *TCPListener) Accept() (Conn, error) {
+	c, err := l.AcceptTCP()
+	return c.opConn(), err
+}
+
+// AcceptTCP waits for and returns the next connection to the listener.
+func (l *TCPListener) AcceptTCP() (*TCPSerialConn, error) {
+	for {
+		sock, err := l.adaptor.AcceptSocket(l.sock)
+		switch err {
+		case nil:
+			return &TCPSerialConn{SerialConn: SerialConn{Adaptor: l.adaptor, Socket: sock}, laddr: l.laddr}, nil
+		case ErrNoConnection:
+			if expired(l.deadline) {
+				return nil, ErrDeadlineExceeded
+			}
+			sleep(pollInterval)
+		default:
+			return nil, err
+		}
+	}
+}
+
+// SetDeadline sets the deadline associated with the listener.
+// A zero time value disables the deadline.
+func (l *TCPListener) SetDeadline(t time.Time) error {
+	l.deadline = t
+	return nil
+}
+
+// Close stops listening on the TCP address.
+// Already accepted connections are not closed.
+func (l *TCPListener) Close() error {
+	return l.adaptor.DisconnectSocket(l.sock)
+}
+
+// Addr returns the listener's network address.
+func (l *TCPListener) Addr() Addr {
+	return l.laddr.opAddr()
+}
+
+func (l *TCPListener) opListener() Listener {
+	if l == nil {
+		return nil
+	}
+	return l
+}
+
+// parsePort parses a port number.
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 0xffff {
+		return 0, errors.New("invalid port")
+	}
+	return port, nil
+}
@@ -0,0 +1,251 @@
+// Package loopback implements a net.DeviceDriver on top of the network of
+// the host, so that the code using the net package, such as net/tls and
+// net/mqtt, can be tested with go test against local servers. It is not
+// intended to be used with TinyGo.
+//
+// Set it as the active device of the net package before dialing:
+//
+//	net.ActiveDevice = loopback.New()
+//
+package loopback // import "tinygo.org/x/drivers/net/loopback"
+
+import (
+	"crypto/tls"
+	"errors"
+	stdnet "net"
+	"sync"
+
+	"tinygo.org/x/drivers/net"
+)
+
+// ErrInvalidSocket is returned when using a socket that is not open.
+var ErrInvalidSocket = errors.New("loopback: invalid socket")
+
+// Driver is a net.DeviceDriver using the network of the host.
+type Driver struct {
+	// TLSConfig is the configuration used by ConnectSSLSocket, for instance
+	// to trust the certificate of an httptest server.
+	TLSConfig *tls.Config
+
+	// NoEOF hides the connections closed by the peer, as the wifinina driver
+	// does: ReadSocket keeps returning no data instead of io.EOF. It tests
+	// the code that must notice lost connections by other means, such as
+	// timeouts.
+	NoEOF bool
+
+	mu      sync.Mutex
+	sockets map[net.Socket]*socket
+	next    net.Socket
+}
+
+// socket is an open connection or listener, with the data received on it
+// buffered by a goroutine so that it can be polled.
+type socket struct {
+	conn     stdnet.Conn
+	listener stdnet.Listener
+
+	mu       sync.Mutex
+	data     []byte
+	err      error
+	accepted []stdnet.Conn
+}
+
+// New returns a new loopback driver.
+func New() *Driver {
+	return &Driver{sockets: make(map[net.Socket]*socket)}
+}
+
+// GetDNS returns the first IPv4 address of the host.
+func (d *Driver) GetDNS(domain string) (string, error) {
+	addrs, err := stdnet.LookupHost(domain)
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ip := stdnet.ParseIP(addr); ip != nil && ip.To4() != nil {
+			return addr, nil
+		}
+	}
+	return "", errors.New("loopback: no IPv4 address for " + domain)
+}
+
+func (d *Driver) ConnectTCPSocket(addr, port string) (net.Socket, error) {
+	conn, err := stdnet.Dial("tcp", stdnet.JoinHostPort(addr, port))
+	if err != nil {
+		return 0, err
+	}
+	return d.open(&socket{conn: conn}), nil
+}
+
+func (d *Driver) ConnectSSLSocket(addr, port string) (net.Socket, error) {
+	conn, err := tls.Dial("tcp", stdnet.JoinHostPort(addr, port), d.TLSConfig)
+	if err != nil {
+		return 0, err
+	}
+	return d.open(&socket{conn: conn}), nil
+}
+
+func (d *Driver) ConnectUDPSocket(addr, sendport, listenport string) (net.Socket, error) {
+	laddr, err := stdnet.ResolveUDPAddr("udp", stdnet.JoinHostPort("", listenport))
+	if err != nil {
+		return 0, err
+	}
+	var conn *stdnet.UDPConn
+	if sendport == "0" {
+		// only listening, as with net.ListenUDP
+		conn, err = stdnet.ListenUDP("udp", laddr)
+	} else {
+		var raddr *stdnet.UDPAddr
+		raddr, err = stdnet.ResolveUDPAddr("udp", stdnet.JoinHostPort(addr, sendport))
+		if err != nil {
+			return 0, err
+		}
+		conn, err = stdnet.DialUDP("udp", laddr, raddr)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return d.open(&socket{conn: conn}), nil
+}
+
+func (d *Driver) ListenTCPSocket(port string) (net.Socket, error) {
+	l, err := stdnet.Listen("tcp", stdnet.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		return 0, err
+	}
+	return d.open(&socket{listener: l}), nil
+}
+
+func (d *Driver) AcceptSocket(listener net.Socket) (net.Socket, error) {
+	l, err := d.socket(listener)
+	if err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.accepted) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+		return 0, net.ErrNoConnection
+	}
+	conn := l.accepted[0]
+	l.accepted = l.accepted[1:]
+	return d.open(&socket{conn: conn}), nil
+}
+
+func (d *Driver) DisconnectSocket(sock net.Socket) error {
+	s, err := d.socket(sock)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	delete(d.sockets, sock)
+	d.mu.Unlock()
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return s.conn.Close()
+}
+
+func (d *Driver) WriteSocket(sock net.Socket, b []byte) (int, error) {
+	s, err := d.socket(sock)
+	if err != nil {
+		return 0, err
+	}
+	if s.conn == nil {
+		return 0, ErrInvalidSocket
+	}
+	return s.conn.Write(b)
+}
+
+// ReadSocket returns the data already received on the socket, which may be
+// none. Once the connection is closed by the peer and all its data has been
+// read, it returns the error of the connection, such as io.EOF, unless NoEOF
+// is set.
+func (d *Driver) ReadSocket(sock net.Socket, b []byte) (int, error) {
+	s, err := d.socket(sock)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.data) == 0 {
+		if d.NoEOF {
+			return 0, nil
+		}
+		return 0, s.err
+	}
+	n := copy(b, s.data)
+	s.data = s.data[n:]
+	return n, nil
+}
+
+// IsSocketDataAvailable returns whether ReadSocket returns data, or the
+// error of a closed connection unless NoEOF is set.
+func (d *Driver) IsSocketDataAvailable(sock net.Socket) bool {
+	s, err := d.socket(sock)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.data) > 0 || s.err != nil && !d.NoEOF
+}
+
+// open registers the socket, and starts receiving on it.
+func (d *Driver) open(s *socket) net.Socket {
+	if s.listener != nil {
+		go s.accept()
+	} else {
+		go s.receive()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sock := d.next
+	d.next++
+	d.sockets[sock] = s
+	return sock
+}
+
+// socket returns an open socket.
+func (d *Driver) socket(sock net.Socket) (*socket, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.sockets[sock]
+	if !ok {
+		return nil, ErrInvalidSocket
+	}
+	return s, nil
+}
+
+// receive buffers the data received on the connection until it is closed.
+func (s *socket) receive() {
+	buf := make([]byte, 1024)
+	for {
+		n, err := s.conn.Read(buf)
+		s.mu.Lock()
+		s.data = append(s.data, buf[:n]...)
+		if err != nil {
+			s.err = err
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+	}
+}
+
+// accept queues the connections to the listener until it is closed.
+func (s *socket) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		s.mu.Lock()
+		if err != nil {
+			s.err = err
+			s.mu.Unlock()
+			return
+		}
+		s.accepted = append(s.accepted, conn)
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,195 @@
+package loopback_test
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	stdnet "net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/net"
+	"tinygo.org/x/drivers/net/loopback"
+	ntls "tinygo.org/x/drivers/net/tls"
+)
+
+func useDriver(c *qt.C) *loopback.Driver {
+	d := loopback.New()
+	c.Patch(&net.ActiveDevice, net.DeviceDriver(d))
+	return d
+}
+
+// readAll reads from conn until the peer closes it.
+func readAll(c *qt.C, conn net.Conn) string {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b, err := ioutil.ReadAll(conn)
+	c.Assert(err, qt.IsNil)
+	return string(b)
+}
+
+func TestDialTCP(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	useDriver(c)
+
+	l, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		// echo back a single line, then close
+		b := make([]byte, 64)
+		n, _ := conn.Read(b)
+		conn.Write(b[:n])
+		conn.Close()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(readAll(c, conn), qt.Equals, "hello\n")
+}
+
+func TestDialTLS(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	d := useDriver(c)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secret")
+	}))
+	defer srv.Close()
+	d.TLSConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	conn, err := ntls.Dial("tcp", strings.TrimPrefix(srv.URL, "https://"), nil)
+	c.Assert(err, qt.IsNil)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.0\r\n\r\n"))
+	c.Assert(err, qt.IsNil)
+	resp := readAll(c, conn)
+	c.Assert(strings.HasPrefix(resp, "HTTP/1.0 200 OK\r\n"), qt.IsTrue, qt.Commentf("%q", resp))
+	c.Assert(strings.HasSuffix(resp, "\r\n\r\nsecret"), qt.IsTrue, qt.Commentf("%q", resp))
+}
+
+func TestDialTLSUntrusted(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	d := useDriver(c)
+
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	d.TLSConfig = &tls.Config{}
+
+	_, err := ntls.Dial("tcp", strings.TrimPrefix(srv.URL, "https://"), nil)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
+
+func TestListen(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	useDriver(c)
+
+	l, err := net.Listen("tcp", ":0")
+	c.Assert(err, qt.IsNil)
+	defer l.Close()
+	l.(*net.TCPListener).SetDeadline(time.Now().Add(100 * time.Millisecond))
+	_, err = l.Accept()
+	c.Assert(err, qt.Equals, net.ErrDeadlineExceeded)
+}
+
+func TestListenAccept(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	useDriver(c)
+
+	// find a free port
+	fl, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	addr := fl.Addr().String()
+	fl.Close()
+
+	l, err := net.Listen("tcp", addr)
+	c.Assert(err, qt.IsNil)
+	defer l.Close()
+
+	client, err := stdnet.Dial("tcp", addr)
+	c.Assert(err, qt.IsNil)
+	defer client.Close()
+
+	l.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := l.Accept()
+	c.Assert(err, qt.IsNil)
+	_, err = client.Write([]byte("hi"))
+	c.Assert(err, qt.IsNil)
+	client.(*stdnet.TCPConn).CloseWrite()
+	c.Assert(readAll(c, conn), qt.Equals, "hi")
+	conn.Close()
+}
+
+func TestUDP(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	useDriver(c)
+
+	pc, err := stdnet.ListenPacket("udp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	defer pc.Close()
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	c.Assert(err, qt.IsNil)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	c.Assert(err, qt.IsNil)
+
+	b := make([]byte, 16)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, addr, err := pc.ReadFrom(b)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b[:n]), qt.Equals, "ping")
+
+	_, err = pc.WriteTo([]byte("pong"), addr)
+	c.Assert(err, qt.IsNil)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err = conn.Read(b)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b[:n]), qt.Equals, "pong")
+}
+
+func TestNoEOF(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	d := useDriver(c)
+	d.NoEOF = true
+
+	l, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("bye"))
+		conn.Close()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	c.Assert(err, qt.IsNil)
+	defer conn.Close()
+
+	// the data is read, then the reads time out instead of returning io.EOF
+	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	b, err := ioutil.ReadAll(conn)
+	c.Assert(err, qt.Equals, net.ErrDeadlineExceeded)
+	c.Assert(string(b), qt.Equals, "bye")
+	c.Assert(conn.(interface{ IsDataAvailable() bool }).IsDataAvailable(), qt.IsFalse)
+}
@@ -4,7 +4,9 @@ package mqtt
 
 import (
 	"errors"
+	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/eclipse/paho.mqtt.golang/packets"
@@ -17,71 +19,171 @@ import (
 // on it before it may be used. This is to make sure resources (such as a net
 // connection) are created before the application is actually ready.
 func NewClient(o *ClientOptions) Client {
-	c := &mqttclient{opts: o, adaptor: o.Adaptor}
+	c := &mqttclient{
+		opts:            o,
+		adaptor:         o.Adaptor,
+		mid:             1,
+		incomingPubChan: make(chan *packets.PublishPacket, 10),
+		inflight:        make(map[uint16]*outbound),
+		received:        make(map[uint16]struct{}),
+		subscriptions:   make(map[string]byte),
+	}
 	c.msgRouter, c.stopRouter = newRouter()
+	c.msgRouter.matchAndDispatch(c.incomingPubChan, c)
 	return c
 }
 
 type mqttclient struct {
 	adaptor         net.DeviceDriver
-	conn            net.Conn
-	connected       bool
 	opts            *ClientOptions
-	mid             uint16
-	inbound         chan packets.ControlPacket
-	stop            chan struct{}
 	msgRouter       *router
 	stopRouter      chan bool
 	incomingPubChan chan *packets.PublishPacket
+
+	// writeMu serializes the packets written to the connection, and protects
+	// the fields below
+	writeMu  sync.Mutex
+	conn     net.Conn
+	lastSent time.Time
+
+	// mu protects the fields below
+	mu sync.Mutex
+	// status is one of disconnected, connecting, reconnecting or connected
+	status uint32
+	// stop is closed when the current connection is closed
+	stop chan struct{}
+	mid  uint16
+	// messages sent and waiting for their acknowledgement, by message ID
+	inflight map[uint16]*outbound
+	// QoS 2 messages received and waiting for their PUBREL, by message ID
+	received map[uint16]struct{}
+	// topics subscribed to, with their QoS, to subscribe again on reconnect
+	subscriptions map[string]byte
+	// time the outstanding PINGREQ was sent, or zero
+	pingSent time.Time
 }
 
+// outbound is a message waiting for its acknowledgement.
+type outbound struct {
+	// packet is the packet to resend if it is not acknowledged in time: the
+	// PUBLISH, then the PUBREL once a QoS 2 PUBLISH is received
+	packet packets.ControlPacket
+	token  tokenCompleter
+	sent   time.Time
+}
+
+var (
+	// ErrNotConnected is returned when the client is not connected.
+	ErrNotConnected = errors.New("MQTT client not connected")
+	// ErrInvalidQoS is returned for a QoS other than 0, 1 or 2.
+	ErrInvalidQoS = errors.New("MQTT invalid QoS")
+	// ErrNoMessageID is returned when all the message IDs are in flight.
+	ErrNoMessageID = errors.New("MQTT no message ID available")
+	// ErrDisconnected is the error of the messages still in flight when the
+	// client disconnects.
+	ErrDisconnected = errors.New("MQTT client disconnected")
+	// ErrPingTimeout is the error passed to the connection lost handler when
+	// the broker did not answer a PINGREQ in time.
+	ErrPingTimeout = errors.New("MQTT pingresp not received")
+)
+
+// SubscribeFailure is the return code of a topic in SubscribeToken.Result
+// when the broker refused the subscription.
+const SubscribeFailure byte = 0x80
+
+const (
+	// defaultRetryInterval is the interval between the retransmissions of the
+	// messages not acknowledged, when ClientOptions.RetryInterval is not set.
+	defaultRetryInterval = 20 * time.Second
+
+	// defaultPingTimeout is the time to wait for a PINGRESP, when
+	// ClientOptions.PingTimeout is not set.
+	defaultPingTimeout = 10 * time.Second
+
+	// defaultMaxReconnectInterval is the longest wait between two reconnect
+	// attempts, when ClientOptions.MaxReconnectInterval is not set.
+	defaultMaxReconnectInterval = 10 * time.Minute
+
+	// pollInterval is the interval at which the connection is polled for
+	// incoming packets.
+	pollInterval = 100 * time.Millisecond
+)
+
 // AddRoute allows you to add a handler for messages on a specific topic
 // without making a subscription. For example having a different handler
 // for parts of a wildcard subscription
 func (c *mqttclient) AddRoute(topic string, callback MessageHandler) {
-	return
+	c.msgRouter.addRoute(topic, callback)
 }
 
 // IsConnected returns a bool signifying whether
 // the client is connected or not.
+// It is also true while the client is reconnecting, if AutoReconnect is set.
 func (c *mqttclient) IsConnected() bool {
-	return c.connected
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.status == connected:
+		return true
+	case c.opts.AutoReconnect && c.status == reconnecting:
+		return true
+	default:
+		return false
+	}
 }
 
 // IsConnectionOpen return a bool signifying whether the client has an active
 // connection to mqtt broker, i.e not in disconnected or reconnect mode
 func (c *mqttclient) IsConnectionOpen() bool {
-	return c.connected
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status == connected
 }
 
 // Connect will create a connection to the message broker.
 func (c *mqttclient) Connect() Token {
+	c.mu.Lock()
+	if c.status != disconnected {
+		c.mu.Unlock()
+		return completedToken(errors.New("MQTT client already connected"))
+	}
+	c.status = connecting
+	c.mu.Unlock()
+
+	if err := c.connect(); err != nil {
+		c.setStatus(disconnected)
+		return completedToken(err)
+	}
+	if c.opts.OnConnect != nil {
+		go c.opts.OnConnect(c)
+	}
+	return completedToken(nil)
+}
+
+// connect opens a connection to the broker and sends the CONNECT packet. Once
+// the broker accepted it, it starts processing the packets of the connection.
+func (c *mqttclient) connect() error {
+	var conn net.Conn
 	var err error
 
 	// make connection
 	if strings.Contains(c.opts.Servers, "ssl://") {
 		url := strings.TrimPrefix(c.opts.Servers, "ssl://")
-		c.conn, err = tls.Dial("tcp", url, nil)
+		conn, err = tls.Dial("tcp", url, nil)
 		if err != nil {
-			return &mqtttoken{err: err}
+			return err
 		}
 	} else if strings.Contains(c.opts.Servers, "tcp://") {
 		url := strings.TrimPrefix(c.opts.Servers, "tcp://")
-		c.conn, err = net.Dial("tcp", url)
+		conn, err = net.Dial("tcp", url)
 		if err != nil {
-			return &mqtttoken{err: err}
+			return err
 		}
 	} else {
 		// invalid protocol
-		return &mqtttoken{err: errors.New("invalid protocol")}
+		return errors.New("invalid protocol")
 	}
 
-	c.mid = 1
-	c.inbound = make(chan packets.ControlPacket, 10)
-	c.stop = make(chan struct{})
-	c.incomingPubChan = make(chan *packets.PublishPacket, 10)
-	c.msgRouter.matchAndDispatch(c.incomingPubChan, c.opts.Order, c)
-
 	// send the MQTT connect message
 	connectPkt := packets.NewControlPacket(packets.Connect).(*packets.ConnectPacket)
 	connectPkt.Qos = 0
@@ -98,41 +200,194 @@ func (c *mqttclient) Connect() Token {
 	connectPkt.ClientIdentifier = c.opts.ClientID
 	connectPkt.ProtocolVersion = byte(c.opts.ProtocolVersion)
 	connectPkt.ProtocolName = "MQTT"
-	connectPkt.Keepalive = 60
+	connectPkt.Keepalive = uint16(c.opts.KeepAlive)
 
-	err = connectPkt.Write(c.conn)
+	err = connectPkt.Write(conn)
 	if err != nil {
-		return &mqtttoken{err: err}
+		conn.Close()
+		return err
 	}
 
-	// TODO: handle timeout as ReadPacket blocks until it gets a packet.
-	// CONNECT response.
-	packet, err := packets.ReadPacket(c.conn)
+	// CONNECT response, which is waited for up to the connect timeout.
+	if c.opts.ConnectTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(c.opts.ConnectTimeout))
+	}
+	packet, err := packets.ReadPacket(conn)
+	conn.SetReadDeadline(time.Time{})
 	if err != nil {
-		return &mqtttoken{err: err}
+		conn.Close()
+		return err
 	}
-	if packet != nil {
-		ack, ok := packet.(*packets.ConnackPacket)
-		if ok {
-			if ack.ReturnCode != 0 {
-				return &mqtttoken{err: errors.New(packet.String())}
-			}
-			c.connected = true
+	if ack, ok := packet.(*packets.ConnackPacket); !ok || ack.ReturnCode != 0 {
+		conn.Close()
+		return errors.New(packet.String())
+	}
+
+	stop := make(chan struct{})
+	inbound := make(chan packets.ControlPacket, 10)
+	c.mu.Lock()
+	if c.status != connecting && c.status != reconnecting {
+		// Disconnect was called meanwhile
+		c.mu.Unlock()
+		conn.Close()
+		return ErrDisconnected
+	}
+	c.writeMu.Lock()
+	c.conn = conn
+	c.lastSent = time.Now()
+	c.writeMu.Unlock()
+	c.status = connected
+	c.stop = stop
+	c.pingSent = time.Time{}
+	c.mu.Unlock()
+
+	go readMessages(c, conn, inbound, stop)
+	go processInbound(c, inbound, stop)
+	return nil
+}
+
+func (c *mqttclient) setStatus(status uint32) {
+	c.mu.Lock()
+	c.status = status
+	c.mu.Unlock()
+}
+
+// connectionLost closes the connection stopped by stop, if it is still the
+// current one, calls the connection lost handler and starts reconnecting if
+// AutoReconnect is set.
+func (c *mqttclient) connectionLost(stop chan struct{}, err error) {
+	c.mu.Lock()
+	if c.stop != stop {
+		// already closed by Disconnect
+		c.mu.Unlock()
+		return
+	}
+	close(stop)
+	c.stop = nil
+	if c.opts.AutoReconnect {
+		c.status = reconnecting
+	} else {
+		c.status = disconnected
+	}
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	c.conn.Close()
+	c.writeMu.Unlock()
+
+	if c.opts.OnConnectionLost != nil {
+		go c.opts.OnConnectionLost(c, err)
+	}
+	if c.opts.AutoReconnect {
+		go c.reconnect()
+	} else {
+		c.failInflight()
+	}
+}
+
+// reconnect tries to connect again, waiting twice as long after each failed
+// attempt, up to the MaxReconnectInterval option. The subscriptions are then
+// renewed, and the messages in flight are sent again.
+func (c *mqttclient) reconnect() {
+	max := c.opts.MaxReconnectInterval
+	if max <= 0 {
+		max = defaultMaxReconnectInterval
+	}
+	delay := time.Second
+	for {
+		if delay > max {
+			delay = max
+		}
+		time.Sleep(delay)
+
+		c.mu.Lock()
+		status := c.status
+		c.mu.Unlock()
+		if status != reconnecting {
+			// Disconnect was called
+			return
+		}
+
+		if err := c.connect(); err == nil {
+			break
 		}
+		delay *= 2
 	}
 
-	go readMessages(c)
-	go processInbound(c)
+	c.resubscribe()
+	c.mu.Lock()
+	for _, o := range c.inflight {
+		o.sent = time.Time{}
+	}
+	c.mu.Unlock()
+	c.resend()
 
-	return &mqtttoken{}
+	if c.opts.OnConnect != nil {
+		go c.opts.OnConnect(c)
+	}
+}
+
+// resubscribe subscribes again to all the topics subscribed to so far.
+func (c *mqttclient) resubscribe() {
+	sub := packets.NewControlPacket(packets.Subscribe).(*packets.SubscribePacket)
+	c.mu.Lock()
+	for topic, qos := range c.subscriptions {
+		sub.Topics = append(sub.Topics, topic)
+		sub.Qoss = append(sub.Qoss, qos)
+	}
+	mid, err := c.nextID()
+	c.mu.Unlock()
+	if len(sub.Topics) == 0 || err != nil {
+		return
+	}
+	sub.MessageID = mid
+	c.write(sub)
 }
 
 // Disconnect will end the connection with the server, but not before waiting
 // the specified number of milliseconds to wait for existing work to be
 // completed.
 func (c *mqttclient) Disconnect(quiesce uint) {
-	c.conn.Close()
-	return
+	c.mu.Lock()
+	status := c.status
+	c.status = disconnected
+	stop := c.stop
+	c.stop = nil
+	c.mu.Unlock()
+
+	if status == connected {
+		// wait for the messages in flight to be acknowledged
+		deadline := time.Now().Add(time.Duration(quiesce) * time.Millisecond)
+		for time.Now().Before(deadline) {
+			c.mu.Lock()
+			n := len(c.inflight)
+			c.mu.Unlock()
+			if n == 0 {
+				break
+			}
+			time.Sleep(pollInterval)
+		}
+
+		close(stop)
+		c.write(packets.NewControlPacket(packets.Disconnect))
+		c.writeMu.Lock()
+		c.conn.Close()
+		c.writeMu.Unlock()
+	}
+
+	c.failInflight()
+}
+
+// failInflight completes the tokens of the messages in flight, which will not
+// be acknowledged anymore.
+func (c *mqttclient) failInflight() {
+	c.mu.Lock()
+	inflight := c.inflight
+	c.inflight = make(map[uint16]*outbound)
+	c.mu.Unlock()
+	for _, o := range inflight {
+		o.token.complete(ErrDisconnected)
+	}
 }
 
 // Publish will publish a message with the specified QoS and content
@@ -140,7 +395,10 @@ func (c *mqttclient) Disconnect(quiesce uint) {
 // Returns a token to track delivery of the message to the broker
 func (c *mqttclient) Publish(topic string, qos byte, retained bool, payload interface{}) Token {
 	if !c.IsConnected() {
-		return &mqtttoken{err: errors.New("MQTT client not connected")}
+		return completedToken(ErrNotConnected)
+	}
+	if qos > 2 {
+		return completedToken(ErrInvalidQoS)
 	}
 
 	pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
@@ -152,90 +410,307 @@ func (c *mqttclient) Publish(topic string, qos byte, retained bool, payload inte
 	case []byte:
 		pub.Payload = payload.([]byte)
 	default:
-		return &mqtttoken{err: errors.New("Unknown payload type")}
+		return completedToken(errors.New("Unknown payload type"))
+	}
+	pub.Retain = retained
+
+	if qos == 0 {
+		return completedToken(c.write(pub))
 	}
-	pub.MessageID = c.mid
-	c.mid++
 
-	err := pub.Write(c.conn)
+	// keep the message until it is acknowledged
+	token := newToken()
+	c.mu.Lock()
+	mid, err := c.nextID()
 	if err != nil {
-		return &mqtttoken{err: err}
+		c.mu.Unlock()
+		return completedToken(err)
 	}
+	pub.MessageID = mid
+	c.inflight[mid] = &outbound{packet: pub, token: token, sent: time.Now()}
+	c.mu.Unlock()
 
-	return &mqtttoken{}
+	// while reconnecting, the message is sent once connected again
+	if err := c.write(pub); err != nil && !c.opts.AutoReconnect {
+		c.complete(mid, err)
+	}
+	return token
 }
 
-// Subscribe starts a new subscription. Provide a MessageHandler to be executed when
-// a message is published on the topic provided.
-func (c *mqttclient) Subscribe(topic string, qos byte, callback MessageHandler) Token {
-	if !c.IsConnected() {
-		return &mqtttoken{err: errors.New("MQTT client not connected")}
+// nextID returns a message ID not in flight. c.mu must be held.
+func (c *mqttclient) nextID() (uint16, error) {
+	for i := 0; i < 0xffff; i++ {
+		mid := c.mid
+		c.mid++
+		if mid == 0 {
+			continue
+		}
+		if _, ok := c.inflight[mid]; !ok {
+			return mid, nil
+		}
 	}
+	return 0, ErrNoMessageID
+}
 
-	sub := packets.NewControlPacket(packets.Subscribe).(*packets.SubscribePacket)
-	sub.Topics = append(sub.Topics, topic)
-	sub.Qoss = append(sub.Qoss, qos)
+// complete completes the token of an in flight message, and forgets it.
+func (c *mqttclient) complete(mid uint16, err error) {
+	c.mu.Lock()
+	o, ok := c.inflight[mid]
+	delete(c.inflight, mid)
+	c.mu.Unlock()
+	if ok {
+		o.token.complete(err)
+	}
+}
 
-	if callback != nil {
-		c.msgRouter.addRoute(topic, callback)
+// write writes a packet to the connection.
+func (c *mqttclient) write(p packets.ControlPacket) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.conn == nil {
+		return ErrNotConnected
 	}
+	c.lastSent = time.Now()
+	return p.Write(c.conn)
+}
 
-	sub.MessageID = c.mid
-	c.mid++
+// resend retransmits the messages that are not acknowledged after the retry
+// interval, with the DUP flag for PUBLISH packets.
+func (c *mqttclient) resend() {
+	interval := c.opts.RetryInterval
+	if interval <= 0 {
+		interval = defaultRetryInterval
+	}
+	now := time.Now()
+	var due []packets.ControlPacket
+	c.mu.Lock()
+	for _, o := range c.inflight {
+		if now.Sub(o.sent) >= interval {
+			o.sent = now
+			due = append(due, o.packet)
+		}
+	}
+	c.mu.Unlock()
 
-	// drop in the channel to send
-	err := sub.Write(c.conn)
-	if err != nil {
-		return &mqtttoken{err: err}
+	for _, p := range due {
+		c.writeMu.Lock()
+		if pub, ok := p.(*packets.PublishPacket); ok {
+			pub.Dup = true
+		}
+		if c.conn != nil {
+			c.lastSent = now
+			p.Write(c.conn)
+		}
+		c.writeMu.Unlock()
 	}
+}
 
-	return &mqtttoken{}
+// Subscribe starts a new subscription. Provide a MessageHandler to be executed when
+// a message is published on the topic provided.
+// The returned token is a *SubscribeToken, giving the QoS granted by the broker.
+func (c *mqttclient) Subscribe(topic string, qos byte, callback MessageHandler) Token {
+	return c.SubscribeMultiple(map[string]byte{topic: qos}, callback)
 }
 
 // SubscribeMultiple starts a new subscription for multiple topics. Provide a MessageHandler to
 // be executed when a message is published on one of the topics provided.
+// The returned token is a *SubscribeToken, giving the QoS granted by the broker.
 func (c *mqttclient) SubscribeMultiple(filters map[string]byte, callback MessageHandler) Token {
-	return &mqtttoken{}
+	sub := packets.NewControlPacket(packets.Subscribe).(*packets.SubscribePacket)
+	for topic, qos := range filters {
+		sub.Topics = append(sub.Topics, topic)
+		sub.Qoss = append(sub.Qoss, qos)
+	}
+	token := newSubscribeToken(sub.Topics)
+	if !c.IsConnected() {
+		token.complete(ErrNotConnected)
+		return token
+	}
+	for _, qos := range sub.Qoss {
+		if qos > 2 {
+			token.complete(ErrInvalidQoS)
+			return token
+		}
+	}
+
+	if callback != nil {
+		for topic := range filters {
+			c.msgRouter.addRoute(topic, callback)
+		}
+	}
+
+	c.mu.Lock()
+	for topic, qos := range filters {
+		c.subscriptions[topic] = qos
+	}
+	mid, err := c.nextID()
+	if err != nil {
+		c.mu.Unlock()
+		token.complete(err)
+		return token
+	}
+	sub.MessageID = mid
+	c.inflight[mid] = &outbound{packet: sub, token: token, sent: time.Now()}
+	c.mu.Unlock()
+
+	if err := c.write(sub); err != nil && !c.opts.AutoReconnect {
+		c.complete(mid, err)
+	}
+	return token
 }
 
 // Unsubscribe will end the subscription from each of the topics provided.
 // Messages published to those topics from other clients will no longer be
 // received.
+// The routes of the topics are removed once the broker acknowledged it.
 func (c *mqttclient) Unsubscribe(topics ...string) Token {
-	return &mqtttoken{}
+	token := newUnsubscribeToken(topics)
+	if !c.IsConnected() {
+		token.complete(ErrNotConnected)
+		return token
+	}
+
+	unsub := packets.NewControlPacket(packets.Unsubscribe).(*packets.UnsubscribePacket)
+	unsub.Topics = topics
+
+	c.mu.Lock()
+	mid, err := c.nextID()
+	if err != nil {
+		c.mu.Unlock()
+		token.complete(err)
+		return token
+	}
+	unsub.MessageID = mid
+	c.inflight[mid] = &outbound{packet: unsub, token: token, sent: time.Now()}
+	c.mu.Unlock()
+
+	if err := c.write(unsub); err != nil && !c.opts.AutoReconnect {
+		c.complete(mid, err)
+	}
+	return token
 }
 
 // OptionsReader returns a ClientOptionsReader which is a copy of the clientoptions
 // in use by the client.
 func (c *mqttclient) OptionsReader() ClientOptionsReader {
-	r := ClientOptionsReader{}
-	return r
+	o := *c.opts
+	return ClientOptionsReader{options: &o}
+}
+
+// acknowledged removes the message mid from the messages in flight, and
+// returns its token if it was there.
+func (c *mqttclient) acknowledged(mid uint16) (tokenCompleter, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, ok := c.inflight[mid]
+	if !ok {
+		return nil, false
+	}
+	delete(c.inflight, mid)
+	return o.token, true
+}
+
+// subscribed records the QoS granted by the broker for each topic of a
+// subscription. The topics refused are not subscribed to again on reconnect.
+func (c *mqttclient) subscribed(token *SubscribeToken, codes []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, topic := range token.subs {
+		if i >= len(codes) {
+			break
+		}
+		token.subResult[topic] = codes[i]
+		if codes[i] == SubscribeFailure {
+			delete(c.subscriptions, topic)
+		}
+	}
 }
 
-func processInbound(c *mqttclient) {
+// unsubscribed forgets the topics of an unsubscription.
+func (c *mqttclient) unsubscribed(token *UnsubscribeToken) {
+	c.mu.Lock()
+	for _, topic := range token.unsubs {
+		delete(c.subscriptions, topic)
+	}
+	c.mu.Unlock()
+	for _, topic := range token.unsubs {
+		c.msgRouter.deleteRoute(topic)
+	}
+}
+
+// processInbound handles the packets received on a connection, until it is
+// stopped.
+func processInbound(c *mqttclient, inbound chan packets.ControlPacket, stop chan struct{}) {
 	for {
 		select {
-		case msg := <-c.inbound:
+		case msg := <-inbound:
 			switch m := msg.(type) {
 			case *packets.PingrespPacket:
-				// TODO: handle this
+				c.mu.Lock()
+				c.pingSent = time.Time{}
+				c.mu.Unlock()
 			case *packets.SubackPacket:
-				// TODO: handle this
+				if token, ok := c.acknowledged(m.MessageID); ok {
+					if sub, ok := token.(*SubscribeToken); ok {
+						c.subscribed(sub, m.ReturnCodes)
+					}
+					token.complete(nil)
+				}
 			case *packets.UnsubackPacket:
-				// TODO: handle this
+				if token, ok := c.acknowledged(m.MessageID); ok {
+					if unsub, ok := token.(*UnsubscribeToken); ok {
+						c.unsubscribed(unsub)
+					}
+					token.complete(nil)
+				}
 			case *packets.PublishPacket:
-				// TODO: handle Qos
-				c.incomingPubChan <- m
+				if m.Qos == 2 {
+					// a QoS 2 message is only delivered once, until its PUBREL
+					c.mu.Lock()
+					_, dup := c.received[m.MessageID]
+					c.received[m.MessageID] = struct{}{}
+					c.mu.Unlock()
+					if dup {
+						pr := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+						pr.MessageID = m.MessageID
+						c.write(pr)
+						continue
+					}
+				}
+				select {
+				case c.incomingPubChan <- m:
+				case <-stop:
+					if m.Qos == 2 {
+						// not delivered, so not a duplicate when it is sent again
+						c.mu.Lock()
+						delete(c.received, m.MessageID)
+						c.mu.Unlock()
+					}
+					return
+				}
 			case *packets.PubackPacket:
-				// TODO: handle this
+				c.complete(m.MessageID, nil)
 			case *packets.PubrecPacket:
-				// TODO: handle this
+				pr := packets.NewControlPacket(packets.Pubrel).(*packets.PubrelPacket)
+				pr.MessageID = m.MessageID
+				c.mu.Lock()
+				if o, ok := c.inflight[m.MessageID]; ok {
+					o.packet = pr
+					o.sent = time.Now()
+				}
+				c.mu.Unlock()
+				c.write(pr)
 			case *packets.PubrelPacket:
-				// TODO: handle this
+				c.mu.Lock()
+				delete(c.received, m.MessageID)
+				c.mu.Unlock()
+				pc := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+				pc.MessageID = m.MessageID
+				c.write(pc)
 			case *packets.PubcompPacket:
-				// TODO: handle this
+				c.complete(m.MessageID, nil)
 			}
-		case <-c.stop:
+		case <-stop:
 			return
 		}
 	}
@@ -243,61 +718,122 @@ func processInbound(c *mqttclient) {
 
 // readMessages reads incoming messages off the wire.
 // incoming messages are then send into inbound channel.
-func readMessages(c *mqttclient) {
-	var err error
-	var cp packets.ControlPacket
-
-PROCESS:
+// It also takes care of the retransmissions and of the keepalive, and reports
+// the loss of the connection.
+func readMessages(c *mqttclient, conn net.Conn, inbound chan packets.ControlPacket, stop chan struct{}) {
 	for {
-		if cp, err = c.ReadPacket(); err != nil {
-			break PROCESS
+		cp, err := readPacket(conn, c.pingTimeout())
+		if err != nil {
+			c.connectionLost(stop, err)
+			return
 		}
 		if cp != nil {
-			c.inbound <- cp
-			// TODO: Notify keepalive logic that we recently received a packet
+			select {
+			case inbound <- cp:
+			case <-stop:
+				return
+			}
 		}
+		c.resend()
+		if err := c.keepalive(); err != nil {
+			c.connectionLost(stop, err)
+			return
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// keepalive sends a PINGREQ when nothing was sent for the KeepAlive interval,
+// and fails when the PINGRESP is not received within the PingTimeout.
+func (c *mqttclient) keepalive() error {
+	if c.opts.KeepAlive <= 0 {
+		return nil
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	pingSent := c.pingSent
+	c.mu.Unlock()
+	if !pingSent.IsZero() {
+		if now.Sub(pingSent) >= c.pingTimeout() {
+			return ErrPingTimeout
+		}
+		return nil
+	}
 
-		time.Sleep(100 * time.Millisecond)
+	c.writeMu.Lock()
+	lastSent := c.lastSent
+	c.writeMu.Unlock()
+	if now.Sub(lastSent) < time.Duration(c.opts.KeepAlive)*time.Second {
+		return nil
 	}
+	c.mu.Lock()
+	c.pingSent = now
+	c.mu.Unlock()
+	return c.write(packets.NewControlPacket(packets.Pingreq))
+}
 
-	// TODO: handle if we received an error on read.
-	// If disconnect is in progress, swallow error and return
+// pingTimeout returns the time to wait for a PINGRESP, which is also the time
+// to wait for the rest of a packet once it started.
+func (c *mqttclient) pingTimeout() time.Duration {
+	if c.opts.PingTimeout <= 0 {
+		return defaultPingTimeout
+	}
+	return c.opts.PingTimeout
 }
 
 func (c *mqttclient) ackFunc(packet *packets.PublishPacket) func() {
 	return func() {
 		switch packet.Qos {
 		case 2:
-			// pr := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
-			// pr.MessageID = packet.MessageID
-			// DEBUG.Println(NET, "putting pubrec msg on obound")
-			// select {
-			// case c.oboundP <- &PacketAndToken{p: pr, t: nil}:
-			// case <-c.stop:
-			// }
-			// DEBUG.Println(NET, "done putting pubrec msg on obound")
+			pr := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+			pr.MessageID = packet.MessageID
+			c.write(pr)
 		case 1:
-			// pa := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
-			// pa.MessageID = packet.MessageID
-			// DEBUG.Println(NET, "putting puback msg on obound")
-			// persistOutbound(c.persist, pa)
-			// select {
-			// case c.oboundP <- &PacketAndToken{p: pa, t: nil}:
-			// case <-c.stop:
-			// }
-			// DEBUG.Println(NET, "done putting puback msg on obound")
+			pa := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+			pa.MessageID = packet.MessageID
+			c.write(pa)
 		case 0:
 			// do nothing, since there is no need to send an ack packet back
 		}
 	}
 }
 
-// ReadPacket tries to read the next incoming packet from the MQTT broker.
+// readPacket tries to read the next incoming packet from the MQTT broker.
 // If there is no data yet but also is no error, it returns nil for both values.
-func (c *mqttclient) ReadPacket() (packets.ControlPacket, error) {
+// A packet must be received in full within timeout, otherwise the timeout
+// error is returned and the connection is lost.
+func readPacket(conn net.Conn, timeout time.Duration) (packets.ControlPacket, error) {
 	// check for data first...
-	if net.ActiveDevice.IsSocketDataAvailable() {
-		return packets.ReadPacket(c.conn)
+	if conn, ok := conn.(interface{ IsDataAvailable() bool }); ok && !conn.IsDataAvailable() {
+		return nil, nil
+	}
+	// without deadline, the devices return no data instead of blocking, and
+	// a partial packet would be waited for forever
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	r := &countingReader{r: conn}
+	cp, err := packets.ReadPacket(r)
+	if err, ok := err.(net.Error); ok && err.Timeout() && r.n == 0 {
+		// no packet started, on connections without IsDataAvailable
+		return nil, nil
 	}
-	return nil, nil
+	return cp, err
+}
+
+// countingReader counts the bytes read, to tell a partial packet from no
+// packet at all.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += n
+	return n, err
 }
@@ -0,0 +1,697 @@
+package mqtt_test
+
+import (
+	"bytes"
+	stdnet "net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/net"
+	"tinygo.org/x/drivers/net/loopback"
+	"tinygo.org/x/drivers/net/mqtt"
+)
+
+// broker is a stand-in for an MQTT broker, serving one client connection at a
+// time over the network of the host. It answers CONNECT packets itself, and
+// passes the other packets to the test.
+type broker struct {
+	c        *qt.C
+	l        stdnet.Listener
+	conns    chan stdnet.Conn
+	received chan packets.ControlPacket
+
+	// connack is the return code sent in answer to CONNECT, or -1 to not
+	// answer.
+	connack int
+}
+
+func newBroker(c *qt.C) *broker {
+	l, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	b := &broker{
+		c:        c,
+		l:        l,
+		conns:    make(chan stdnet.Conn, 1),
+		received: make(chan packets.ControlPacket, 10),
+	}
+	c.Defer(func() {
+		l.Close()
+		b.drop()
+	})
+	go b.serve()
+	return b
+}
+
+func (b *broker) serve() {
+	for {
+		conn, err := b.l.Accept()
+		if err != nil {
+			return
+		}
+		b.conns <- conn
+		b.handle(conn)
+	}
+}
+
+func (b *broker) handle(conn stdnet.Conn) {
+	for {
+		p, err := packets.ReadPacket(conn)
+		if err != nil {
+			b.drop()
+			return
+		}
+		if _, ok := p.(*packets.ConnectPacket); ok {
+			if b.connack >= 0 {
+				ack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+				ack.ReturnCode = byte(b.connack)
+				ack.Write(conn)
+			}
+			continue
+		}
+		b.received <- p
+	}
+}
+
+// drop closes the connection of the client, if any.
+func (b *broker) drop() {
+	select {
+	case conn := <-b.conns:
+		conn.Close()
+	default:
+	}
+}
+
+// expect returns the next packet sent by the client.
+func (b *broker) expect() packets.ControlPacket {
+	select {
+	case p := <-b.received:
+		return p
+	case <-time.After(5 * time.Second):
+		b.c.Fatal("timeout waiting for a packet from the client")
+		return nil
+	}
+}
+
+// send sends a packet to the client.
+func (b *broker) send(p packets.ControlPacket) {
+	select {
+	case conn := <-b.conns:
+		b.conns <- conn
+		b.c.Assert(p.Write(conn), qt.IsNil)
+	case <-time.After(5 * time.Second):
+		b.c.Fatal("no client connected")
+	}
+}
+
+// write writes raw bytes to the client.
+func (b *broker) write(data []byte) {
+	select {
+	case conn := <-b.conns:
+		b.conns <- conn
+		_, err := conn.Write(data)
+		b.c.Assert(err, qt.IsNil)
+	case <-time.After(5 * time.Second):
+		b.c.Fatal("no client connected")
+	}
+}
+
+func (b *broker) url() string {
+	return "tcp://" + b.l.Addr().String()
+}
+
+func newClient(c *qt.C, b *broker) (mqtt.Client, *mqtt.ClientOptions) {
+	c.Patch(&net.ActiveDevice, net.DeviceDriver(loopback.New()))
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(b.url()).SetClientID("test")
+	cl := mqtt.NewClient(opts)
+	c.Defer(func() { cl.Disconnect(0) })
+	return cl, opts
+}
+
+func TestConnectPublish(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+
+	token := cl.Connect()
+	c.Assert(token.Wait(), qt.IsTrue)
+	c.Assert(token.Error(), qt.IsNil)
+	c.Assert(cl.IsConnected(), qt.IsTrue)
+
+	token = cl.Publish("a/b", 0, false, "hello")
+	c.Assert(token.Error(), qt.IsNil)
+	p, ok := b.expect().(*packets.PublishPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(p.TopicName, qt.Equals, "a/b")
+	c.Assert(string(p.Payload), qt.Equals, "hello")
+	c.Assert(p.Qos, qt.Equals, byte(0))
+}
+
+func TestConnectRefused(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	b.connack = packets.ErrRefusedNotAuthorised
+	cl, _ := newClient(c, b)
+
+	token := cl.Connect()
+	c.Assert(token.Error(), qt.Not(qt.IsNil))
+	c.Assert(cl.IsConnected(), qt.IsFalse)
+}
+
+func TestConnectTimeout(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	b.connack = -1
+	cl, opts := newClient(c, b)
+	opts.ConnectTimeout = 100 * time.Millisecond
+
+	token := cl.Connect()
+	c.Assert(token.Error(), qt.Equals, net.ErrDeadlineExceeded)
+	c.Assert(cl.IsConnected(), qt.IsFalse)
+}
+
+func TestSubscribeReceive(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	messages := make(chan mqtt.Message, 1)
+	token := cl.Subscribe("sensors/+", 0, func(_ mqtt.Client, m mqtt.Message) {
+		messages <- m
+	})
+	c.Assert(token.Error(), qt.IsNil)
+	sub, ok := b.expect().(*packets.SubscribePacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(sub.Topics, qt.DeepEquals, []string{"sensors/+"})
+
+	ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+	ack.MessageID = sub.MessageID
+	ack.ReturnCodes = []byte{0}
+	b.send(ack)
+
+	pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	pub.TopicName = "sensors/temperature"
+	pub.Payload = []byte("21.5")
+	b.send(pub)
+
+	select {
+	case m := <-messages:
+		c.Assert(m.Topic(), qt.Equals, "sensors/temperature")
+		c.Assert(string(m.Payload()), qt.Equals, "21.5")
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the message")
+	}
+}
+
+func TestPublishQoS1(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	token := cl.Publish("a/b", 1, false, "hello")
+	p, ok := b.expect().(*packets.PublishPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(p.Qos, qt.Equals, byte(1))
+	c.Assert(p.MessageID, qt.Not(qt.Equals), uint16(0))
+	c.Assert(token.WaitTimeout(100*time.Millisecond), qt.IsFalse)
+
+	ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+	ack.MessageID = p.MessageID
+	b.send(ack)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.IsNil)
+}
+
+func TestPublishQoS2(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	token := cl.Publish("a/b", 2, false, "hello")
+	p, ok := b.expect().(*packets.PublishPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(p.Qos, qt.Equals, byte(2))
+
+	rec := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+	rec.MessageID = p.MessageID
+	b.send(rec)
+	rel, ok := b.expect().(*packets.PubrelPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(rel.MessageID, qt.Equals, p.MessageID)
+	c.Assert(token.WaitTimeout(100*time.Millisecond), qt.IsFalse)
+
+	comp := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+	comp.MessageID = p.MessageID
+	b.send(comp)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.IsNil)
+}
+
+func TestPublishRetry(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, opts := newClient(c, b)
+	opts.SetRetryInterval(200 * time.Millisecond)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	token := cl.Publish("a/b", 1, false, "hello")
+	p, ok := b.expect().(*packets.PublishPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(p.Dup, qt.IsFalse)
+
+	dup, ok := b.expect().(*packets.PublishPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(dup.Dup, qt.IsTrue)
+	c.Assert(dup.MessageID, qt.Equals, p.MessageID)
+	c.Assert(string(dup.Payload), qt.Equals, "hello")
+
+	ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+	ack.MessageID = p.MessageID
+	b.send(ack)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.IsNil)
+}
+
+func TestPublishDisconnect(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	token := cl.Publish("a/b", 1, false, "hello")
+	b.expect()
+	cl.Disconnect(0)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.Equals, mqtt.ErrDisconnected)
+}
+
+func TestPublishInvalidQoS(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	token := cl.Publish("a/b", 3, false, "hello")
+	c.Assert(token.WaitTimeout(time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.Equals, mqtt.ErrInvalidQoS)
+	token = cl.SubscribeMultiple(map[string]byte{"a/b": 1, "c/d": 3}, nil)
+	c.Assert(token.WaitTimeout(time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.Equals, mqtt.ErrInvalidQoS)
+}
+
+// subscribe subscribes the client to topic at qos, and returns the messages
+// it receives.
+func subscribe(c *qt.C, b *broker, cl mqtt.Client, topic string, qos byte) chan mqtt.Message {
+	messages := make(chan mqtt.Message, 2)
+	token := cl.Subscribe(topic, qos, func(_ mqtt.Client, m mqtt.Message) {
+		messages <- m
+	})
+	c.Assert(token.Error(), qt.IsNil)
+	sub, ok := b.expect().(*packets.SubscribePacket)
+	c.Assert(ok, qt.IsTrue)
+
+	ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+	ack.MessageID = sub.MessageID
+	ack.ReturnCodes = []byte{qos}
+	b.send(ack)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.IsNil)
+	return messages
+}
+
+func receive(c *qt.C, messages chan mqtt.Message) mqtt.Message {
+	select {
+	case m := <-messages:
+		return m
+	case <-time.After(5 * time.Second):
+		c.Fatal("timeout waiting for the message")
+		return nil
+	}
+}
+
+func TestReceiveQoS1(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+	messages := subscribe(c, b, cl, "a/b", 1)
+
+	pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	pub.TopicName = "a/b"
+	pub.Qos = 1
+	pub.MessageID = 7
+	pub.Payload = []byte("hello")
+	b.send(pub)
+
+	m := receive(c, messages)
+	c.Assert(m.Qos(), qt.Equals, byte(1))
+	c.Assert(m.MessageID(), qt.Equals, uint16(7))
+	ack, ok := b.expect().(*packets.PubackPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(ack.MessageID, qt.Equals, uint16(7))
+}
+
+func TestReceiveQoS2(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+	messages := subscribe(c, b, cl, "a/b", 2)
+
+	pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	pub.TopicName = "a/b"
+	pub.Qos = 2
+	pub.MessageID = 9
+	pub.Payload = []byte("hello")
+	b.send(pub)
+
+	m := receive(c, messages)
+	c.Assert(string(m.Payload()), qt.Equals, "hello")
+	rec, ok := b.expect().(*packets.PubrecPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(rec.MessageID, qt.Equals, uint16(9))
+
+	// a duplicate is acknowledged again, but not delivered twice
+	pub.Dup = true
+	b.send(pub)
+	rec, ok = b.expect().(*packets.PubrecPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(rec.MessageID, qt.Equals, uint16(9))
+	select {
+	case <-messages:
+		c.Fatal("duplicate message delivered")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	rel := packets.NewControlPacket(packets.Pubrel).(*packets.PubrelPacket)
+	rel.MessageID = 9
+	b.send(rel)
+	comp, ok := b.expect().(*packets.PubcompPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(comp.MessageID, qt.Equals, uint16(9))
+}
+
+func TestKeepAlive(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, opts := newClient(c, b)
+	opts.SetKeepAlive(time.Second)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	for i := 0; i < 2; i++ {
+		_, ok := b.expect().(*packets.PingreqPacket)
+		c.Assert(ok, qt.IsTrue)
+		b.send(packets.NewControlPacket(packets.Pingresp))
+	}
+	c.Assert(cl.IsConnectionOpen(), qt.IsTrue)
+}
+
+func TestPingTimeout(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, opts := newClient(c, b)
+	lost := make(chan error, 1)
+	opts.SetKeepAlive(time.Second).
+		SetPingTimeout(200 * time.Millisecond).
+		SetAutoReconnect(false).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			lost <- err
+		})
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	_, ok := b.expect().(*packets.PingreqPacket)
+	c.Assert(ok, qt.IsTrue)
+	select {
+	case err := <-lost:
+		c.Assert(err, qt.Equals, mqtt.ErrPingTimeout)
+	case <-time.After(5 * time.Second):
+		c.Fatal("connection lost handler not called")
+	}
+	c.Assert(cl.IsConnected(), qt.IsFalse)
+}
+
+// TestPartialPacket checks that the connection is lost when a packet is not
+// received in full, even without keepalive.
+func TestPartialPacket(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, opts := newClient(c, b)
+	net.ActiveDevice.(*loopback.Driver).NoEOF = true
+	lost := make(chan error, 1)
+	opts.SetAutoReconnect(false).
+		SetKeepAlive(0).
+		SetPingTimeout(300 * time.Millisecond).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			lost <- err
+		})
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	pub.TopicName = "a/b"
+	pub.Payload = []byte("hello")
+	var buf bytes.Buffer
+	c.Assert(pub.Write(&buf), qt.IsNil)
+	b.write(buf.Bytes()[:buf.Len()/2])
+	select {
+	case err := <-lost:
+		nerr, ok := err.(net.Error)
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(nerr.Timeout(), qt.IsTrue)
+	case <-time.After(5 * time.Second):
+		c.Fatal("connection lost handler not called")
+	}
+	c.Assert(cl.IsConnected(), qt.IsFalse)
+}
+
+func TestDisconnect(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	cl.Disconnect(0)
+	_, ok := b.expect().(*packets.DisconnectPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(cl.IsConnected(), qt.IsFalse)
+	c.Assert(cl.Publish("a/b", 0, false, "hello").Error(), qt.Equals, mqtt.ErrNotConnected)
+}
+
+// TestDisconnectBlockedHandler checks that the client stops delivering the
+// messages when it disconnects, even when the handler does not return.
+func TestDisconnectBlockedHandler(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+	token := cl.Subscribe("a/b", 0, func(mqtt.Client, mqtt.Message) {
+		<-unblock
+	})
+	sub, ok := b.expect().(*packets.SubscribePacket)
+	c.Assert(ok, qt.IsTrue)
+	ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+	ack.MessageID = sub.MessageID
+	ack.ReturnCodes = []byte{0}
+	b.send(ack)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+
+	// more messages than the client buffers, read one per poll
+	for i := 0; i < 15; i++ {
+		pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+		pub.TopicName = "a/b"
+		pub.Payload = []byte("hello")
+		b.send(pub)
+	}
+	time.Sleep(2 * time.Second)
+	cl.Disconnect(0)
+
+	buf := make([]byte, 1<<20)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stack := buf[:runtime.Stack(buf, true)]
+		if !bytes.Contains(stack, []byte("mqtt.processInbound(")) {
+			break
+		}
+		if time.Now().After(deadline) {
+			c.Fatalf("processInbound still running after Disconnect:\n%s", stack)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	c := qt.New(t)
+	for _, noEOF := range []bool{false, true} {
+		name := "EOF"
+		if noEOF {
+			name = "NoEOF"
+		}
+		c.Run(name, func(c *qt.C) {
+			defer c.Done()
+			testReconnect(c, noEOF)
+		})
+	}
+}
+
+// testReconnect drops the connection of the client. Without EOF, as with the
+// devices, the client only notices it with the keepalive.
+func testReconnect(c *qt.C, noEOF bool) {
+	b := newBroker(c)
+	cl, opts := newClient(c, b)
+	net.ActiveDevice.(*loopback.Driver).NoEOF = noEOF
+	connects := make(chan struct{}, 2)
+	lost := make(chan error, 1)
+	opts.SetMaxReconnectInterval(100 * time.Millisecond).
+		SetKeepAlive(time.Second).
+		SetPingTimeout(200 * time.Millisecond).
+		SetOnConnectHandler(func(mqtt.Client) {
+			connects <- struct{}{}
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			lost <- err
+		})
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+	subscribe(c, b, cl, "a/b", 1)
+	token := cl.Publish("a/b", 1, false, "hello")
+	p, ok := b.expect().(*packets.PublishPacket)
+	c.Assert(ok, qt.IsTrue)
+
+	b.drop()
+	select {
+	case err := <-lost:
+		c.Assert(err, qt.Not(qt.IsNil))
+	case <-time.After(5 * time.Second):
+		c.Fatal("connection lost handler not called")
+	}
+	c.Assert(cl.IsConnected(), qt.IsTrue)
+
+	// the subscriptions are renewed, and the message sent again
+	sub, ok := b.expect().(*packets.SubscribePacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(sub.Topics, qt.DeepEquals, []string{"a/b"})
+	c.Assert(sub.Qoss, qt.DeepEquals, []byte{1})
+	dup, ok := b.expect().(*packets.PublishPacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(dup.Dup, qt.IsTrue)
+	c.Assert(dup.MessageID, qt.Equals, p.MessageID)
+
+	ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+	ack.MessageID = p.MessageID
+	b.send(ack)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.IsNil)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-connects:
+		case <-time.After(5 * time.Second):
+			c.Fatal("connect handler not called")
+		}
+	}
+	c.Assert(cl.IsConnectionOpen(), qt.IsTrue)
+}
+
+func TestSubscribeMultiple(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+
+	token := cl.SubscribeMultiple(map[string]byte{"a/b": 1, "c/d": 2}, nil)
+	sub, ok := b.expect().(*packets.SubscribePacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(sub.Topics, qt.HasLen, 2)
+	c.Assert(token.WaitTimeout(100*time.Millisecond), qt.IsFalse)
+
+	// the broker grants QoS 1 for c/d, and refuses a/b
+	ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+	ack.MessageID = sub.MessageID
+	for _, topic := range sub.Topics {
+		if topic == "a/b" {
+			ack.ReturnCodes = append(ack.ReturnCodes, mqtt.SubscribeFailure)
+		} else {
+			ack.ReturnCodes = append(ack.ReturnCodes, 1)
+		}
+	}
+	b.send(ack)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.IsNil)
+	c.Assert(token.(*mqtt.SubscribeToken).Result(), qt.DeepEquals, map[string]byte{
+		"a/b": mqtt.SubscribeFailure,
+		"c/d": 1,
+	})
+}
+
+func TestUnsubscribe(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, _ := newClient(c, b)
+	c.Assert(cl.Connect().Error(), qt.IsNil)
+	exact := subscribe(c, b, cl, "a/b", 0)
+	wildcard := subscribe(c, b, cl, "a/+", 0)
+
+	token := cl.Unsubscribe("a/b")
+	unsub, ok := b.expect().(*packets.UnsubscribePacket)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(unsub.Topics, qt.DeepEquals, []string{"a/b"})
+	c.Assert(token.WaitTimeout(100*time.Millisecond), qt.IsFalse)
+
+	ack := packets.NewControlPacket(packets.Unsuback).(*packets.UnsubackPacket)
+	ack.MessageID = unsub.MessageID
+	b.send(ack)
+	c.Assert(token.WaitTimeout(5*time.Second), qt.IsTrue)
+	c.Assert(token.Error(), qt.IsNil)
+
+	// only the route of the wildcard subscription is left
+	pub := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	pub.TopicName = "a/b"
+	pub.Payload = []byte("hello")
+	b.send(pub)
+	m := receive(c, wildcard)
+	c.Assert(m.Topic(), qt.Equals, "a/b")
+	select {
+	case <-exact:
+		c.Fatal("message delivered after unsubscribing")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestOptionsReader(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	b := newBroker(c)
+	cl, opts := newClient(c, b)
+	opts.SetKeepAlive(time.Minute).SetAutoReconnect(false)
+
+	r := cl.OptionsReader()
+	c.Assert(r.Servers(), qt.Equals, b.url())
+	c.Assert(r.ClientID(), qt.Equals, "test")
+	c.Assert(r.KeepAlive(), qt.Equals, time.Minute)
+	c.Assert(r.AutoReconnect(), qt.IsFalse)
+}
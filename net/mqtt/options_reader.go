@@ -0,0 +1,126 @@
+// The following code is a slightly modified version of code taken from the Paho MQTT library.
+// It is here until TinyGo can compile the "net" package from the standard library, at which time
+// it can be removed.
+
+/*
+ * Copyright (c) 2013 IBM Corp.
+ *
+ * All rights reserved. This program and the accompanying materials
+ * are made available under the terms of the Eclipse Public License v1.0
+ * which accompanies this distribution, and is available at
+ * http://www.eclipse.org/legal/epl-v10.html
+ *
+ * Contributors:
+ *    Seth Hoenig
+ *    Allan Stockdill-Mander
+ *    Mike Robertson
+ */
+
+package mqtt
+
+import "time"
+
+// Servers returns the broker URI that is set in the ClientOptions
+func (r *ClientOptionsReader) Servers() string {
+	return r.options.Servers
+}
+
+// ResumeSubs returns true if resuming stored (un)sub is enabled
+func (r *ClientOptionsReader) ResumeSubs() bool {
+	return r.options.ResumeSubs
+}
+
+// ClientID returns the set client id
+func (r *ClientOptionsReader) ClientID() string {
+	return r.options.ClientID
+}
+
+// Username returns the set username
+func (r *ClientOptionsReader) Username() string {
+	return r.options.Username
+}
+
+// Password returns the set password
+func (r *ClientOptionsReader) Password() string {
+	return r.options.Password
+}
+
+// CleanSession returns whether Cleansession is set
+func (r *ClientOptionsReader) CleanSession() bool {
+	return r.options.CleanSession
+}
+
+// Order returns the set value of Order
+func (r *ClientOptionsReader) Order() bool {
+	return r.options.Order
+}
+
+// WillEnabled returns whether a Will is enabled
+func (r *ClientOptionsReader) WillEnabled() bool {
+	return r.options.WillEnabled
+}
+
+// WillTopic returns the set Will topic
+func (r *ClientOptionsReader) WillTopic() string {
+	return r.options.WillTopic
+}
+
+// WillPayload returns the set Will payload
+func (r *ClientOptionsReader) WillPayload() []byte {
+	return r.options.WillPayload
+}
+
+// WillQos returns the set Will QoS
+func (r *ClientOptionsReader) WillQos() byte {
+	return r.options.WillQos
+}
+
+// WillRetained returns the set Will retained value
+func (r *ClientOptionsReader) WillRetained() bool {
+	return r.options.WillRetained
+}
+
+// ProtocolVersion returns the set protocol version
+func (r *ClientOptionsReader) ProtocolVersion() uint {
+	return r.options.ProtocolVersion
+}
+
+// KeepAlive returns the set keep alive time
+func (r *ClientOptionsReader) KeepAlive() time.Duration {
+	return time.Duration(r.options.KeepAlive) * time.Second
+}
+
+// PingTimeout returns the set ping timeout
+func (r *ClientOptionsReader) PingTimeout() time.Duration {
+	return r.options.PingTimeout
+}
+
+// ConnectTimeout returns the set connect timeout
+func (r *ClientOptionsReader) ConnectTimeout() time.Duration {
+	return r.options.ConnectTimeout
+}
+
+// MaxReconnectInterval returns the set maximum reconnect interval
+func (r *ClientOptionsReader) MaxReconnectInterval() time.Duration {
+	return r.options.MaxReconnectInterval
+}
+
+// AutoReconnect returns whether auto reconnect is enabled
+func (r *ClientOptionsReader) AutoReconnect() bool {
+	return r.options.AutoReconnect
+}
+
+// WriteTimeout returns the set write timeout
+func (r *ClientOptionsReader) WriteTimeout() time.Duration {
+	return r.options.WriteTimeout
+}
+
+// RetryInterval returns the set retry interval
+func (r *ClientOptionsReader) RetryInterval() time.Duration {
+	return r.options.RetryInterval
+}
+
+// MessageChannelDepth returns the set message channel depth
+func (r *ClientOptionsReader) MessageChannelDepth() uint {
+	return r.options.MessageChannelDepth
+}
@@ -22,6 +22,7 @@ package mqtt
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/eclipse/paho.mqtt.golang/packets"
@@ -100,6 +101,17 @@ type Token interface {
 	Error() error
 }
 
+// ConnectionLostHandler is a callback type which can be set to be
+// executed upon an unintended disconnection from the MQTT broker.
+// Disconnects caused by calling Disconnect or ForceDisconnect will
+// not cause an OnConnectionLost callback to execute.
+type ConnectionLostHandler func(Client, error)
+
+// OnConnectHandler is a callback that is called when the client
+// state changes from unconnected/disconnected to connected. Both
+// at initial connection and on reconnection
+type OnConnectHandler func(Client)
+
 // MessageHandler is a callback type which can be set to be
 // executed upon the arrival of messages published to topics
 // to which the client is subscribed.
@@ -126,6 +138,7 @@ type message struct {
 	messageID uint16
 	payload   []byte
 	ack       func()
+	once      sync.Once
 }
 
 func (m *message) Duplicate() bool {
@@ -153,7 +166,7 @@ func (m *message) Payload() []byte {
 }
 
 func (m *message) Ack() {
-	return
+	m.once.Do(m.ack)
 }
 
 func messageFromPublish(p *packets.PublishPacket, ack func()) Message {
@@ -200,9 +213,10 @@ type ClientOptions struct {
 	AutoReconnect        bool
 	//Store                   Store
 	//DefaultPublishHandler   MessageHandler
-	//OnConnect               OnConnectHandler
-	//OnConnectionLost        ConnectionLostHandler
+	OnConnect            OnConnectHandler
+	OnConnectionLost     ConnectionLostHandler
 	WriteTimeout        time.Duration
+	RetryInterval       time.Duration
 	MessageChannelDepth uint
 	ResumeSubs          bool
 	//HTTPHeaders             http.Header
@@ -210,7 +224,15 @@ type ClientOptions struct {
 
 // NewClientOptions returns a new ClientOptions struct.
 func NewClientOptions() *ClientOptions {
-	return &ClientOptions{Adaptor: net.ActiveDevice, ProtocolVersion: 4}
+	return &ClientOptions{
+		Adaptor:              net.ActiveDevice,
+		ProtocolVersion:      4,
+		KeepAlive:            30,
+		PingTimeout:          10 * time.Second,
+		ConnectTimeout:       30 * time.Second,
+		MaxReconnectInterval: 10 * time.Minute,
+		AutoReconnect:        true,
+	}
 }
 
 // AddBroker adds a broker URI to the list of brokers to be used. The format should be
@@ -278,3 +300,64 @@ func (o *ClientOptions) SetBinaryWill(topic string, payload []byte, qos byte, re
 	o.WillRetained = retained
 	return o
 }
+
+// SetRetryInterval sets the time to wait for the acknowledgement of a QoS 1
+// or 2 message before sending it again. The default is 20 seconds.
+func (o *ClientOptions) SetRetryInterval(d time.Duration) *ClientOptions {
+	o.RetryInterval = d
+	return o
+}
+
+// SetKeepAlive will set the amount of time (in seconds) that the client
+// should wait before sending a PING request to the broker. This will
+// allow the client to know that a connection has not been lost with the
+// server.
+func (o *ClientOptions) SetKeepAlive(k time.Duration) *ClientOptions {
+	o.KeepAlive = int64(k / time.Second)
+	return o
+}
+
+// SetPingTimeout will set the amount of time (in seconds) that the client
+// will wait after sending a PING request to the broker, before deciding
+// that the connection has been lost. Default is 10 seconds.
+func (o *ClientOptions) SetPingTimeout(k time.Duration) *ClientOptions {
+	o.PingTimeout = k
+	return o
+}
+
+// SetConnectTimeout limits how long the client will wait when trying to open a connection
+// to an MQTT server before timing out. A duration of 0 never times out.
+// Default 30 seconds.
+func (o *ClientOptions) SetConnectTimeout(t time.Duration) *ClientOptions {
+	o.ConnectTimeout = t
+	return o
+}
+
+// SetMaxReconnectInterval sets the maximum time that will be waited between reconnection attempts
+// when connection is lost
+func (o *ClientOptions) SetMaxReconnectInterval(t time.Duration) *ClientOptions {
+	o.MaxReconnectInterval = t
+	return o
+}
+
+// SetAutoReconnect sets whether the automatic reconnection logic should be used
+// when the connection is lost, even if disabled the ConnectionLostHandler is still
+// called
+func (o *ClientOptions) SetAutoReconnect(a bool) *ClientOptions {
+	o.AutoReconnect = a
+	return o
+}
+
+// SetOnConnectHandler sets the function to be called when the client is connected. Both
+// at initial connection time and upon automatic reconnect.
+func (o *ClientOptions) SetOnConnectHandler(onConn OnConnectHandler) *ClientOptions {
+	o.OnConnect = onConn
+	return o
+}
+
+// SetConnectionLostHandler will set the OnConnectionLost callback to be executed
+// in the case where the client unexpectedly loses connection with the MQTT broker.
+func (o *ClientOptions) SetConnectionLostHandler(onLost ConnectionLostHandler) *ClientOptions {
+	o.OnConnectionLost = onLost
+	return o
+}
@@ -2,18 +2,100 @@ package mqtt
 
 import "time"
 
+// mqtttoken is completed when the operation it tracks completes, with the
+// error of the operation if any.
 type mqtttoken struct {
-	err error
+	done chan struct{}
+	err  error
 }
 
+func newToken() *mqtttoken {
+	return &mqtttoken{done: make(chan struct{})}
+}
+
+// completedToken returns a token already completed with err.
+func completedToken(err error) *mqtttoken {
+	t := newToken()
+	t.complete(err)
+	return t
+}
+
+// complete marks the operation as completed.
+func (t *mqtttoken) complete(err error) {
+	t.err = err
+	close(t.done)
+}
+
+// Wait will wait indefinitely for the Token to complete, ie the Publish
+// to be sent and confirmed receipt from the broker.
 func (t *mqtttoken) Wait() bool {
+	<-t.done
 	return true
 }
 
-func (t *mqtttoken) WaitTimeout(time.Duration) bool {
-	return true
+// WaitTimeout takes a time.Duration to wait for the flow associated with the
+// Token to complete, returns true if it returned before the timeout or
+// returns false if the timeout occurred.
+func (t *mqtttoken) WaitTimeout(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-t.done:
+		return true
+	case <-timer.C:
+		return false
+	}
 }
 
+// Error returns the error of the operation once it is completed, or nil.
 func (t *mqtttoken) Error() error {
-	return t.err
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
+
+// tokenCompleter is a token which can be completed, when the acknowledgement
+// of its packet is received.
+type tokenCompleter interface {
+	Token
+	complete(err error)
+}
+
+// SubscribeToken is an extension of Token containing the extra fields
+// required to provide information about calls to Subscribe()
+type SubscribeToken struct {
+	*mqtttoken
+	subs      []string
+	subResult map[string]byte
+}
+
+func newSubscribeToken(subs []string) *SubscribeToken {
+	return &SubscribeToken{mqtttoken: newToken(), subs: subs, subResult: make(map[string]byte)}
+}
+
+// Result returns a map of topics that were subscribed to along with
+// the matching return code from the broker. This is either the Qos
+// value of the subscription or an error code (0x80).
+// It is empty until the token is completed.
+func (s *SubscribeToken) Result() map[string]byte {
+	select {
+	case <-s.done:
+		return s.subResult
+	default:
+		return map[string]byte{}
+	}
+}
+
+// UnsubscribeToken is an extension of Token containing the extra fields
+// required to provide information about calls to Unsubscribe()
+type UnsubscribeToken struct {
+	*mqtttoken
+	unsubs []string
+}
+
+func newUnsubscribeToken(unsubs []string) *UnsubscribeToken {
+	return &UnsubscribeToken{mqtttoken: newToken(), unsubs: unsubs}
 }
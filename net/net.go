@@ -17,16 +17,13 @@ func DialUDP(network string, laddr, raddr *UDPAddr) (*UDPSerialConn, error) {
 	sendport := strconv.Itoa(raddr.Port)
 	listenport := strconv.Itoa(laddr.Port)
 
-	// disconnect any old socket
-	ActiveDevice.DisconnectSocket()
-
 	// connect new socket
-	err := ActiveDevice.ConnectUDPSocket(addr, sendport, listenport)
+	sock, err := ActiveDevice.ConnectUDPSocket(addr, sendport, listenport)
 	if err != nil {
 		return nil, err
 	}
 
-	return &UDPSerialConn{SerialConn: SerialConn{Adaptor: ActiveDevice}, laddr: laddr, raddr: raddr}, nil
+	return &UDPSerialConn{SerialConn: SerialConn{Adaptor: ActiveDevice, Socket: sock}, laddr: laddr, raddr: raddr}, nil
 }
 
 // ListenUDP listens for UDP connections on the port listed in laddr.
@@ -35,16 +32,13 @@ func ListenUDP(network string, laddr *UDPAddr) (*UDPSerialConn, error) {
 	sendport := "0"
 	listenport := strconv.Itoa(laddr.Port)
 
-	// disconnect any old socket
-	ActiveDevice.DisconnectSocket()
-
 	// connect new socket
-	err := ActiveDevice.ConnectUDPSocket(addr, sendport, listenport)
+	sock, err := ActiveDevice.ConnectUDPSocket(addr, sendport, listenport)
 	if err != nil {
 		return nil, err
 	}
 
-	return &UDPSerialConn{SerialConn: SerialConn{Adaptor: ActiveDevice}, laddr: laddr}, nil
+	return &UDPSerialConn{SerialConn: SerialConn{Adaptor: ActiveDevice, Socket: sock}, laddr: laddr}, nil
 }
 
 // DialTCP makes a TCP network connection. raadr is the port that the messages will
@@ -54,16 +48,13 @@ func DialTCP(network string, laddr, raddr *TCPAddr) (*TCPSerialConn, error) {
 	addr := raddr.IP.String()
 	sendport := strconv.Itoa(raddr.Port)
 
-	// disconnect any old socket?
-	//ActiveDevice.DisconnectSocket()
-
 	// connect new socket
-	err := ActiveDevice.ConnectTCPSocket(addr, sendport)
+	sock, err := ActiveDevice.ConnectTCPSocket(addr, sendport)
 	if err != nil {
 		return nil, err
 	}
 
-	return &TCPSerialConn{SerialConn: SerialConn{Adaptor: ActiveDevice}, laddr: laddr, raddr: raddr}, nil
+	return &TCPSerialConn{SerialConn: SerialConn{Adaptor: ActiveDevice, Socket: sock}, laddr: laddr, raddr: raddr}, nil
 }
 
 // Dial connects to the address on the named network.
@@ -95,6 +86,10 @@ func Dial(network, address string) (Conn, error) {
 // SerialConn is a loosely net.Conn compatible implementation
 type SerialConn struct {
 	Adaptor DeviceDriver
+	Socket  Socket
+
+	readDeadline  time.Time
+	writeDeadline time.Time
 }
 
 // UDPSerialConn is a loosely net.Conn compatible intended to support
@@ -124,44 +119,50 @@ func NewTCPSerialConn(c SerialConn, laddr, raddr *TCPAddr) *TCPSerialConn {
 }
 
 // Read reads data from the connection.
-// TODO: implement the full method functionality:
-// Read can be made to time out and return an Error with Timeout() == true
-// after a fixed time limit; see SetDeadline and SetReadDeadline.
+// Without a read deadline, Read only returns the data that has already been
+// received by the device, which may be none. With a read deadline, Read waits
+// for data, and returns ErrDeadlineExceeded if there is none before the
+// deadline; see SetDeadline and SetReadDeadline.
 func (c *SerialConn) Read(b []byte) (n int, err error) {
-	// read only the data that has been received via "+IPD" socket
-	return c.Adaptor.ReadSocket(b)
+	if c.readDeadline.IsZero() {
+		return c.Adaptor.ReadSocket(c.Socket, b)
+	}
+	for {
+		if c.Adaptor.IsSocketDataAvailable(c.Socket) {
+			n, err = c.Adaptor.ReadSocket(c.Socket, b)
+			if n > 0 || err != nil {
+				return n, err
+			}
+		}
+		if expired(c.readDeadline) {
+			return 0, ErrDeadlineExceeded
+		}
+		sleep(pollInterval)
+	}
 }
 
 // Write writes data to the connection.
-// TODO: implement the full method functionality for timeouts.
-// Write can be made to time out and return an Error with Timeout() == true
-// after a fixed time limit; see SetDeadline and SetWriteDeadline.
+// Write returns ErrDeadlineExceeded once the write deadline has passed; see
+// SetDeadline and SetWriteDeadline.
 func (c *SerialConn) Write(b []byte) (n int, err error) {
-	// specify that is a data transfer to the
-	// currently open socket, not commands to the ESP8266/ESP32.
-	err = c.Adaptor.StartSocketSend(len(b))
-	if err != nil {
-		return
-	}
-	n, err = c.Adaptor.Write(b)
-	if err != nil {
-		return n, err
+	if expired(c.writeDeadline) {
+		return 0, ErrDeadlineExceeded
 	}
-	/* TODO(bcg): this is kind of specific to espat, should maybe refactor */
-	_, err = c.Adaptor.Response(1000)
-	if err != nil {
-		return n, err
-	}
-	return n, err
+	return c.Adaptor.WriteSocket(c.Socket, b)
 }
 
 // Close closes the connection.
 // Currently only supports a single Read or Write operations without blocking.
 func (c *SerialConn) Close() error {
-	c.Adaptor.DisconnectSocket()
+	c.Adaptor.DisconnectSocket(c.Socket)
 	return nil
 }
 
+// IsDataAvailable returns whether there is data to read from the connection.
+func (c *SerialConn) IsDataAvailable() bool {
+	return c.Adaptor.IsSocketDataAvailable(c.Socket)
+}
+
 // LocalAddr returns the local network address.
 func (c *UDPSerialConn) LocalAddr() Addr {
 	return c.laddr.opAddr()
@@ -212,6 +213,8 @@ func (c *TCPSerialConn) opConn() Conn {
 //
 // A zero value for t means I/O operations will not time out.
 func (c *SerialConn) SetDeadline(t time.Time) error {
+	c.readDeadline = t
+	c.writeDeadline = t
 	return nil
 }
 
@@ -219,6 +222,7 @@ func (c *SerialConn) SetDeadline(t time.Time) error {
 // and any currently-blocked Read call.
 // A zero value for t means Read will not time out.
 func (c *SerialConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline = t
 	return nil
 }
 
@@ -228,6 +232,7 @@ func (c *SerialConn) SetReadDeadline(t time.Time) error {
 // some of the data was successfully written.
 // A zero value for t means Write will not time out.
 func (c *SerialConn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline = t
 	return nil
 }
 
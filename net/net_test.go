@@ -0,0 +1,169 @@
+package net
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// memDriver is a DeviceDriver keeping the data of its sockets in memory.
+type memDriver struct {
+	rx      map[Socket][]byte // data to be read
+	tx      map[Socket][]byte // data written
+	pending []Socket          // connections to be accepted
+}
+
+func newMemDriver() *memDriver {
+	return &memDriver{rx: map[Socket][]byte{}, tx: map[Socket][]byte{}}
+}
+
+func (d *memDriver) GetDNS(domain string) (string, error) { return domain, nil }
+
+func (d *memDriver) ConnectTCPSocket(addr, port string) (Socket, error) {
+	return Socket(len(d.rx)), nil
+}
+
+func (d *memDriver) ConnectSSLSocket(addr, port string) (Socket, error) {
+	return d.ConnectTCPSocket(addr, port)
+}
+
+func (d *memDriver) ConnectUDPSocket(addr, sendport, listenport string) (Socket, error) {
+	return d.ConnectTCPSocket(addr, sendport)
+}
+
+func (d *memDriver) ListenTCPSocket(port string) (Socket, error) { return 100, nil }
+
+func (d *memDriver) AcceptSocket(listener Socket) (Socket, error) {
+	if len(d.pending) == 0 {
+		return 0, ErrNoConnection
+	}
+	sock := d.pending[0]
+	d.pending = d.pending[1:]
+	return sock, nil
+}
+
+func (d *memDriver) DisconnectSocket(sock Socket) error { return nil }
+
+func (d *memDriver) WriteSocket(sock Socket, b []byte) (int, error) {
+	d.tx[sock] = append(d.tx[sock], b...)
+	return len(b), nil
+}
+
+func (d *memDriver) ReadSocket(sock Socket, b []byte) (int, error) {
+	n := copy(b, d.rx[sock])
+	d.rx[sock] = d.rx[sock][n:]
+	return n, nil
+}
+
+func (d *memDriver) IsSocketDataAvailable(sock Socket) bool { return len(d.rx[sock]) > 0 }
+
+// fakeClock replaces the clock of the package by one only advancing when
+// sleeping, calling onSleep after each sleep.
+func fakeClock(c *qt.C, onSleep func(elapsed time.Duration)) time.Time {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	t := start
+	c.Patch(&now, func() time.Time { return t })
+	c.Patch(&sleep, func(d time.Duration) {
+		t = t.Add(d)
+		if onSleep != nil {
+			onSleep(t.Sub(start))
+		}
+	})
+	return start
+}
+
+func TestReadWithoutDeadline(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	fakeClock(c, func(time.Duration) { c.Fatal("unexpected sleep") })
+	d := newMemDriver()
+	conn := &SerialConn{Adaptor: d, Socket: 1}
+
+	var b [4]byte
+	n, err := conn.Read(b[:])
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 0)
+
+	d.rx[1] = []byte("hello")
+	n, err = conn.Read(b[:])
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b[:n]), qt.Equals, "hell")
+}
+
+func TestReadDeadline(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	d := newMemDriver()
+	start := fakeClock(c, func(elapsed time.Duration) {
+		if elapsed == 50*time.Millisecond {
+			d.rx[1] = []byte("hi")
+		}
+	})
+	conn := &SerialConn{Adaptor: d, Socket: 1}
+	conn.SetReadDeadline(start.Add(100 * time.Millisecond))
+
+	// the data arrives before the deadline
+	var b [4]byte
+	n, err := conn.Read(b[:])
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b[:n]), qt.Equals, "hi")
+	c.Assert(now().Sub(start), qt.Equals, 50*time.Millisecond)
+
+	// no more data before the deadline
+	n, err = conn.Read(b[:])
+	c.Assert(err, qt.Equals, ErrDeadlineExceeded)
+	c.Assert(err.(Error).Timeout(), qt.IsTrue)
+	c.Assert(n, qt.Equals, 0)
+	c.Assert(now().Sub(start), qt.Equals, 100*time.Millisecond)
+
+	// the deadline can be extended
+	d.rx[1] = []byte("again")
+	conn.SetDeadline(start.Add(time.Second))
+	n, err = conn.Read(b[:])
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b[:n]), qt.Equals, "agai")
+}
+
+func TestWriteDeadline(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	start := fakeClock(c, nil)
+	d := newMemDriver()
+	conn := &SerialConn{Adaptor: d, Socket: 2}
+
+	conn.SetWriteDeadline(start.Add(time.Millisecond))
+	n, err := conn.Write([]byte("ok"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 2)
+
+	conn.SetWriteDeadline(start)
+	_, err = conn.Write([]byte("late"))
+	c.Assert(err, qt.Equals, ErrDeadlineExceeded)
+
+	conn.SetWriteDeadline(time.Time{})
+	_, err = conn.Write([]byte("!"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(d.tx[2]), qt.Equals, "ok!")
+}
+
+func TestAcceptDeadline(t *testing.T) {
+	c := qt.New(t)
+	defer c.Done()
+	d := newMemDriver()
+	start := fakeClock(c, func(elapsed time.Duration) {
+		if elapsed == 30*time.Millisecond {
+			d.pending = append(d.pending, 3)
+		}
+	})
+	l := &TCPListener{adaptor: d, sock: 100, laddr: &TCPAddr{Port: 80}}
+	l.SetDeadline(start.Add(100 * time.Millisecond))
+
+	conn, err := l.AcceptTCP()
+	c.Assert(err, qt.IsNil)
+	c.Assert(conn.Socket, qt.Equals, Socket(3))
+
+	_, err = l.Accept()
+	c.Assert(err, qt.Equals, ErrDeadlineExceeded)
+	c.Assert(now().Sub(start), qt.Equals, 100*time.Millisecond)
+}
@@ -20,16 +20,13 @@ func Dial(network, address string, config *Config) (*net.TCPSerialConn, error) {
 	addr := raddr.IP.String()
 	sendport := strconv.Itoa(raddr.Port)
 
-	// disconnect any old socket
-	net.ActiveDevice.DisconnectSocket()
-
 	// connect new socket
-	err = net.ActiveDevice.ConnectSSLSocket(addr, sendport)
+	sock, err := net.ActiveDevice.ConnectSSLSocket(addr, sendport)
 	if err != nil {
 		return nil, err
 	}
 
-	return net.NewTCPSerialConn(net.SerialConn{Adaptor: net.ActiveDevice}, nil, raddr), nil
+	return net.NewTCPSerialConn(net.SerialConn{Adaptor: net.ActiveDevice, Socket: sock}, nil, raddr), nil
 }
 
 // Config is a placeholder for future compatibility with
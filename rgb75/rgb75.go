@@ -19,13 +19,16 @@ import (
 	"errors"
 	"image/color"
 	"machine"
+	"runtime/volatile"
 
+	"tinygo.org/x/drivers/hub75/matrix"
 	"tinygo.org/x/drivers/rgb75/native"
 )
 
 var (
 	ErrInvalidDataPins = errors.New("RGB data pins must be on a common GPIO port")
 	ErrInvalidHeight   = errors.New("invalid matrix height for given number of row address pins")
+	ErrInvalidDepth    = errors.New("color depth must not exceed 8 bits")
 )
 
 // Default configuration settings for a Device.
@@ -41,7 +44,25 @@ const (
 type Config struct {
 	Width      int   // (pixels) total width of matrix chain
 	Height     int   // (pixels) total height of matrix chain
-	ColorDepth uint8 // (bits) color depth of each R,G,B component
+	ColorDepth uint8 // (bits) color depth of each R,G,B component, up to 8
+
+	// Gamma is the gamma correction applied to the colors, its tables must
+	// have ColorDepth bits. Defaults to a gamma of 2.2 for all components,
+	// use an empty matrix.Gamma for a linear response.
+	Gamma *matrix.Gamma
+
+	// Layout describes chained panels and outdoor panels with a low scan
+	// rate. Its size is set from Width and Height.
+	Layout matrix.Config
+
+	// DoubleBuffer allocates a back buffer: SetPixel draws into the back
+	// buffer while the front buffer is shown, and Swap exchanges them at the
+	// end of a frame, so that animations do not tear.
+	DoubleBuffer bool
+
+	// OnFrame, if set, is called from the interrupt at the end of every
+	// frame, right after a pending swap. It must return quickly.
+	OnFrame func()
 
 	oneAddrPort bool // all address pins are on a single GPIO port
 	clkDataPort bool // RGB and CLK pins are all on a single GPIO port
@@ -49,21 +70,45 @@ type Config struct {
 	maxHeight   int  // (pixels) maximum height given number of row address pins
 }
 
+// The Width and Height of the configuration held by a Device are the size of
+// the chain as seen by the driver, see matrix.Layout.
+
 // Device represents a connection to a chain of one or more RGB LED matrix
 // panels (HUB75).
 type Device struct {
-	cfg Config         // configuration settings
-	hub native.Hub75   // HUB75 connection
-	oen machine.Pin    // output enable pin (active low)
-	lat machine.Pin    // RGB data latch pin
-	clk machine.Pin    // RGB clock pin
-	rgb dataPins       // all (6) RGB data pins
-	row []machine.Pin  // slice of all row address pins
-	buf [][]color.RGBA // panel framebuffer
-	pos rowPlane       // current row/bitplane of ISR
-	val uint32         // current timer position
+	cfg Config             // configuration settings
+	lay matrix.Layout      // mapping of the display onto the chain
+	gam *matrix.Gamma      // gamma correction
+	hub native.Hub75       // HUB75 connection
+	oen machine.Pin        // output enable pin (active low)
+	lat machine.Pin        // RGB data latch pin
+	clk machine.Pin        // RGB clock pin
+	rgb dataPins           // all (6) RGB data pins
+	row []machine.Pin      // slice of all row address pins
+	buf [2]bitplanes       // front (shown) and back (drawn) framebuffers
+	swp volatile.Register8 // non-zero while a swap is pending
+	run bool               // row-scan timer is running
+	pos rowPlane           // current row/bitplane of ISR
+	val uint32             // current timer position
 }
 
+// bitplanes is the framebuffer storage format. For each bitplane and each
+// pair of rows driven together, it holds one byte per column with the bits of
+// the upper and lower pixels, in the order they are sent to the RGB data pins
+// (see rgbBit*). That's half the size of a color.RGBA framebuffer at 4 bits of
+// color depth.
+type bitplanes []uint8
+
+// Bits of each byte of the framebuffer.
+const (
+	rgbBitR1 = 1 << iota
+	rgbBitG1
+	rgbBitB1
+	rgbBitR2
+	rgbBitG2
+	rgbBitB2
+)
+
 type (
 	// rgbPins holds one set of GPIO pins (3) for the RGB data lines on a HUB75
 	// connector (upper-half OR lower-half of matrix).
@@ -117,7 +162,6 @@ func New(oen, lat, clk machine.Pin, rgb [6]machine.Pin, row []machine.Pin) *Devi
 			lo: rgbPins{r: rgb[3], g: rgb[4], b: rgb[5]},
 		},
 		row: row,
-		buf: nil,
 		pos: rowPlane{},
 		val: 0,
 	}
@@ -141,16 +185,28 @@ func (d *Device) Configure(cfg Config) error {
 	// on its purpose and validity, see the comments above its assignment inside
 	// of method `(*Device).New`.
 	if 0 != cfg.Height {
-		if cfg.Height > d.cfg.maxHeight {
-			// Bail out with error if given height exceeds maximum height. Otherwise,
-			// entire rows may get dropped, or, worse, row index might wrap around and
-			// overwrite correct rows
-			return ErrInvalidHeight
-		}
-		d.cfg.Height = cfg.Height // use given height if it passes all restrictions
+		d.cfg.Height = cfg.Height // use given height, verified with the layout
 	} else {
 		d.cfg.Height = d.cfg.maxHeight // use maximum height if undefined
 	}
+
+	// Map the display onto the chain of panels, which is what we actually drive
+	// from here on.
+	cfg.Layout.Width = int16(d.cfg.Width)
+	cfg.Layout.Height = int16(d.cfg.Height)
+	lay, err := matrix.New(cfg.Layout)
+	if err != nil {
+		return err
+	}
+	w, h := lay.ChainSize()
+	if int(h) > d.cfg.maxHeight {
+		// Bail out with error if chain height exceeds maximum height. Otherwise,
+		// entire rows may get dropped, or, worse, row index might wrap around and
+		// overwrite correct rows
+		return ErrInvalidHeight
+	}
+	d.lay = lay
+	d.cfg.Width, d.cfg.Height = int(w), int(h)
 	// use the final height selection (H) to determine number of row pairs (H/2),
 	// which is the number of iterations required to scan all matrix rows.
 	d.cfg.numAddrRows = d.cfg.Height / 2
@@ -161,6 +217,19 @@ func (d *Device) Configure(cfg Config) error {
 	} else {
 		d.cfg.ColorDepth = DefaultColorDepth // use default depth when undefined
 	}
+	if d.cfg.ColorDepth > 8 {
+		return ErrInvalidDepth // framebuffer holds 8-bit components
+	}
+
+	// Configure gamma correction, with the same depth as the bitplanes.
+	if nil != cfg.Gamma {
+		if err := cfg.Gamma.Validate(); err != nil {
+			return err
+		}
+		d.gam = cfg.Gamma
+	} else {
+		d.gam = matrix.NewGamma(2.2, d.cfg.ColorDepth)
+	}
 
 	// decide if all row address lines are on the same GPIO port, which isn't a
 	// requirement, but it will improve performance by efficiently setting row
@@ -195,10 +264,15 @@ func (d *Device) Configure(cfg Config) error {
 		d.row[i].Configure(machine.PinConfig{Mode: machine.PinOutput})
 	}
 
-	// allocate the framebuffer
-	d.buf = make([][]color.RGBA, d.cfg.Height)
-	for i := range d.buf {
-		d.buf[i] = make([]color.RGBA, d.cfg.Width)
+	// allocate the framebuffers, which are shared without double buffering
+	d.cfg.DoubleBuffer = cfg.DoubleBuffer
+	d.cfg.OnFrame = cfg.OnFrame
+	size := int(d.cfg.ColorDepth) * d.cfg.numAddrRows * d.cfg.Width
+	d.buf[0] = make(bitplanes, size)
+	if d.cfg.DoubleBuffer {
+		d.buf[1] = make(bitplanes, size)
+	} else {
+		d.buf[1] = d.buf[0]
 	}
 
 	return d.initialize()
@@ -206,41 +280,88 @@ func (d *Device) Configure(cfg Config) error {
 
 // Size returns the current size of the display.
 func (d *Device) Size() (x, y int16) {
-	return int16(d.cfg.Width), int16(d.cfg.Height)
+	return d.lay.Size()
 }
 
-// SetPixel modifies the internal buffer.
+// SetPixel modifies the internal buffer. The color is gamma corrected and
+// stored with ColorDepth bits per component.
 func (d *Device) SetPixel(x, y int16, c color.RGBA) {
-	if y >= 0 && int(y) < len(d.buf) {
-		if x >= 0 && int(x) < len(d.buf[y]) {
-			d.buf[y][x] = c
+	x, y, ok := d.lay.Map(x, y)
+	if !ok {
+		return
+	}
+	r, g, b := d.gam.Apply(c, d.cfg.ColorDepth)
+
+	// select the row pair and the bits of the upper or lower row
+	row := int(y)
+	bitR, bitG, bitB := uint8(rgbBitR1), uint8(rgbBitG1), uint8(rgbBitB1)
+	if row >= d.cfg.numAddrRows {
+		row -= d.cfg.numAddrRows
+		bitR, bitG, bitB = rgbBitR2, rgbBitG2, rgbBitB2
+	}
+	buf := d.buf[1]
+	for n := 0; n < int(d.cfg.ColorDepth); n++ {
+		i := (n*d.cfg.numAddrRows+row)*d.cfg.Width + int(x)
+		v := buf[i] &^ (bitR | bitG | bitB)
+		if 0 != r&(1<<n) {
+			v |= bitR
 		}
+		if 0 != g&(1<<n) {
+			v |= bitG
+		}
+		if 0 != b&(1<<n) {
+			v |= bitB
+		}
+		buf[i] = v
 	}
 }
 
-// Display sends the buffer (if any) to the screen.
+// Display sends the buffer (if any) to the screen. With double buffering, it
+// swaps the buffers and waits for the swap to happen.
 func (d *Device) Display() error {
 	d.Resume()
+	if d.cfg.DoubleBuffer {
+		d.Swap()
+	}
 	return nil
 }
 
-// ClearDisplay clears the display
+// Swap exchanges the front and back buffers at the end of the frame being
+// shown, and waits for it. Afterwards, the back buffer holds the frame shown
+// before, which must be redrawn or cleared. Swap does nothing without double
+// buffering.
+func (d *Device) Swap() {
+	if !d.cfg.DoubleBuffer {
+		return
+	}
+	if !d.run {
+		// no frame to wait for
+		d.buf[0], d.buf[1] = d.buf[1], d.buf[0]
+		return
+	}
+	d.swp.Set(1)
+	for d.swp.Get() != 0 {
+	} // wait for the ISR to swap at the frame boundary
+}
+
+// ClearDisplay clears the display, or the back buffer with double buffering.
 func (d *Device) ClearDisplay() {
-	for y := range d.buf {
-		for x := range d.buf[y] {
-			d.buf[y][x] = color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0x00}
-		}
+	buf := d.buf[1]
+	for i := range buf {
+		buf[i] = 0
 	}
 }
 
 // Resume starts or restarts updating the display.
 func (d *Device) Resume() {
+	d.run = true
 	d.hub.ResumeTimer(d.val, d.pos.cyc)
 }
 
 // Pause stops updating the display. Use Resume to restart updates.
 func (d *Device) Pause() {
 	d.val = d.hub.PauseTimer()
+	d.run = false
 }
 
 // initialize initializes all GPIO pin levels and Device state machines prior to
@@ -286,18 +407,15 @@ func (d *Device) initialize() error {
 	return nil
 }
 
-// rgbBit returns the n'th bit (LSB) of each R, G, B component of the color in
-// the receiver's framebuffer at column x and row y.
+// rgbBits returns the n'th bitplane of the pair of rows driven with the
+// upper row y from the front buffer, one byte per column.
 //
 // Note that for performance efficiency, the arguments are NOT validated or
 // range-checked. So be very careful you are providing valid inputs, otherwise
 // this is a rather dangerous function susceptible to access violations!
-func (d *Device) rgbBit(x, y, n int) (r, g, b bool) {
-	cr, cg, cb, _ := d.buf[y][x].RGBA()
-	r = 0 != (cr & (1 << n))
-	g = 0 != (cg & (1 << n))
-	b = 0 != (cb & (1 << n))
-	return
+func (d *Device) rgbBits(y, n int) bitplanes {
+	i := (n*d.cfg.numAddrRows + y) * d.cfg.Width
+	return d.buf[0][i : i+d.cfg.Width]
 }
 
 // handleRow is the interrupt service routine (ISR) for the main HUB75 row-scan
@@ -320,19 +438,21 @@ func (d *Device) handleRow() {
 	// stop the row select timer, switch rows if we have incremented to a new row,
 	// and then re-enable the row select timer.
 	d.selectRow(d.pos.yUp)
-	d.increment()
+	if d.increment() {
+		d.endFrame()
+	}
 
 	// close the latch before clocking out the next row of data, and enable output
 	d.lat.Low()
 	d.oen.Low()
 
 	// pulse color data to the next pair of rows while we wait for the timer
-	for x := 0; x < d.cfg.Width; x++ {
+	for _, c := range d.rgbBits(d.pos.yUp, d.pos.bit) {
 		// for the current rows (d.pos.yUp/yLo) and current bitplane (d.pos.bit),
-		// grab the corresponding bit in each R,G,B color component of the pixel in
-		// column x.
-		r1, g1, b1 := d.rgbBit(x, d.pos.yUp, d.pos.bit) // get upper row
-		r2, g2, b2 := d.rgbBit(x, d.pos.yLo, d.pos.bit) // get lower row
+		// grab the corresponding bit in each R,G,B color component of the pixels
+		// in this column.
+		r1, g1, b1 := 0 != c&rgbBitR1, 0 != c&rgbBitG1, 0 != c&rgbBitB1 // upper row
+		r2, g2, b2 := 0 != c&rgbBitR2, 0 != c&rgbBitG2, 0 != c&rgbBitB2 // lower row
 
 		// check if we can set both RGB data and CLK at the same time.
 		if d.cfg.clkDataPort {
@@ -354,8 +474,21 @@ func (d *Device) handleRow() {
 	}
 }
 
-// increment updates the active row and bitplane indices by one.
-func (d *Device) increment() {
+// endFrame swaps the buffers if requested and signals the end of a frame. It
+// is called by the ISR before sending the first row of a frame.
+func (d *Device) endFrame() {
+	if d.swp.Get() != 0 {
+		d.buf[0], d.buf[1] = d.buf[1], d.buf[0]
+		d.swp.Set(0)
+	}
+	if d.cfg.OnFrame != nil {
+		d.cfg.OnFrame()
+	}
+}
+
+// increment updates the active row and bitplane indices by one, and returns
+// true when a new frame starts.
+func (d *Device) increment() bool {
 	d.pos.bit++    // increment bitplane index
 	d.pos.cyc *= 2 // double timer period
 	// check for bitplane index rollover
@@ -369,8 +502,10 @@ func (d *Device) increment() {
 			d.pos.yUp = 0                 // reset upper row index
 			d.pos.yLo = d.cfg.numAddrRows // reset lower row index
 			d.pos.frame++                 // update frame index
+			return true
 		}
 	}
+	return false
 }
 
 // selectRow configures the row address control lines, which selects the active
@@ -0,0 +1,157 @@
+package sprite
+
+// Image is an RGB565 bitmap, stored one row after the other.
+type Image struct {
+	Width  int16
+	Height int16
+	Pixels []uint16
+
+	// Transparent reports whether pixels of the Key color are transparent.
+	Transparent bool
+	Key         uint16
+}
+
+// Sprite is an image drawn at a position on the screen.
+//
+// The engine compares the position, visibility and z-order of each sprite
+// with the last frame to find out what to redraw. Call Invalidate after
+// changing the pixels of the image or switching to another image.
+type Sprite struct {
+	X, Y    int16
+	Z       int8
+	Visible bool
+	Image   *Image
+
+	last  Rect
+	lastZ int8
+	drawn bool
+	dirty bool
+}
+
+// NewSprite returns a visible sprite showing img at the given position.
+func NewSprite(img *Image, x, y int16) *Sprite {
+	return &Sprite{
+		X:       x,
+		Y:       y,
+		Visible: true,
+		Image:   img,
+		dirty:   true,
+	}
+}
+
+// MoveTo changes the position of the sprite.
+func (s *Sprite) MoveTo(x, y int16) {
+	s.X, s.Y = x, y
+}
+
+// SetImage changes the image shown by the sprite.
+func (s *Sprite) SetImage(img *Image) {
+	if img != s.Image {
+		s.Image = img
+		s.dirty = true
+	}
+}
+
+// Invalidate forces the sprite to be redrawn on the next frame.
+func (s *Sprite) Invalidate() {
+	s.dirty = true
+}
+
+// Bounds returns the area of the screen covered by the sprite.
+func (s *Sprite) Bounds() Rect {
+	if s.Image == nil {
+		return Rect{X: s.X, Y: s.Y}
+	}
+	return Rect{s.X, s.Y, s.Image.Width, s.Image.Height}
+}
+
+// compose draws the part of the sprite inside r into buf, which holds the
+// pixels of r.
+func (s *Sprite) compose(r Rect, buf []uint16) {
+	img := s.Image
+	if img == nil {
+		return
+	}
+	a := s.Bounds().Intersect(r)
+	if a.Empty() {
+		return
+	}
+	for y := a.Y; y < a.Y+a.H; y++ {
+		src := img.Pixels[int(y-s.Y)*int(img.Width)+int(a.X-s.X):]
+		dst := buf[int(y-r.Y)*int(r.W)+int(a.X-r.X):]
+		if !img.Transparent {
+			copy(dst[:a.W], src[:a.W])
+			continue
+		}
+		for x := int16(0); x < a.W; x++ {
+			if c := src[x]; c != img.Key {
+				dst[x] = c
+			}
+		}
+	}
+}
+
+// Tileset is a set of equally sized RGB565 tiles. Tile i takes the
+// Width*Height pixels starting at Pixels[i*Width*Height].
+type Tileset struct {
+	Width  int16
+	Height int16
+	Pixels []uint16
+}
+
+// Len returns the number of tiles in the set.
+func (t *Tileset) Len() int {
+	return len(t.Pixels) / (int(t.Width) * int(t.Height))
+}
+
+// TileMap is a grid of tiles placed at X, Y on the screen. Map holds the
+// tile index of each cell, one row after the other. Cells with an index
+// outside of the tileset are not drawn and show the background color.
+//
+// Change tiles through Engine.SetTile so the engine knows what to redraw.
+type TileMap struct {
+	X, Y    int16
+	Columns int16
+	Map     []uint16
+	Tileset *Tileset
+}
+
+// Rows returns the number of tile rows in the map.
+func (m *TileMap) Rows() int16 {
+	if m.Columns == 0 {
+		return 0
+	}
+	return int16(len(m.Map) / int(m.Columns))
+}
+
+// composeRow draws the tiles of screen row y starting at column x into row.
+func (m *TileMap) composeRow(x, y int16, row []uint16) {
+	tw, th := m.Tileset.Width, m.Tileset.Height
+	ty := y - m.Y
+	if ty < 0 || ty >= m.Rows()*th {
+		return
+	}
+	cells := m.Map[int(ty/th)*int(m.Columns):]
+	n := m.Tileset.Len()
+	for i := 0; i < len(row); {
+		tx := x + int16(i) - m.X
+		if tx < 0 {
+			i += int(-tx)
+			continue
+		}
+		if tx >= m.Columns*tw {
+			return
+		}
+		// copy up to the end of this tile
+		col, off := tx/tw, tx%tw
+		count := int(tw - off)
+		if count > len(row)-i {
+			count = len(row) - i
+		}
+		if index := int(cells[col]); index < n {
+			start := (index*int(th)+int(ty%th))*int(tw) + int(off)
+			copy(row[i:i+count], m.Tileset.Pixels[start:start+count])
+		}
+		i += count
+	}
+}
@@ -0,0 +1,102 @@
+package sprite
+
+// Rect is a rectangle on the screen.
+type Rect struct {
+	X, Y int16
+	W, H int16
+}
+
+// Empty reports whether the rectangle contains no pixels.
+func (r Rect) Empty() bool {
+	return r.W <= 0 || r.H <= 0
+}
+
+// Intersect returns the largest rectangle contained by both r and s.
+func (r Rect) Intersect(s Rect) Rect {
+	x0, y0 := max16(r.X, s.X), max16(r.Y, s.Y)
+	x1, y1 := min16(r.X+r.W, s.X+s.W), min16(r.Y+r.H, s.Y+s.H)
+	if x1 <= x0 || y1 <= y0 {
+		return Rect{}
+	}
+	return Rect{x0, y0, x1 - x0, y1 - y0}
+}
+
+// Union returns the smallest rectangle that contains both r and s.
+func (r Rect) Union(s Rect) Rect {
+	if r.Empty() {
+		return s
+	}
+	if s.Empty() {
+		return r
+	}
+	x0, y0 := min16(r.X, s.X), min16(r.Y, s.Y)
+	x1, y1 := max16(r.X+r.W, s.X+s.W), max16(r.Y+r.H, s.Y+s.H)
+	return Rect{x0, y0, x1 - x0, y1 - y0}
+}
+
+// Overlaps reports whether r and s have at least one pixel in common.
+func (r Rect) Overlaps(s Rect) bool {
+	return !r.Intersect(s).Empty()
+}
+
+func (r Rect) area() int32 {
+	return int32(r.W) * int32(r.H)
+}
+
+// maxDamage is the number of separate damaged regions kept per frame. When
+// exceeded, regions are merged into their bounding box.
+const maxDamage = 16
+
+// damageList is a set of non-overlapping damaged regions.
+type damageList struct {
+	rects []Rect
+	buf   [maxDamage]Rect
+}
+
+func (l *damageList) reset() {
+	l.rects = l.buf[:0]
+}
+
+// add adds r to the list. Regions that overlap r, or that are cheaper to push
+// together with r than on their own, are merged with it.
+func (l *damageList) add(r Rect) {
+	if l.rects == nil {
+		l.reset()
+	}
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(l.rects); i++ {
+			o := l.rects[i]
+			u := r.Union(o)
+			if !r.Overlaps(o) && u.area() > r.area()+o.area() {
+				continue
+			}
+			r = u
+			l.rects[i] = l.rects[len(l.rects)-1]
+			l.rects = l.rects[:len(l.rects)-1]
+			merged = true
+			break
+		}
+	}
+	if len(l.rects) == maxDamage {
+		for _, o := range l.rects {
+			r = r.Union(o)
+		}
+		l.rects = l.rects[:0]
+	}
+	l.rects = append(l.rects, r)
+}
+
+func min16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
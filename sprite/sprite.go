@@ -0,0 +1,280 @@
+// Package sprite implements a small sprite and tile engine for RGB565
+// displays such as the ILI9341, ST7789, ST7735 and SSD1351.
+//
+// The screen is composed of a tile map background (or a plain color) and any
+// number of sprites drawn on top of it in z-order. Instead of redrawing the
+// whole screen, the engine keeps track of the regions that changed since the
+// last frame and only pushes those to the display, one strip of rows at a
+// time, so that no full framebuffer is needed.
+//
+// The engine does not depend on the machine package, so the compositing
+// logic can be tested on the host.
+package sprite // import "tinygo.org/x/drivers/sprite"
+
+import "image/color"
+
+// Displayer is a display that can draw RGB565 bitmaps, such as the ili9341.
+type Displayer interface {
+	// Size returns the current size of the display.
+	Size() (x, y int16)
+
+	// DrawRGBBitmap copies an RGB565 bitmap to the display at the given
+	// coordinates.
+	DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error
+}
+
+// RGBADisplayer is a display that draws color.RGBA buffers, such as the
+// st7735, st7789 and ssd1351.
+type RGBADisplayer interface {
+	// Size returns the current size of the display.
+	Size() (x, y int16)
+
+	// FillRectangleWithBuffer fills a rectangle at the given coordinates
+	// with a buffer.
+	FillRectangleWithBuffer(x, y, width, height int16, buffer []color.RGBA) error
+}
+
+// FromRGBA returns a Displayer that converts the RGB565 pixels pushed by the
+// engine and draws them on d. The conversion buffer is allocated on first use
+// and has the size of the engine strip buffer.
+func FromRGBA(d RGBADisplayer) Displayer {
+	return &rgbaDisplay{d: d}
+}
+
+type rgbaDisplay struct {
+	d   RGBADisplayer
+	buf []color.RGBA
+}
+
+func (r *rgbaDisplay) Size() (x, y int16) {
+	return r.d.Size()
+}
+
+func (r *rgbaDisplay) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
+	if cap(r.buf) < len(data) {
+		r.buf = make([]color.RGBA, len(data))
+	}
+	buf := r.buf[:len(data)]
+	for i, c := range data {
+		buf[i] = RGB565ToRGBA(c)
+	}
+	return r.d.FillRectangleWithBuffer(x, y, w, h, buf)
+}
+
+// RGBATo565 converts a color.RGBA to an RGB565 pixel.
+func RGBATo565(c color.RGBA) uint16 {
+	return uint16(c.R&0xF8)<<8 | uint16(c.G&0xFC)<<3 | uint16(c.B)>>3
+}
+
+// RGB565ToRGBA converts an RGB565 pixel to a color.RGBA.
+func RGB565ToRGBA(c uint16) color.RGBA {
+	r := uint8(c>>8) & 0xF8
+	g := uint8(c>>3) & 0xFC
+	b := uint8(c<<3) & 0xF8
+	return color.RGBA{r | r>>5, g | g>>6, b | b>>5, 0xFF}
+}
+
+// Config is the configuration of the engine.
+type Config struct {
+	// StripHeight is the height in pixels of the strip buffer used to push
+	// damaged regions to the display. The buffer takes StripHeight times the
+	// display width pixels. Defaults to 8.
+	StripHeight int16
+
+	// Background is the RGB565 color drawn where there is no tile map.
+	Background uint16
+}
+
+// Engine composes a tile map and sprites, and pushes the regions that
+// changed to the display.
+type Engine struct {
+	display Displayer
+	width   int16
+	height  int16
+	bg      uint16
+	strip   []uint16
+	tiles   *TileMap
+	sprites []*Sprite
+	damage  damageList
+}
+
+// New returns a new engine drawing on the given display. The whole screen is
+// damaged, so the first call to Update draws everything.
+func New(display Displayer, cfg Config) *Engine {
+	if cfg.StripHeight <= 0 {
+		cfg.StripHeight = 8
+	}
+	w, h := display.Size()
+	if cfg.StripHeight > h {
+		cfg.StripHeight = h
+	}
+	e := &Engine{
+		display: display,
+		width:   w,
+		height:  h,
+		bg:      cfg.Background,
+		strip:   make([]uint16, int(w)*int(cfg.StripHeight)),
+	}
+	e.InvalidateAll()
+	return e
+}
+
+// Size returns the size of the screen handled by the engine.
+func (e *Engine) Size() (x, y int16) {
+	return e.width, e.height
+}
+
+// SetBackground changes the color drawn where there is no tile map.
+func (e *Engine) SetBackground(c uint16) {
+	e.bg = c
+	e.InvalidateAll()
+}
+
+// SetTileMap sets the background tile map, or removes it if m is nil.
+func (e *Engine) SetTileMap(m *TileMap) {
+	e.tiles = m
+	e.InvalidateAll()
+}
+
+// SetTile changes a single tile of the tile map and damages its area.
+func (e *Engine) SetTile(col, row int16, index uint16) {
+	m := e.tiles
+	if m == nil || col < 0 || row < 0 || col >= m.Columns || row >= m.Rows() {
+		return
+	}
+	m.Map[int(row)*int(m.Columns)+int(col)] = index
+	e.Invalidate(Rect{
+		X: m.X + col*m.Tileset.Width,
+		Y: m.Y + row*m.Tileset.Height,
+		W: m.Tileset.Width,
+		H: m.Tileset.Height,
+	})
+}
+
+// AddSprite adds a sprite to the screen.
+func (e *Engine) AddSprite(s *Sprite) {
+	for _, other := range e.sprites {
+		if other == s {
+			return
+		}
+	}
+	s.drawn = false
+	s.dirty = true
+	e.sprites = append(e.sprites, s)
+}
+
+// RemoveSprite removes a sprite from the screen, damaging the area where it
+// was last drawn.
+func (e *Engine) RemoveSprite(s *Sprite) {
+	for i, other := range e.sprites {
+		if other != s {
+			continue
+		}
+		if s.drawn {
+			e.Invalidate(s.last)
+		}
+		s.drawn = false
+		copy(e.sprites[i:], e.sprites[i+1:])
+		e.sprites[len(e.sprites)-1] = nil
+		e.sprites = e.sprites[:len(e.sprites)-1]
+		return
+	}
+}
+
+// Invalidate marks a region of the screen to be redrawn on the next Update.
+func (e *Engine) Invalidate(r Rect) {
+	r = r.Intersect(Rect{0, 0, e.width, e.height})
+	if r.Empty() {
+		return
+	}
+	e.damage.add(r)
+}
+
+// InvalidateAll marks the whole screen to be redrawn on the next Update.
+func (e *Engine) InvalidateAll() {
+	e.damage.reset()
+	e.damage.add(Rect{0, 0, e.width, e.height})
+}
+
+// Damage collects the regions changed since the last frame, including the
+// sprites that moved, and returns them. The returned slice is only valid
+// until the next call to Damage or Update.
+func (e *Engine) Damage() []Rect {
+	e.sortSprites()
+	for _, s := range e.sprites {
+		cur := s.Bounds()
+		if !s.Visible {
+			cur = Rect{}
+		}
+		changed := s.dirty || cur != s.last || s.Z != s.lastZ
+		if s.drawn && changed {
+			e.Invalidate(s.last)
+		}
+		if !s.drawn || changed {
+			e.Invalidate(cur)
+		}
+		s.last = cur
+		s.lastZ = s.Z
+		s.drawn = !cur.Empty()
+		s.dirty = false
+	}
+	return e.damage.rects
+}
+
+// Update draws all damaged regions to the display.
+func (e *Engine) Update() error {
+	damage := e.Damage()
+	stripHeight := int16(len(e.strip) / int(e.width))
+	for _, r := range damage {
+		for y := r.Y; y < r.Y+r.H; y += stripHeight {
+			h := stripHeight
+			if y+h > r.Y+r.H {
+				h = r.Y + r.H - y
+			}
+			strip := Rect{r.X, y, r.W, h}
+			buf := e.strip[:int(strip.W)*int(strip.H)]
+			e.Compose(strip, buf)
+			if err := e.display.DrawRGBBitmap(strip.X, strip.Y, buf, strip.W, strip.H); err != nil {
+				e.damage.reset()
+				return err
+			}
+		}
+	}
+	e.damage.reset()
+	return nil
+}
+
+// Compose renders the given region of the screen into buf, one row after the
+// other. The buffer must hold at least r.W*r.H pixels.
+func (e *Engine) Compose(r Rect, buf []uint16) {
+	e.composeBackground(r, buf)
+	for _, s := range e.sprites {
+		if s.Visible {
+			s.compose(r, buf)
+		}
+	}
+}
+
+func (e *Engine) composeBackground(r Rect, buf []uint16) {
+	m := e.tiles
+	for y := int16(0); y < r.H; y++ {
+		row := buf[int(y)*int(r.W) : int(y+1)*int(r.W)]
+		for x := range row {
+			row[x] = e.bg
+		}
+		if m != nil {
+			m.composeRow(r.X, r.Y+y, row)
+		}
+	}
+}
+
+// sortSprites orders the sprites by z-order, keeping the insertion order for
+// sprites with the same Z. The number of sprites is usually small and they
+// are mostly sorted already, so an insertion sort does well.
+func (e *Engine) sortSprites() {
+	for i := 1; i < len(e.sprites); i++ {
+		for j := i; j > 0 && e.sprites[j-1].Z > e.sprites[j].Z; j-- {
+			e.sprites[j-1], e.sprites[j] = e.sprites[j], e.sprites[j-1]
+		}
+	}
+}
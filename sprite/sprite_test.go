@@ -0,0 +1,177 @@
+package sprite
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// fakeDisplay is an in-memory display that records the bitmaps pushed to it.
+type fakeDisplay struct {
+	w, h   int16
+	pixels []uint16
+	draws  []Rect
+}
+
+func newFakeDisplay(w, h int16) *fakeDisplay {
+	return &fakeDisplay{w: w, h: h, pixels: make([]uint16, int(w)*int(h))}
+}
+
+func (d *fakeDisplay) Size() (x, y int16) {
+	return d.w, d.h
+}
+
+func (d *fakeDisplay) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
+	d.draws = append(d.draws, Rect{x, y, w, h})
+	for j := int16(0); j < h; j++ {
+		copy(d.pixels[int(y+j)*int(d.w)+int(x):], data[int(j)*int(w):int(j+1)*int(w)])
+	}
+	return nil
+}
+
+func (d *fakeDisplay) at(x, y int16) uint16 {
+	return d.pixels[int(y)*int(d.w)+int(x)]
+}
+
+func solidImage(w, h int16, c uint16) *Image {
+	img := &Image{Width: w, Height: h, Pixels: make([]uint16, int(w)*int(h))}
+	for i := range img.Pixels {
+		img.Pixels[i] = c
+	}
+	return img
+}
+
+func TestFirstUpdateDrawsScreen(t *testing.T) {
+	c := qt.New(t)
+	d := newFakeDisplay(32, 20)
+	e := New(d, Config{StripHeight: 4, Background: 0x1234})
+
+	c.Assert(e.Update(), qt.IsNil)
+	for _, p := range d.pixels {
+		c.Assert(p, qt.Equals, uint16(0x1234))
+	}
+	c.Assert(d.draws, qt.HasLen, 5)
+	for _, r := range d.draws {
+		c.Assert(r.H <= 4, qt.IsTrue)
+	}
+
+	// nothing changed, nothing to draw
+	d.draws = nil
+	c.Assert(e.Update(), qt.IsNil)
+	c.Assert(d.draws, qt.HasLen, 0)
+}
+
+func TestMovingSpriteRedrawsOldAndNewArea(t *testing.T) {
+	c := qt.New(t)
+	d := newFakeDisplay(64, 64)
+	e := New(d, Config{StripHeight: 64})
+	s := NewSprite(solidImage(4, 4, 0xFFFF), 10, 10)
+	e.AddSprite(s)
+	c.Assert(e.Update(), qt.IsNil)
+	c.Assert(d.at(10, 10), qt.Equals, uint16(0xFFFF))
+
+	d.draws = nil
+	s.MoveTo(12, 11)
+	c.Assert(e.Update(), qt.IsNil)
+	c.Assert(d.draws, qt.DeepEquals, []Rect{{10, 10, 6, 5}})
+	c.Assert(d.at(10, 10), qt.Equals, uint16(0))
+	c.Assert(d.at(12, 11), qt.Equals, uint16(0xFFFF))
+	c.Assert(d.at(15, 14), qt.Equals, uint16(0xFFFF))
+
+	// far apart moves are pushed as two regions
+	d.draws = nil
+	s.MoveTo(40, 40)
+	c.Assert(e.Update(), qt.IsNil)
+	c.Assert(d.draws, qt.HasLen, 2)
+
+	d.draws = nil
+	e.RemoveSprite(s)
+	c.Assert(e.Update(), qt.IsNil)
+	c.Assert(d.draws, qt.DeepEquals, []Rect{{40, 40, 4, 4}})
+	c.Assert(d.at(41, 41), qt.Equals, uint16(0))
+}
+
+func TestZOrderAndTransparency(t *testing.T) {
+	c := qt.New(t)
+	d := newFakeDisplay(16, 16)
+	e := New(d, Config{Background: 0x0001})
+
+	front := NewSprite(solidImage(4, 4, 0xF800), 2, 2)
+	front.Z = 1
+	front.Image.Transparent = true
+	front.Image.Key = 0xF800
+	front.Image.Pixels[0] = 0x07E0
+	back := NewSprite(solidImage(4, 4, 0x001F), 2, 2)
+	e.AddSprite(front)
+	e.AddSprite(back)
+	c.Assert(e.Update(), qt.IsNil)
+
+	c.Assert(d.at(2, 2), qt.Equals, uint16(0x07E0))
+	c.Assert(d.at(3, 3), qt.Equals, uint16(0x001F))
+	c.Assert(d.at(6, 6), qt.Equals, uint16(0x0001))
+
+	// changing the z-order redraws the sprite
+	d.draws = nil
+	front.Image.Transparent = false
+	back.Z = 2
+	c.Assert(e.Update(), qt.IsNil)
+	c.Assert(d.draws, qt.DeepEquals, []Rect{{2, 2, 4, 4}})
+	c.Assert(d.at(3, 3), qt.Equals, uint16(0x001F))
+}
+
+func TestTileMap(t *testing.T) {
+	c := qt.New(t)
+	d := newFakeDisplay(10, 6)
+	e := New(d, Config{Background: 0xAAAA})
+	tiles := &Tileset{Width: 2, Height: 2, Pixels: []uint16{
+		1, 2,
+		3, 4,
+
+		5, 6,
+		7, 8,
+	}}
+	m := &TileMap{X: 1, Y: 1, Columns: 3, Tileset: tiles, Map: []uint16{
+		0, 1, 9,
+		1, 0, 0,
+	}}
+	e.SetTileMap(m)
+	c.Assert(e.Update(), qt.IsNil)
+
+	c.Assert(d.at(0, 0), qt.Equals, uint16(0xAAAA))
+	c.Assert(d.at(1, 1), qt.Equals, uint16(1))
+	c.Assert(d.at(2, 2), qt.Equals, uint16(4))
+	c.Assert(d.at(3, 1), qt.Equals, uint16(5))
+	c.Assert(d.at(4, 2), qt.Equals, uint16(8))
+	c.Assert(d.at(5, 1), qt.Equals, uint16(0xAAAA)) // index out of tileset
+	c.Assert(d.at(1, 3), qt.Equals, uint16(5))
+	c.Assert(d.at(7, 1), qt.Equals, uint16(0xAAAA))
+	c.Assert(d.at(1, 5), qt.Equals, uint16(0xAAAA))
+
+	d.draws = nil
+	e.SetTile(2, 1, 1)
+	c.Assert(e.Update(), qt.IsNil)
+	c.Assert(d.draws, qt.DeepEquals, []Rect{{5, 3, 2, 2}})
+	c.Assert(d.at(6, 4), qt.Equals, uint16(8))
+}
+
+func TestDamageListMerge(t *testing.T) {
+	c := qt.New(t)
+	var l damageList
+	l.reset()
+	l.add(Rect{0, 0, 4, 4})
+	l.add(Rect{2, 2, 4, 4})
+	c.Assert(l.rects, qt.DeepEquals, []Rect{{0, 0, 6, 6}})
+
+	l.add(Rect{20, 20, 2, 2})
+	c.Assert(l.rects, qt.HasLen, 2)
+
+	// a region bridging two others merges all of them
+	l.add(Rect{4, 4, 17, 17})
+	c.Assert(l.rects, qt.DeepEquals, []Rect{{0, 0, 22, 22}})
+
+	l.reset()
+	for i := int16(0); i < maxDamage+1; i++ {
+		l.add(Rect{i * 10, 0, 1, 1})
+	}
+	c.Assert(l.rects, qt.DeepEquals, []Rect{{0, 0, maxDamage*10 + 1, 1}})
+}
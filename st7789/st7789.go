@@ -238,13 +238,18 @@ func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
 
 // setWindow prepares the screen to be modified at a given rectangle
 func (d *Device) setWindow(x, y, w, h int16) {
+	d.setAddrWindow(x, y, w, h)
+	d.Command(RAMWR)
+}
+
+// setAddrWindow sets the column and row address of the given rectangle
+func (d *Device) setAddrWindow(x, y, w, h int16) {
 	x += d.columnOffset
 	y += d.rowOffset
 	d.Tx([]uint8{CASET}, true)
 	d.Tx([]uint8{uint8(x >> 8), uint8(x), uint8((x + w - 1) >> 8), uint8(x + w - 1)}, false)
 	d.Tx([]uint8{RASET}, true)
 	d.Tx([]uint8{uint8(y >> 8), uint8(y), uint8((y + h - 1) >> 8), uint8(y + h - 1)}, false)
-	d.Command(RAMWR)
 }
 
 // FillRectangle fills a rectangle at a given coordinates with a color
@@ -313,6 +318,64 @@ func (d *Device) FillRectangleWithBuffer(x, y, width, height int16, buffer []col
 	return nil
 }
 
+// ReadPixel reads back the color of a single pixel from display memory
+func (d *Device) ReadPixel(x, y int16) (color.RGBA, error) {
+	var buf [1]uint16
+	if err := d.ReadRect(x, y, 1, 1, buf[:]); err != nil {
+		return color.RGBA{}, err
+	}
+	return RGB565ToRGBA(buf[0]), nil
+}
+
+// ReadRect reads back a rectangle of display memory into data as RGB565
+// pixels, one row after the other. The display returns 18-bit (RGB666) pixels
+// on read; they are truncated to RGB565.
+//
+// Reading requires the SDO line of the display to be
+// connected to the SPI SDI pin, and usually a lower SPI frequency.
+func (d *Device) ReadRect(x, y, width, height int16, data []uint16) error {
+	i, j := d.Size()
+	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
+		x >= i || (x+width) > i || y >= j || (y+height) > j {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	if int32(width)*int32(height) > int32(len(data)) {
+		return errors.New("buffer too small for rectangle size")
+	}
+	d.setAddrWindow(x, y, width, height)
+
+	d.dcPin.Low()
+	d.csPin.Low()
+	d.bus.Transfer(RAMRD)
+	d.dcPin.High()
+	d.bus.Transfer(0xFF) // dummy byte
+	for k, c := 0, int(width)*int(height); k < c; k++ {
+		r, _ := d.bus.Transfer(0xFF)
+		g, _ := d.bus.Transfer(0xFF)
+		b, _ := d.bus.Transfer(0xFF)
+		data[k] = uint16(r&0xF8)<<8 | uint16(g&0xFC)<<3 | uint16(b)>>3
+	}
+	d.csPin.High()
+	return nil
+}
+
+// ReadDisplayID returns the 24-bit display identification: manufacturer ID,
+// module/driver version ID and module/driver ID, from the most to the least
+// significant byte.
+func (d *Device) ReadDisplayID() uint32 {
+	data := []uint8{0x00, 0x00, 0x00}
+	d.rxDummy(RDDID, data)
+	return uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2])
+}
+
+// ReadDisplayStatus returns the 32-bit display status as described for the
+// RDDST command in the datasheet
+func (d *Device) ReadDisplayStatus() uint32 {
+	data := []uint8{0x00, 0x00, 0x00, 0x00}
+	d.rxDummy(RDDST, data)
+	return uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+}
+
 // DrawFastVLine draws a vertical line faster than using SetPixel
 func (d *Device) DrawFastVLine(x, y0, y1 int16, c color.RGBA) {
 	if y0 > y1 {
@@ -403,6 +466,22 @@ func (d *Device) Rx(command uint8, read_bytes []byte) {
 	d.csPin.High()
 }
 
+// rxDummy reads data from the display for the commands that insert a single
+// dummy clock cycle before their data, which shifts every byte by one bit
+func (d *Device) rxDummy(command uint8, read_bytes []byte) {
+	d.dcPin.Low()
+	d.csPin.Low()
+	d.bus.Transfer(command)
+	d.dcPin.High()
+	prev, _ := d.bus.Transfer(0xFF)
+	for i := range read_bytes {
+		next, _ := d.bus.Transfer(0xFF)
+		read_bytes[i] = prev<<1 | next>>7
+		prev = next
+	}
+	d.csPin.High()
+}
+
 // Size returns the current size of the display.
 func (d *Device) Size() (w, h int16) {
 	if d.rotation == NO_ROTATION || d.rotation == ROTATION_180 {
@@ -434,6 +513,14 @@ func (d *Device) IsBGR(bgr bool) {
 	d.isBGR = bgr
 }
 
+// RGB565ToRGBA converts a uint16 used in the display to a color.RGBA
+func RGB565ToRGBA(c uint16) color.RGBA {
+	r := uint8(c>>8) & 0xF8
+	g := uint8(c>>3) & 0xFC
+	b := uint8(c<<3) & 0xF8
+	return color.RGBA{r | r>>5, g | g>>6, b | b>>5, 0xFF}
+}
+
 // RGBATo565 converts a color.RGBA to uint16 used in the display
 func RGBATo565(c color.RGBA) uint16 {
 	r, g, b, _ := c.RGBA()
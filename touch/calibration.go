@@ -0,0 +1,116 @@
+package touch
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrCalibration is returned when the calibration touches are aligned, or too
+// close to each other.
+var ErrCalibration = errors.New("touch: touches are aligned, cannot calibrate")
+
+// Rotation is the clock-wise rotation of the display, with the same values as
+// the Rotation types of the display drivers.
+type Rotation uint8
+
+const (
+	Rotation0   Rotation = 0
+	Rotation90  Rotation = 1 // 90 degrees clock-wise rotation
+	Rotation180 Rotation = 2
+	Rotation270 Rotation = 3
+)
+
+// Calibration is the affine transformation mapping raw readings to display
+// coordinates, in the native orientation of the display (Rotation0):
+//
+//	X = A*x + B*y + C
+//	Y = D*x + E*y + F
+//
+// It corrects the offset, scale, rotation and skew of the touch panel over
+// the display.
+type Calibration struct {
+	A, B, C float32
+	D, E, F float32
+}
+
+// CalibrationSize is the size of a Calibration encoded by MarshalBinary.
+const CalibrationSize = 24
+
+// CalibrationTargets returns three display points suitable for a calibration
+// of a display of the given size: far from each other, far from the edges and
+// not aligned.
+func CalibrationTargets(width, height int) [3]Point {
+	return [3]Point{
+		{X: width / 10, Y: height / 10},
+		{X: width * 9 / 10, Y: height / 2},
+		{X: width / 2, Y: height * 9 / 10},
+	}
+}
+
+// NewCalibration computes the calibration from three touches: display are the
+// points shown on the display and raw the readings of the touches on them.
+func NewCalibration(display, raw [3]Point) (Calibration, error) {
+	var x, y, dx, dy [3]float64
+	for i := range raw {
+		x[i], y[i] = float64(raw[i].X), float64(raw[i].Y)
+		dx[i], dy[i] = float64(display[i].X), float64(display[i].Y)
+	}
+	det := (x[0]-x[2])*(y[1]-y[2]) - (x[1]-x[2])*(y[0]-y[2])
+	if det == 0 {
+		return Calibration{}, ErrCalibration
+	}
+	solve := func(d [3]float64) (a, b, c float32) {
+		ka := ((d[0]-d[2])*(y[1]-y[2]) - (d[1]-d[2])*(y[0]-y[2])) / det
+		kb := ((x[0]-x[2])*(d[1]-d[2]) - (d[0]-d[2])*(x[1]-x[2])) / det
+		kc := d[0] - ka*x[0] - kb*y[0]
+		return float32(ka), float32(kb), float32(kc)
+	}
+	var c Calibration
+	c.A, c.B, c.C = solve(dx)
+	c.D, c.E, c.F = solve(dy)
+	return c, nil
+}
+
+// Apply maps a raw reading to display coordinates. Z is left unchanged.
+func (c *Calibration) Apply(p Point) Point {
+	x, y := float32(p.X), float32(p.Y)
+	return Point{
+		X: round(c.A*x + c.B*y + c.C),
+		Y: round(c.D*x + c.E*y + c.F),
+		Z: p.Z,
+	}
+}
+
+func round(v float32) int {
+	if v < 0 {
+		return int(v - 0.5)
+	}
+	return int(v + 0.5)
+}
+
+// MarshalBinary encodes the calibration in CalibrationSize bytes, for instance
+// to keep it in flash or EEPROM.
+func (c Calibration) MarshalBinary() ([]byte, error) {
+	b := make([]byte, CalibrationSize)
+	for i, v := range [6]float32{c.A, c.B, c.C, c.D, c.E, c.F} {
+		binary.LittleEndian.PutUint32(b[i*4:], math.Float32bits(v))
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a calibration encoded by MarshalBinary.
+func (c *Calibration) UnmarshalBinary(b []byte) error {
+	if len(b) != CalibrationSize {
+		return errors.New("touch: invalid calibration size")
+	}
+	var v [6]float32
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+		if math.IsNaN(float64(v[i])) || math.IsInf(float64(v[i]), 0) {
+			return errors.New("touch: invalid calibration")
+		}
+	}
+	c.A, c.B, c.C, c.D, c.E, c.F = v[0], v[1], v[2], v[3], v[4], v[5]
+	return nil
+}
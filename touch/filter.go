@@ -0,0 +1,156 @@
+package touch
+
+// Filter wraps a Pointer returning raw readings, such as the resistive
+// touchscreens, to return filtered touches in display coordinates.
+//
+// Touches are detected with a pressure threshold with hysteresis, the
+// readings are the median of several samples to reject the outliers and small
+// moves are ignored to suppress the jitter. When there is no touch,
+// ReadTouchPoint returns a zero Point.
+type Filter struct {
+	pointer Pointer
+	cfg     FilterConfig
+	samples []Point
+	values  []int
+	pressed bool
+	last    Point // last raw reading reported
+}
+
+// FilterConfig is the configuration of a Filter.
+type FilterConfig struct {
+	// Calibration maps the readings to display coordinates. If nil, the raw
+	// readings are returned.
+	Calibration *Calibration
+
+	// Width and Height are the size of the display in its native orientation,
+	// used to rotate the touches.
+	Width  int
+	Height int
+
+	// Rotation is the rotation of the display, see SetRotation.
+	Rotation Rotation
+
+	// A touch starts when Z reaches PressThreshold and ends when Z goes below
+	// ReleaseThreshold. ReleaseThreshold defaults to PressThreshold, and Z
+	// must be above zero in any case.
+	PressThreshold   int
+	ReleaseThreshold int
+
+	// Samples is the number of readings of every touch, the median of which
+	// is returned. Defaults to 5.
+	Samples int
+
+	// Jitter is the distance in raw units under which moves are ignored.
+	Jitter int
+}
+
+// NewFilter returns a filter of the touches of p.
+func NewFilter(p Pointer, cfg FilterConfig) *Filter {
+	if cfg.Samples < 1 {
+		cfg.Samples = 5
+	}
+	if cfg.ReleaseThreshold == 0 || cfg.ReleaseThreshold > cfg.PressThreshold {
+		cfg.ReleaseThreshold = cfg.PressThreshold
+	}
+	return &Filter{
+		pointer: p,
+		cfg:     cfg,
+		samples: make([]Point, cfg.Samples),
+		values:  make([]int, cfg.Samples),
+	}
+}
+
+// SetCalibration changes the calibration, nil to return the raw readings.
+func (f *Filter) SetCalibration(c *Calibration) {
+	f.cfg.Calibration = c
+}
+
+// SetRotation changes the rotation of the display. The touches are rotated
+// the same way as the images shown by the display drivers: with Rotation90,
+// the top left corner of the display in its native orientation is the top
+// right one.
+func (f *Filter) SetRotation(r Rotation) {
+	f.cfg.Rotation = r % 4
+}
+
+// ReadTouchPoint reads a touch in display coordinates. Z is the pressure, 0
+// when there is no touch.
+func (f *Filter) ReadTouchPoint() Point {
+	p := f.ReadRawPoint()
+	if p.Z == 0 {
+		return Point{}
+	}
+	if c := f.cfg.Calibration; c != nil {
+		p = c.Apply(p)
+	}
+	return f.rotate(p)
+}
+
+// ReadRawPoint reads a touch like ReadTouchPoint, without the calibration and
+// rotation. It is used to read the touches of a calibration.
+func (f *Filter) ReadRawPoint() Point {
+	for i := range f.samples {
+		f.samples[i] = f.pointer.ReadTouchPoint()
+	}
+	p := median(f.samples, f.values)
+
+	threshold := f.cfg.PressThreshold
+	if f.pressed {
+		threshold = f.cfg.ReleaseThreshold
+	}
+	f.pressed = p.Z > 0 && p.Z >= threshold
+	if !f.pressed {
+		f.last = Point{}
+		return Point{}
+	}
+
+	// a new touch, or a move larger than the jitter
+	if f.last.Z == 0 || abs(p.X-f.last.X) > f.cfg.Jitter || abs(p.Y-f.last.Y) > f.cfg.Jitter {
+		f.last.X, f.last.Y = p.X, p.Y
+	}
+	f.last.Z = p.Z
+	return f.last
+}
+
+// rotate rotates p from the native orientation of the display.
+func (f *Filter) rotate(p Point) Point {
+	w, h := f.cfg.Width, f.cfg.Height
+	switch f.cfg.Rotation {
+	case Rotation90:
+		p.X, p.Y = h-1-p.Y, p.X
+	case Rotation180:
+		p.X, p.Y = w-1-p.X, h-1-p.Y
+	case Rotation270:
+		p.X, p.Y = p.Y, w-1-p.X
+	}
+	return p
+}
+
+// median returns the median of each coordinate of the samples, using values
+// as scratch space.
+func median(samples []Point, values []int) (p Point) {
+	p.X = medianOf(samples, values, func(p *Point) int { return p.X })
+	p.Y = medianOf(samples, values, func(p *Point) int { return p.Y })
+	p.Z = medianOf(samples, values, func(p *Point) int { return p.Z })
+	return p
+}
+
+func medianOf(samples []Point, values []int, coord func(*Point) int) int {
+	// insertion sort, there are only a few samples
+	for i := range samples {
+		v := coord(&samples[i])
+		j := i
+		for ; j > 0 && values[j-1] > v; j-- {
+			values[j] = values[j-1]
+		}
+		values[j] = v
+	}
+	return values[len(samples)/2]
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
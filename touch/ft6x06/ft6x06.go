@@ -0,0 +1,130 @@
+// Package ft6x06 implements a driver for the FocalTech FT6x06 capacitive touch
+// controllers: FT6206, FT6236 and FT6336, which report up to two touches.
+//
+// Datasheet: https://cdn-shop.adafruit.com/datasheets/FT6x06+Datasheet_V0.1_Preliminary_20120723.pdf
+//
+package ft6x06 // import "tinygo.org/x/drivers/touch/ft6x06"
+
+import (
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/touch"
+)
+
+// MaxTouches is the number of touches reported by the controller.
+const MaxTouches = 2
+
+// Pin is an input pin, such as a machine.Pin configured as input.
+type Pin interface {
+	Get() bool
+}
+
+// Device wraps an I2C connection to a FT6x06 device.
+type Device struct {
+	bus       drivers.I2C
+	Address   uint8
+	interrupt Pin
+	buf       [1 + 6*MaxTouches]byte
+}
+
+// Config is the configuration of a FT6x06 device.
+type Config struct {
+	// Threshold is the touch detection threshold, 128 by default. Lower
+	// values are more sensitive.
+	Threshold uint8
+
+	// Interrupt is the INT pin of the controller, low while touched. It is
+	// optional, and saves the I2C transfers while there is no touch.
+	Interrupt Pin
+}
+
+// Touch is a touch reported by the controller.
+type Touch struct {
+	touch.Point
+
+	// ID identifies the touch while it lasts, 0 or 1.
+	ID uint8
+
+	// Event is EVENT_PRESS_DOWN, EVENT_LIFT_UP or EVENT_CONTACT.
+	Event uint8
+}
+
+// New creates a new FT6x06 connection. The I2C bus must already be
+// configured.
+//
+// This function only creates the Device object, it does not touch the device.
+func New(bus drivers.I2C) Device {
+	return Device{bus: bus, Address: ADDRESS}
+}
+
+// Connected returns whether a FT6x06 controller has been found.
+func (d *Device) Connected() bool {
+	data := []byte{0}
+	d.bus.ReadRegister(d.Address, FOCALTECH_ID, data)
+	if data[0] != VENDOR_ID {
+		return false
+	}
+	d.bus.ReadRegister(d.Address, CIPHER, data)
+	switch data[0] {
+	case CHIP_FT6206, CHIP_FT6236, CHIP_FT6336U:
+		return true
+	}
+	return false
+}
+
+// Configure sets up the device.
+func (d *Device) Configure(cfg Config) error {
+	if cfg.Threshold == 0 {
+		cfg.Threshold = 128
+	}
+	d.interrupt = cfg.Interrupt
+	err := d.bus.WriteRegister(d.Address, TH_GROUP, []byte{cfg.Threshold})
+	if err != nil {
+		return err
+	}
+	return d.bus.WriteRegister(d.Address, G_MODE, []byte{INTERRUPT_POLLING})
+}
+
+// ReadTouches reads the current touches into touches, and returns the number
+// of touches read: up to MaxTouches, and no more than len(touches).
+func (d *Device) ReadTouches(touches []Touch) (int, error) {
+	if d.interrupt != nil && d.interrupt.Get() {
+		return 0, nil
+	}
+	err := d.bus.ReadRegister(d.Address, TD_STATUS, d.buf[:])
+	if err != nil {
+		return 0, err
+	}
+	n := int(d.buf[0] & 0x0f)
+	if n > MaxTouches {
+		// the controller reports invalid counts when it is not ready
+		n = 0
+	}
+	if n > len(touches) {
+		n = len(touches)
+	}
+	for i := 0; i < n; i++ {
+		p := d.buf[1+6*i:]
+		touches[i] = Touch{
+			Point: touch.Point{
+				X: int(p[0]&0x0f)<<8 | int(p[1]),
+				Y: int(p[2]&0x0f)<<8 | int(p[3]),
+				// the weight is zero for some controllers
+				Z: int(p[4]) + 1,
+			},
+			ID:    p[2] >> 4,
+			Event: p[0] >> 6,
+		}
+	}
+	return n, nil
+}
+
+// ReadTouchPoint reads the first touch. Z is the weight of the touch, plus one,
+// and 0 if there is no touch.
+func (d *Device) ReadTouchPoint() touch.Point {
+	var touches [1]Touch
+	n, _ := d.ReadTouches(touches[:])
+	if n == 0 {
+		return touch.Point{}
+	}
+	return touches[0].Point
+}
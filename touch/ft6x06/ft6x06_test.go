@@ -0,0 +1,85 @@
+package ft6x06
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/tester"
+	"tinygo.org/x/drivers/touch"
+)
+
+func newDevice(c *qt.C) (*Device, *tester.I2CDevice) {
+	bus := tester.NewI2CBus(c)
+	fake := tester.NewI2CDevice(c, ADDRESS)
+	fake.SetupRegister(FOCALTECH_ID, VENDOR_ID)
+	fake.SetupRegister(CIPHER, CHIP_FT6206)
+	bus.AddDevice(fake)
+	dev := New(bus)
+	return &dev, fake
+}
+
+func TestConnected(t *testing.T) {
+	c := qt.New(t)
+	dev, fake := newDevice(c)
+	c.Assert(dev.Connected(), qt.Equals, true)
+
+	fake.SetupRegister(CIPHER, 0x99)
+	c.Assert(dev.Connected(), qt.Equals, false)
+}
+
+func TestConfigure(t *testing.T) {
+	c := qt.New(t)
+	dev, fake := newDevice(c)
+	fake.SetupRegister(G_MODE, INTERRUPT_TRIGGER)
+	c.Assert(dev.Configure(Config{}), qt.IsNil)
+
+	buf := []byte{0}
+	fake.ReadRegister(TH_GROUP, buf)
+	c.Assert(buf[0], qt.Equals, uint8(128))
+	fake.ReadRegister(G_MODE, buf)
+	c.Assert(buf[0], qt.Equals, uint8(INTERRUPT_POLLING))
+}
+
+func TestReadTouches(t *testing.T) {
+	c := qt.New(t)
+	dev, fake := newDevice(c)
+	c.Assert(dev.Configure(Config{}), qt.IsNil)
+	c.Assert(dev.ReadTouchPoint(), qt.Equals, touch.Point{})
+
+	fake.SetupRegisters([]uint8{
+		TD_STATUS: 2,
+		P1_XH:     EVENT_CONTACT<<6 | 0x01, P1_XL: 0x2c, // 300
+		P1_YH: 0x10, P1_YL: 0x64, // ID 1, 100
+		P1_WEIGHT: 20,
+		P2_XH:     EVENT_PRESS_DOWN<<6 | 0x00, P2_XL: 0x0a,
+		P2_YH: 0x00, P2_YL: 0xf0,
+		P2_WEIGHT: 0,
+	})
+	var touches [3]Touch
+	n, err := dev.ReadTouches(touches[:])
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 2)
+	c.Assert(touches[0], qt.Equals, Touch{Point: touch.Point{X: 300, Y: 100, Z: 21}, ID: 1, Event: EVENT_CONTACT})
+	c.Assert(touches[1], qt.Equals, Touch{Point: touch.Point{X: 10, Y: 240, Z: 1}, ID: 0, Event: EVENT_PRESS_DOWN})
+	c.Assert(dev.ReadTouchPoint(), qt.Equals, touch.Point{X: 300, Y: 100, Z: 21})
+
+	// invalid count
+	fake.SetupRegister(TD_STATUS, 0x0f)
+	c.Assert(dev.ReadTouchPoint(), qt.Equals, touch.Point{})
+}
+
+type pin bool
+
+func (p *pin) Get() bool { return bool(*p) }
+
+func TestInterrupt(t *testing.T) {
+	c := qt.New(t)
+	dev, fake := newDevice(c)
+	irq := pin(true)
+	c.Assert(dev.Configure(Config{Interrupt: &irq}), qt.IsNil)
+	fake.SetupRegisters([]uint8{TD_STATUS: 1, P1_XL: 5, P1_YL: 6})
+	c.Assert(dev.ReadTouchPoint(), qt.Equals, touch.Point{})
+
+	irq = false
+	c.Assert(dev.ReadTouchPoint(), qt.Equals, touch.Point{X: 5, Y: 6, Z: 1})
+}
@@ -0,0 +1,47 @@
+package ft6x06
+
+const (
+	// Constants/addresses used for I2C.
+	ADDRESS = 0x38
+
+	// registers
+	DEV_MODE     = 0x00
+	GEST_ID      = 0x01
+	TD_STATUS    = 0x02
+	P1_XH        = 0x03
+	P1_XL        = 0x04
+	P1_YH        = 0x05
+	P1_YL        = 0x06
+	P1_WEIGHT    = 0x07
+	P1_MISC      = 0x08
+	P2_XH        = 0x09
+	P2_XL        = 0x0A
+	P2_YH        = 0x0B
+	P2_YL        = 0x0C
+	P2_WEIGHT    = 0x0D
+	P2_MISC      = 0x0E
+	TH_GROUP     = 0x80
+	PERIODACTIVE = 0x88
+	CIPHER       = 0xA3
+	G_MODE       = 0xA4
+	FIRMID       = 0xA6
+	FOCALTECH_ID = 0xA8
+
+	// values of CIPHER, the chip ID
+	CHIP_FT6206  = 0x06
+	CHIP_FT6236  = 0x36
+	CHIP_FT6336U = 0x64
+
+	// value of FOCALTECH_ID
+	VENDOR_ID = 0x11
+
+	// values of G_MODE
+	INTERRUPT_POLLING = 0x00 // INT is low while touched
+	INTERRUPT_TRIGGER = 0x01 // INT pulses on touch events
+
+	// events, in the 2 high bits of Px_XH
+	EVENT_PRESS_DOWN = 0x0
+	EVENT_LIFT_UP    = 0x1
+	EVENT_CONTACT    = 0x2
+	EVENT_NO_EVENT   = 0x3
+)
@@ -0,0 +1,268 @@
+// Package gesture recognizes touch events and gestures from the touches read
+// from a touch.Pointer: presses, moves, releases, taps, double taps, long
+// presses and swipes.
+//
+// A Recognizer works on time-stamped touches fed by Feed. Poll and Events feed
+// it with the touches of a touch.Pointer.
+package gesture // import "tinygo.org/x/drivers/touch/gesture"
+
+import (
+	"time"
+
+	"tinygo.org/x/drivers/touch"
+)
+
+// Kind is the kind of an event.
+type Kind uint8
+
+const (
+	// Down is sent when a touch starts.
+	Down Kind = iota + 1
+
+	// Move is sent when a touch moves, once it moved farther than the
+	// MoveThreshold from where it started.
+	Move
+
+	// Up is sent when a touch ends.
+	Up
+
+	// Tap is sent after Up, for a short touch which did not move.
+	Tap
+
+	// DoubleTap is sent instead of Tap for the second of two taps close in
+	// time and place.
+	DoubleTap
+
+	// LongPress is sent while a touch is held without moving.
+	LongPress
+
+	// Swipe is sent after Up, for a fast move over a long distance.
+	Swipe
+)
+
+// Direction is the direction of a swipe, in display coordinates.
+type Direction uint8
+
+const (
+	DirectionNone Direction = iota
+	DirectionLeft
+	DirectionRight
+	DirectionUp
+	DirectionDown
+)
+
+// Event is a touch event.
+type Event struct {
+	Kind Kind
+
+	// Point is where the event happened. For Up, Tap, DoubleTap and Swipe,
+	// it is the last point of the touch.
+	Point touch.Point
+
+	// Start is where the touch started.
+	Start touch.Point
+
+	// Duration is the time elapsed since the touch started.
+	Duration time.Duration
+
+	// Direction is the direction of a Swipe.
+	Direction Direction
+}
+
+// Config holds the thresholds of the recognizer. The zero values are replaced
+// by the defaults.
+type Config struct {
+	// MoveThreshold is the distance from the start of a touch under which it
+	// is not moving. Defaults to 10.
+	MoveThreshold int
+
+	// TapTimeout is the longest touch recognized as a tap. Defaults to 300ms.
+	TapTimeout time.Duration
+
+	// DoubleTapTimeout is the longest time between the end of a tap and the
+	// start of the next one to make a double tap. Defaults to 300ms.
+	DoubleTapTimeout time.Duration
+
+	// LongPressTimeout is the time a touch must be held without moving to be
+	// a long press. Defaults to 600ms.
+	LongPressTimeout time.Duration
+
+	// SwipeDistance is the shortest move recognized as a swipe. Defaults to
+	// 50.
+	SwipeDistance int
+
+	// SwipeTimeout is the longest touch recognized as a swipe. Defaults to
+	// 500ms.
+	SwipeTimeout time.Duration
+}
+
+// Recognizer turns touches into events.
+type Recognizer struct {
+	cfg     Config
+	handler func(Event)
+
+	pressed   bool
+	moved     bool
+	longPress bool
+	start     touch.Point
+	startTime time.Time
+	last      touch.Point
+
+	tapped  bool // a tap may be followed by a double tap
+	tap     touch.Point
+	tapTime time.Time
+}
+
+// NewRecognizer returns a recognizer calling handler for every event.
+func NewRecognizer(cfg Config, handler func(Event)) *Recognizer {
+	if cfg.MoveThreshold == 0 {
+		cfg.MoveThreshold = 10
+	}
+	if cfg.TapTimeout == 0 {
+		cfg.TapTimeout = 300 * time.Millisecond
+	}
+	if cfg.DoubleTapTimeout == 0 {
+		cfg.DoubleTapTimeout = 300 * time.Millisecond
+	}
+	if cfg.LongPressTimeout == 0 {
+		cfg.LongPressTimeout = 600 * time.Millisecond
+	}
+	if cfg.SwipeDistance == 0 {
+		cfg.SwipeDistance = 50
+	}
+	if cfg.SwipeTimeout == 0 {
+		cfg.SwipeTimeout = 500 * time.Millisecond
+	}
+	return &Recognizer{
+		cfg:     cfg,
+		handler: handler,
+	}
+}
+
+// Feed processes a touch read at time t. A zero Z means that there is no
+// touch. It must be called regularly for long presses to be recognized.
+func (r *Recognizer) Feed(p touch.Point, t time.Time) {
+	if p.Z == 0 {
+		if r.pressed {
+			r.release(t)
+		}
+		return
+	}
+
+	if !r.pressed {
+		r.pressed = true
+		r.moved = false
+		r.longPress = false
+		r.start = p
+		r.startTime = t
+		r.last = p
+		r.send(Down, p, t)
+		return
+	}
+
+	if !r.moved && distance(p, r.start) > r.cfg.MoveThreshold {
+		r.moved = true
+	}
+	if r.moved && (p.X != r.last.X || p.Y != r.last.Y) {
+		r.last = p
+		r.send(Move, p, t)
+	}
+	if !r.moved && !r.longPress && t.Sub(r.startTime) >= r.cfg.LongPressTimeout {
+		r.longPress = true
+		r.tapped = false
+		r.send(LongPress, r.last, t)
+	}
+}
+
+func (r *Recognizer) release(t time.Time) {
+	r.pressed = false
+	r.send(Up, r.last, t)
+	d := t.Sub(r.startTime)
+
+	switch {
+	case r.moved:
+		r.tapped = false
+		if d <= r.cfg.SwipeTimeout && distance(r.last, r.start) >= r.cfg.SwipeDistance {
+			r.handler(Event{
+				Kind:      Swipe,
+				Point:     r.last,
+				Start:     r.start,
+				Duration:  d,
+				Direction: direction(r.start, r.last),
+			})
+		}
+	case r.longPress || d > r.cfg.TapTimeout:
+		r.tapped = false
+	case r.tapped && r.startTime.Sub(r.tapTime) <= r.cfg.DoubleTapTimeout &&
+		distance(r.start, r.tap) <= 2*r.cfg.MoveThreshold:
+		r.tapped = false
+		r.send(DoubleTap, r.last, t)
+	default:
+		r.tapped = true
+		r.tap = r.start
+		r.tapTime = t
+		r.send(Tap, r.last, t)
+	}
+}
+
+func (r *Recognizer) send(kind Kind, p touch.Point, t time.Time) {
+	r.handler(Event{
+		Kind:     kind,
+		Point:    p,
+		Start:    r.start,
+		Duration: t.Sub(r.startTime),
+	})
+}
+
+// distance returns the largest distance of a and b along X or Y.
+func distance(a, b touch.Point) int {
+	dx, dy := abs(a.X-b.X), abs(a.Y-b.Y)
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+// direction returns the main direction of the move from a to b.
+func direction(a, b touch.Point) Direction {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	switch {
+	case abs(dx) >= abs(dy) && dx < 0:
+		return DirectionLeft
+	case abs(dx) >= abs(dy):
+		return DirectionRight
+	case dy < 0:
+		return DirectionUp
+	default:
+		return DirectionDown
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Poll reads p every interval, feeding the touches to r. It never returns.
+func Poll(p touch.Pointer, r *Recognizer, interval time.Duration) {
+	for {
+		r.Feed(p.ReadTouchPoint(), time.Now())
+		time.Sleep(interval)
+	}
+}
+
+// Events starts polling p every interval in a goroutine, and returns the
+// channel of the events. Events are dropped when the channel is full.
+func Events(p touch.Pointer, cfg Config, interval time.Duration) <-chan Event {
+	events := make(chan Event, 8)
+	r := NewRecognizer(cfg, func(e Event) {
+		select {
+		case events <- e:
+		default:
+		}
+	})
+	go Poll(p, r, interval)
+	return events
+}
@@ -0,0 +1,116 @@
+package gesture
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"tinygo.org/x/drivers/touch"
+)
+
+// touches feeds a recognizer with one touch every 10ms, and returns the kinds
+// of the events.
+type touches struct {
+	r      *Recognizer
+	t      time.Time
+	events []Event
+}
+
+func newTouches() *touches {
+	ts := &touches{t: time.Unix(0, 0)}
+	ts.r = NewRecognizer(Config{}, func(e Event) {
+		ts.events = append(ts.events, e)
+	})
+	return ts
+}
+
+func (ts *touches) feed(points ...touch.Point) []Kind {
+	ts.events = nil
+	for _, p := range points {
+		ts.r.Feed(p, ts.t)
+		ts.t = ts.t.Add(10 * time.Millisecond)
+	}
+	var kinds []Kind
+	for _, e := range ts.events {
+		kinds = append(kinds, e.Kind)
+	}
+	return kinds
+}
+
+func (ts *touches) hold(p touch.Point, d time.Duration) []Kind {
+	var points []touch.Point
+	for i := time.Duration(0); i < d; i += 10 * time.Millisecond {
+		points = append(points, p)
+	}
+	return ts.feed(points...)
+}
+
+var none = touch.Point{}
+
+func pt(x, y int) touch.Point {
+	return touch.Point{X: x, Y: y, Z: 1}
+}
+
+func TestTap(t *testing.T) {
+	c := qt.New(t)
+	ts := newTouches()
+	c.Assert(ts.feed(pt(100, 100), pt(103, 98), none), qt.DeepEquals, []Kind{Down, Up, Tap})
+	c.Assert(ts.events[1].Point, qt.Equals, pt(100, 100))
+
+	// a double tap, then a tap
+	c.Assert(ts.feed(pt(105, 100), none), qt.DeepEquals, []Kind{Down, Up, DoubleTap})
+	c.Assert(ts.feed(pt(105, 100), none), qt.DeepEquals, []Kind{Down, Up, Tap})
+
+	// too late for a double tap
+	ts.hold(none, 310*time.Millisecond)
+	c.Assert(ts.feed(pt(105, 100), none), qt.DeepEquals, []Kind{Down, Up, Tap})
+
+	// too far for a double tap
+	c.Assert(ts.feed(pt(200, 100), none), qt.DeepEquals, []Kind{Down, Up, Tap})
+}
+
+func TestLongPress(t *testing.T) {
+	c := qt.New(t)
+	ts := newTouches()
+	c.Assert(ts.hold(pt(10, 10), 590*time.Millisecond), qt.DeepEquals, []Kind{Down})
+	c.Assert(ts.hold(pt(12, 10), time.Second), qt.DeepEquals, []Kind{LongPress})
+	c.Assert(ts.feed(none), qt.DeepEquals, []Kind{Up})
+
+	// too long for a tap
+	ts.hold(none, 310*time.Millisecond)
+	c.Assert(ts.hold(pt(10, 10), 400*time.Millisecond), qt.DeepEquals, []Kind{Down})
+	c.Assert(ts.feed(none), qt.DeepEquals, []Kind{Up})
+}
+
+func TestSwipe(t *testing.T) {
+	c := qt.New(t)
+	ts := newTouches()
+	c.Assert(ts.feed(pt(100, 100), pt(105, 95), pt(85, 105), pt(40, 110), none),
+		qt.DeepEquals, []Kind{Down, Move, Move, Up, Swipe})
+	e := ts.events[4]
+	c.Assert(e.Direction, qt.Equals, DirectionLeft)
+	c.Assert(e.Start, qt.Equals, pt(100, 100))
+	c.Assert(e.Point, qt.Equals, pt(40, 110))
+	c.Assert(e.Duration, qt.Equals, 40*time.Millisecond)
+
+	c.Assert(ts.feed(pt(100, 100), pt(100, 180), none), qt.DeepEquals, []Kind{Down, Move, Up, Swipe})
+	c.Assert(ts.events[3].Direction, qt.Equals, DirectionDown)
+
+	// too short
+	c.Assert(ts.feed(pt(100, 100), pt(100, 130), none), qt.DeepEquals, []Kind{Down, Move, Up})
+
+	// too slow
+	ts.feed(pt(100, 100), pt(100, 30))
+	c.Assert(ts.hold(pt(100, 30), time.Second), qt.HasLen, 0)
+	c.Assert(ts.feed(none), qt.DeepEquals, []Kind{Up})
+}
+
+func TestHitTest(t *testing.T) {
+	c := qt.New(t)
+	buttons := []Rect{{0, 0, 30, 30}, {30, 0, 30, 30}}
+	c.Assert(HitTest(touch.Point{X: 29, Y: 29}, buttons), qt.Equals, 0)
+	c.Assert(HitTest(touch.Point{X: 30, Y: 0}, buttons), qt.Equals, 1)
+	c.Assert(HitTest(touch.Point{X: 60, Y: 0}, buttons), qt.Equals, -1)
+	c.Assert(HitTest(touch.Point{X: 10, Y: 30}, buttons), qt.Equals, -1)
+}
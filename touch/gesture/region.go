@@ -0,0 +1,25 @@
+package gesture
+
+import "tinygo.org/x/drivers/touch"
+
+// Rect is a rectangular region of the display, such as a button.
+type Rect struct {
+	X, Y          int
+	Width, Height int
+}
+
+// Contains reports whether p is inside the region.
+func (r Rect) Contains(p touch.Point) bool {
+	return p.X >= r.X && p.X < r.X+r.Width && p.Y >= r.Y && p.Y < r.Y+r.Height
+}
+
+// HitTest returns the index of the first region containing p, or -1 if there
+// is none.
+func HitTest(p touch.Point, regions []Rect) int {
+	for i, r := range regions {
+		if r.Contains(p) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,94 @@
+package touch
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// readings is a Pointer returning the given readings, then zero points.
+type readings []Point
+
+func (r *readings) ReadTouchPoint() (p Point) {
+	if len(*r) > 0 {
+		p, *r = (*r)[0], (*r)[1:]
+	}
+	return p
+}
+
+func TestCalibration(t *testing.T) {
+	c := qt.New(t)
+	// raw readings decreasing with the display coordinates, and swapped axes
+	rawOf := func(p Point) Point {
+		return Point{X: 50000 - p.Y*100, Y: 40000 - p.X*150}
+	}
+	targets := CalibrationTargets(240, 320)
+	var raw [3]Point
+	for i, p := range targets {
+		raw[i] = rawOf(p)
+	}
+	cal, err := NewCalibration(targets, raw)
+	c.Assert(err, qt.IsNil)
+	for _, p := range []Point{{0, 0, 7}, {239, 319, 7}, {120, 10, 7}} {
+		c.Assert(cal.Apply(Point{X: rawOf(p).X, Y: rawOf(p).Y, Z: 7}), qt.Equals, p)
+	}
+
+	b, err := cal.MarshalBinary()
+	c.Assert(err, qt.IsNil)
+	c.Assert(b, qt.HasLen, CalibrationSize)
+	var cal2 Calibration
+	c.Assert(cal2.UnmarshalBinary(b), qt.IsNil)
+	c.Assert(cal2, qt.Equals, cal)
+	c.Assert(cal2.UnmarshalBinary(b[1:]), qt.ErrorMatches, "touch: invalid calibration size")
+
+	_, err = NewCalibration(targets, [3]Point{{1, 1, 0}, {2, 2, 0}, {3, 3, 0}})
+	c.Assert(err, qt.Equals, ErrCalibration)
+}
+
+func TestFilterMedian(t *testing.T) {
+	c := qt.New(t)
+	r := readings{{100, 200, 50}, {9000, 210, 50}, {101, 0, 50}, {102, 205, 40}, {99, 204, 60}}
+	f := NewFilter(&r, FilterConfig{PressThreshold: 10})
+	c.Assert(f.ReadTouchPoint(), qt.Equals, Point{101, 204, 50})
+	c.Assert(f.ReadTouchPoint(), qt.Equals, Point{})
+}
+
+func TestFilterThreshold(t *testing.T) {
+	c := qt.New(t)
+	r := readings{{10, 10, 20}, {10, 10, 40}, {10, 10, 20}, {10, 10, 5}}
+	f := NewFilter(&r, FilterConfig{PressThreshold: 30, ReleaseThreshold: 10, Samples: 1})
+	c.Assert(f.ReadTouchPoint().Z, qt.Equals, 0)
+	c.Assert(f.ReadTouchPoint().Z, qt.Equals, 40)
+	c.Assert(f.ReadTouchPoint().Z, qt.Equals, 20)
+	c.Assert(f.ReadTouchPoint().Z, qt.Equals, 0)
+}
+
+func TestFilterJitter(t *testing.T) {
+	c := qt.New(t)
+	r := readings{{100, 100, 1}, {103, 98, 2}, {110, 98, 3}, {0, 0, 0}, {103, 98, 4}}
+	f := NewFilter(&r, FilterConfig{Samples: 1, Jitter: 5})
+	c.Assert(f.ReadTouchPoint(), qt.Equals, Point{100, 100, 1})
+	c.Assert(f.ReadTouchPoint(), qt.Equals, Point{100, 100, 2})
+	c.Assert(f.ReadTouchPoint(), qt.Equals, Point{110, 98, 3})
+	c.Assert(f.ReadTouchPoint(), qt.Equals, Point{})
+	c.Assert(f.ReadTouchPoint(), qt.Equals, Point{103, 98, 4})
+}
+
+func TestFilterRotation(t *testing.T) {
+	c := qt.New(t)
+	cal := Calibration{A: 1, E: 1}
+	f := NewFilter(nil, FilterConfig{Calibration: &cal, Width: 240, Height: 320, Samples: 1})
+	for _, test := range []struct {
+		r    Rotation
+		want Point
+	}{
+		{Rotation0, Point{10, 20, 1}},
+		{Rotation90, Point{299, 10, 1}},
+		{Rotation180, Point{229, 299, 1}},
+		{Rotation270, Point{20, 229, 1}},
+	} {
+		f.pointer = &readings{{10, 20, 1}}
+		f.SetRotation(test.r)
+		c.Assert(f.ReadTouchPoint(), qt.Equals, test.want)
+	}
+}
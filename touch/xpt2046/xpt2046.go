@@ -0,0 +1,99 @@
+// Package xpt2046 implements a driver for the XPT2046 and ADS7846 resistive
+// touch screen controllers.
+//
+// Datasheet: https://ldm-systems.ru/f/doc/catalog/HY-TFT-2,8/XPT2046.pdf
+//
+package xpt2046 // import "tinygo.org/x/drivers/touch/xpt2046"
+
+import (
+	"machine"
+
+	"tinygo.org/x/drivers/touch"
+)
+
+// Control bytes of the conversions: start bit, channel, 12 bit differential
+// mode, with the ADC kept on between conversions.
+const (
+	readX  = 0xD1
+	readY  = 0x91
+	readZ1 = 0xB1
+	readZ2 = 0xC1
+
+	// powerDown powers the ADC down with the PENIRQ output enabled.
+	powerDown = 0x90
+)
+
+// Device wraps the SPI connection to a XPT2046 device.
+type Device struct {
+	bus machine.SPI
+	cs  machine.Pin
+	irq machine.Pin
+	tx  []byte
+	rx  []byte
+}
+
+// New returns a new XPT2046 driver. Pass in a fully configured SPI bus, at
+// 2MHz or less. The irq pin is the PENIRQ output of the controller, low while
+// touched, or machine.NoPin if it is not connected.
+func New(bus machine.SPI, cs, irq machine.Pin) *Device {
+	return &Device{
+		bus: bus,
+		cs:  cs,
+		irq: irq,
+		tx:  make([]byte, 3),
+		rx:  make([]byte, 3),
+	}
+}
+
+// Configure sets up the device for communication, and enables the PENIRQ
+// output.
+func (d *Device) Configure() {
+	d.cs.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	d.cs.High()
+	if d.irq != machine.NoPin {
+		d.irq.Configure(machine.PinConfig{Mode: machine.PinInputPullup})
+	}
+	d.read(powerDown)
+}
+
+// Touched reports whether the screen is touched, using the PENIRQ output.
+// It is always true if the PENIRQ pin is not connected.
+func (d *Device) Touched() bool {
+	return d.irq == machine.NoPin || !d.irq.Get()
+}
+
+// ReadTouchPoint reads a touch. X, Y and Z are on a 16 bit scale, like the
+// readings of resistive.FourWire: Z is 0 without touch and grows with the
+// pressure.
+func (d *Device) ReadTouchPoint() touch.Point {
+	if !d.Touched() {
+		return touch.Point{}
+	}
+	z1 := int(d.read(readZ1))
+	z2 := int(d.read(readZ2))
+	z := z1 + 4095 - z2
+	if z <= 0 {
+		d.read(powerDown)
+		return touch.Point{}
+	}
+
+	// the first conversion after the Z measurement is noisy
+	d.read(readX)
+	x := d.read(readX)
+	y := d.read(readY)
+	d.read(powerDown)
+	return touch.Point{
+		X: int(x) << 4,
+		Y: int(y) << 4,
+		Z: z << 4,
+	}
+}
+
+// read runs a conversion and returns its 12 bit result.
+func (d *Device) read(control byte) uint16 {
+	d.tx[0] = control
+	d.cs.Low()
+	d.bus.Tx(d.tx, d.rx)
+	d.cs.High()
+	return (uint16(d.rx[1])<<8 | uint16(d.rx[2])) >> 3
+}
@@ -0,0 +1,428 @@
+package waveshareepd
+
+import (
+	"errors"
+	"image/color"
+	"machine"
+	"time"
+)
+
+// Config is the configuration of the display. Zero values take the defaults
+// of the panel.
+type Config struct {
+	Width        int16 // Width is the display resolution
+	Height       int16
+	LogicalWidth int16    // LogicalWidth must be a multiple of 8 and same size or bigger than Width
+	Rotation     Rotation // Rotation is clock-wise
+	Colors       uint8    // Colors is 2 for black and white only, 3 to use the second color plane
+}
+
+// Device is a generic e-paper display, driven according to its Panel.
+type Device struct {
+	bus          machine.SPI
+	cs           machine.Pin
+	dc           machine.Pin
+	rst          machine.Pin
+	busy         machine.Pin
+	panel        *Panel
+	logicalWidth int16
+	width        int16
+	height       int16
+	buffer       [][]uint8
+	bufferLength uint32
+	rotation     Rotation
+	partial      bool
+}
+
+// New returns a new e-paper driver for the given panel. Pass in a fully
+// configured SPI bus.
+func New(bus machine.SPI, csPin, dcPin, rstPin, busyPin machine.Pin, panel *Panel) Device {
+	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	dcPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	rstPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	busyPin.Configure(machine.PinConfig{Mode: machine.PinInput})
+	return Device{
+		bus:   bus,
+		cs:    csPin,
+		dc:    dcPin,
+		rst:   rstPin,
+		busy:  busyPin,
+		panel: panel,
+	}
+}
+
+// Configure sets up the device.
+func (d *Device) Configure(cfg Config) {
+	if cfg.Width != 0 {
+		d.width = cfg.Width
+	} else {
+		d.width = d.panel.Width
+	}
+	if cfg.LogicalWidth != 0 {
+		d.logicalWidth = cfg.LogicalWidth
+	} else {
+		d.logicalWidth = (d.width + 7) &^ 7
+	}
+	if cfg.Height != 0 {
+		d.height = cfg.Height
+	} else {
+		d.height = d.panel.Height
+	}
+	d.rotation = cfg.Rotation
+
+	if cfg.Colors == 0 {
+		cfg.Colors = d.panel.Colors
+	}
+	planes := 1
+	if cfg.Colors > 2 {
+		planes = 2
+	}
+	d.bufferLength = (uint32(d.logicalWidth) * uint32(d.height)) / 8
+	d.buffer = make([][]uint8, planes)
+	for i := range d.buffer {
+		d.buffer[i] = make([]uint8, d.bufferLength)
+	}
+	d.ClearBuffer()
+
+	d.cs.Low()
+	d.dc.Low()
+	d.rst.Low()
+
+	d.Reset()
+	d.SendSequence(d.panel.Init)
+
+	switch d.panel.Controller {
+	case SSD1680:
+		d.SendCommand(ssdDriverOutputControl)
+		d.SendData(uint8((d.height - 1) & 0xFF))
+		d.SendData(uint8(((d.height - 1) >> 8) & 0xFF))
+		d.SendData(d.panel.GateScan)
+		d.SendCommand(ssdDataEntryModeSetting)
+		d.SendData(0x03) // X increment; Y increment
+	case UC8151:
+		d.SendCommand(ucResolutionSetting)
+		d.SendData(uint8(d.logicalWidth))
+		d.SendData(uint8(d.height >> 8))
+		d.SendData(uint8(d.height))
+	case UC8176:
+		d.SendCommand(ucResolutionSetting)
+		d.SendData(uint8(d.logicalWidth >> 8))
+		d.SendData(uint8(d.logicalWidth))
+		d.SendData(uint8(d.height >> 8))
+		d.SendData(uint8(d.height))
+	}
+}
+
+// Reset resets the device
+func (d *Device) Reset() {
+	d.rst.Low()
+	time.Sleep(200 * time.Millisecond)
+	d.rst.High()
+	time.Sleep(200 * time.Millisecond)
+}
+
+// DeepSleep puts the display into deepsleep
+func (d *Device) DeepSleep() {
+	d.SendSequence(d.panel.Sleep)
+}
+
+// SendCommand sends a command to the display
+func (d *Device) SendCommand(command uint8) {
+	d.sendDataCommand(true, command)
+}
+
+// SendData sends a data byte to the display
+func (d *Device) SendData(data uint8) {
+	d.sendDataCommand(false, data)
+}
+
+// SendSequence sends a sequence of commands encoded as described in Panel.
+func (d *Device) SendSequence(seq []uint8) {
+	for i := 0; i+1 < len(seq); {
+		n := int(seq[i+1] & 0x7F)
+		d.SendCommand(seq[i])
+		for _, b := range seq[i+2 : i+2+n] {
+			d.SendData(b)
+		}
+		if seq[i+1]&0x80 != 0 {
+			d.WaitUntilIdle()
+		}
+		i += n + 2
+	}
+}
+
+// sendDataCommand sends image data or a command to the screen
+func (d *Device) sendDataCommand(isCommand bool, data uint8) {
+	if isCommand {
+		d.dc.Low()
+	} else {
+		d.dc.High()
+	}
+	d.cs.Low()
+	d.bus.Transfer(data)
+	d.cs.High()
+}
+
+// SetPixel modifies the internal buffer in a single pixel.
+// We use RGBA(0,0,0, 255) as white (transparent)
+// RGBA(1-255,0,0,255) as colored (red or yellow) on panels with a second
+// color plane
+// Anything else as black
+func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
+	if c.R == 0 && c.G == 0 && c.B == 0 { // TRANSPARENT / WHITE
+		d.SetEPDPixel(x, y, WHITE)
+	} else if c.G == 0 && c.B == 0 { // COLORED
+		d.SetEPDPixel(x, y, COLORED)
+	} else { // BLACK
+		d.SetEPDPixel(x, y, BLACK)
+	}
+}
+
+// SetEPDPixel modifies the internal buffer in a single pixel. COLORED pixels
+// are black on black and white panels.
+func (d *Device) SetEPDPixel(x int16, y int16, c Color) {
+	x, y = d.xy(x, y)
+	if x < 0 || x >= d.logicalWidth || y < 0 || y >= d.height {
+		return
+	}
+	byteIndex := (uint32(x) + uint32(y)*uint32(d.logicalWidth)) / 8
+	mask := uint8(0x80) >> uint8(x%8)
+	if c == COLORED && len(d.buffer) == 1 {
+		c = BLACK
+	}
+	if c == BLACK {
+		d.buffer[0][byteIndex] &^= mask
+	} else {
+		d.buffer[0][byteIndex] |= mask
+	}
+	if len(d.buffer) > 1 {
+		if c == COLORED {
+			d.buffer[1][byteIndex] &^= mask
+		} else {
+			d.buffer[1][byteIndex] |= mask
+		}
+	}
+}
+
+// Buffer returns the internal buffer of a color plane (BLACK or COLORED), or
+// nil if the panel has no such plane. Each row takes LogicalWidth/8 bytes,
+// with the leftmost pixel in the most significant bit. A bit reset means the
+// pixel has the color of the plane.
+func (d *Device) Buffer(c Color) []uint8 {
+	if c == WHITE || int(c) > len(d.buffer) {
+		return nil
+	}
+	return d.buffer[c-1]
+}
+
+// Display sends the buffer to the screen.
+func (d *Device) Display() error {
+	d.writePlanes(0, 0, d.logicalWidth, d.height)
+	d.SendSequence(d.panel.Update)
+	return nil
+}
+
+// DisplayRect sends only an area of the buffer to the screen.
+// The rectangle points need to be a multiple of 8 in the screen.
+// They might not work as expected if the screen is rotated.
+func (d *Device) DisplayRect(x int16, y int16, width int16, height int16) error {
+	x, y = d.xy(x, y)
+	if x < 0 || y < 0 || x >= d.logicalWidth || y >= d.height || width < 0 || height < 0 {
+		return errors.New("wrong rectangle")
+	}
+	if d.rotation == ROTATION_90 {
+		width, height = height, width
+		x -= width
+	} else if d.rotation == ROTATION_180 {
+		x -= width - 1
+		y -= height - 1
+	} else if d.rotation == ROTATION_270 {
+		width, height = height, width
+		y -= height
+	}
+	x &= 0xF8
+	width &= 0xF8
+	if x+width > d.logicalWidth {
+		width = d.logicalWidth - x
+	}
+	if y+height > d.height {
+		height = d.height - y
+	}
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	d.writePlanes(x, y, width, height)
+	d.SendSequence(d.panel.Update)
+	return nil
+}
+
+// ClearDisplay erases the device SRAM, without refreshing the screen
+func (d *Device) ClearDisplay() {
+	for c := BLACK; int(c) <= len(d.buffer); c++ {
+		d.beginWrite(c, 0, 0, d.logicalWidth, d.height)
+		for i := uint32(0); i < d.bufferLength; i++ {
+			d.SendData(d.planeByte(c, 0xFF))
+		}
+		d.endWrite()
+	}
+}
+
+// WriteRAM writes a rectangle of a color plane (BLACK or COLORED) to the
+// device SRAM directly, without refreshing the screen. The coordinates are
+// those of the panel RAM, x and w must be multiples of 8. The data holds w/8
+// bytes per row and uses the same format as Buffer.
+func (d *Device) WriteRAM(c Color, data []uint8, x, y, w, h int16) error {
+	if w%8 != 0 || x%8 != 0 {
+		return errors.New("rectangle width needs to be a multiple of 8")
+	}
+	if int(w/8)*int(h) > len(data) {
+		return errors.New("buffer has the wrong size")
+	}
+	if c == WHITE || int(c) > len(d.buffer) {
+		return errors.New("wrong color")
+	}
+	d.writeRect(c, data, w/8, x, y, w, h)
+	return nil
+}
+
+// writePlanes sends a rectangle of the internal buffer of all the planes
+func (d *Device) writePlanes(x, y, w, h int16) {
+	for c := BLACK; int(c) <= len(d.buffer); c++ {
+		offset := int(y)*int(d.logicalWidth/8) + int(x/8)
+		d.writeRect(c, d.buffer[c-1][offset:], d.logicalWidth/8, x, y, w, h)
+	}
+}
+
+// writeRect sends a rectangle of a plane, read from data starting at its
+// first byte with the given number of bytes per row
+func (d *Device) writeRect(c Color, data []uint8, stride int16, x, y, w, h int16) {
+	d.beginWrite(c, x, y, w, h)
+	for j := 0; j < int(h); j++ {
+		for _, b := range data[j*int(stride) : j*int(stride)+int(w/8)] {
+			d.SendData(d.planeByte(c, b))
+		}
+	}
+	d.endWrite()
+}
+
+// beginWrite sets up the RAM window of a rectangle and starts writing the
+// data of a color plane
+func (d *Device) beginWrite(c Color, x, y, w, h int16) {
+	x1, y1 := x+w-1, y+h-1
+	switch d.panel.Controller {
+	case SSD1680:
+		d.SendCommand(ssdRAMXStartEnd)
+		d.SendData(uint8(x >> 3))
+		d.SendData(uint8(x1 >> 3))
+		d.SendCommand(ssdRAMYStartEnd)
+		d.SendData(uint8(y))
+		d.SendData(uint8(y >> 8))
+		d.SendData(uint8(y1))
+		d.SendData(uint8(y1 >> 8))
+		d.SendCommand(ssdRAMXCounter)
+		d.SendData(uint8(x >> 3))
+		d.SendCommand(ssdRAMYCounter)
+		d.SendData(uint8(y))
+		d.SendData(uint8(y >> 8))
+		if c == COLORED {
+			d.SendCommand(ssdWriteRAMColor)
+		} else {
+			d.SendCommand(ssdWriteRAM)
+		}
+	case UC8151, UC8176:
+		d.partial = x != 0 || y != 0 || w != d.logicalWidth || h != d.height
+		if d.partial {
+			d.SendCommand(ucPartialIn)
+			d.SendCommand(ucPartialWindow)
+			if d.panel.Controller == UC8176 {
+				d.SendData(uint8(x >> 8))
+			}
+			d.SendData(uint8(x) & 0xF8)
+			if d.panel.Controller == UC8176 {
+				d.SendData(uint8(x1 >> 8))
+			}
+			d.SendData(uint8(x1) | 0x07)
+			d.SendData(uint8(y >> 8))
+			d.SendData(uint8(y))
+			d.SendData(uint8(y1 >> 8))
+			d.SendData(uint8(y1))
+			d.SendData(0x01)
+		}
+		// black and white panels take the new image in the second plane,
+		// the first one holds the previous image
+		if c == COLORED || d.panel.Colors < 3 {
+			d.SendCommand(ucDataStartTransmission2)
+		} else {
+			d.SendCommand(ucDataStartTransmission1)
+		}
+	}
+}
+
+// endWrite ends writing a rectangle started with beginWrite
+func (d *Device) endWrite() {
+	if d.partial {
+		d.SendCommand(ucPartialOut)
+		d.partial = false
+	}
+}
+
+// planeByte converts a byte of the internal buffer of a plane to the format
+// of the panel
+func (d *Device) planeByte(c Color, b uint8) uint8 {
+	if c == COLORED && d.panel.ColorActiveHigh {
+		return ^b
+	}
+	return b
+}
+
+// WaitUntilIdle waits until the display is ready
+func (d *Device) WaitUntilIdle() {
+	for d.IsBusy() {
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// IsBusy returns the busy status of the display
+func (d *Device) IsBusy() bool {
+	if d.panel.Controller == SSD1680 {
+		return d.busy.Get()
+	}
+	return !d.busy.Get()
+}
+
+// ClearBuffer sets the buffer to 0xFF (white)
+func (d *Device) ClearBuffer() {
+	for i := range d.buffer {
+		for j := range d.buffer[i] {
+			d.buffer[i][j] = 0xFF
+		}
+	}
+}
+
+// Size returns the current size of the display.
+func (d *Device) Size() (w, h int16) {
+	if d.rotation == ROTATION_90 || d.rotation == ROTATION_270 {
+		return d.height, d.logicalWidth
+	}
+	return d.logicalWidth, d.height
+}
+
+// SetRotation changes the rotation (clock-wise) of the device
+func (d *Device) SetRotation(rotation Rotation) {
+	d.rotation = rotation
+}
+
+// xy chages the coordinates according to the rotation
+func (d *Device) xy(x, y int16) (int16, int16) {
+	switch d.rotation {
+	case NO_ROTATION:
+		return x, y
+	case ROTATION_90:
+		return d.width - y - 1, x
+	case ROTATION_180:
+		return d.width - x - 1, d.height - y - 1
+	case ROTATION_270:
+		return y, d.height - x - 1
+	}
+	return x, y
+}
@@ -5,10 +5,10 @@
 package epd2in13 // import "tinygo.org/x/drivers/waveshare-epd/epd2in13"
 
 import (
-	"errors"
-	"image/color"
 	"machine"
-	"time"
+
+	waveshareepd "tinygo.org/x/drivers/waveshare-epd"
+	"tinygo.org/x/drivers/waveshare-epd/refresh"
 )
 
 type Config struct {
@@ -16,312 +16,178 @@ type Config struct {
 	Height       int16
 	LogicalWidth int16    // LogicalWidth must be a multiple of 8 and same size or bigger than Width
 	Rotation     Rotation // Rotation is clock-wise
+	RefreshMode  RefreshMode
+	// FullRefreshInterval is the number of fast or partial refreshes after
+	// which a full refresh is done to clear the accumulated ghosting.
+	// Zero disables the automatic full refresh.
+	FullRefreshInterval uint16
 }
 
 type Device struct {
-	bus          machine.SPI
-	cs           machine.Pin
-	dc           machine.Pin
-	rst          machine.Pin
-	busy         machine.Pin
-	logicalWidth int16
-	width        int16
-	height       int16
-	buffer       []uint8
-	bufferLength uint32
-	rotation     Rotation
+	waveshareepd.Device
+	policy refresh.Policy
+	luts   [3]*LUT
+	loaded *LUT
+}
+
+type Rotation = waveshareepd.Rotation
+
+// RefreshMode selects the waveform used to update the display. This panel
+// supports FULL_REFRESH, FAST_REFRESH and PARTIAL_REFRESH. Its controller
+// has a single RAM plane, so it cannot show gray levels.
+type RefreshMode = refresh.Mode
+
+// LUT holds the waveform look up table of the controller, sent as is to the
+// WRITE_LUT_REGISTER command: the voltages of every phase for the 4 pixel
+// transitions, then the length of the phases.
+type LUT [30]uint8
+
+var panel = waveshareepd.Panel{
+	Controller: waveshareepd.SSD1680,
+	Width:      122,
+	Height:     250,
+	Colors:     2,
+	GateScan:   0x00, // GD = 0; SM = 0; TB = 0;
+	Init: []uint8{
+		BOOSTER_SOFT_START_CONTROL, 3, 0xD7, 0xD6, 0x9D,
+		WRITE_VCOM_REGISTER, 1, 0xA8, // VCOM 7C
+		SET_DUMMY_LINE_PERIOD, 1, 0x1A, // 4 dummy lines per gate
+		SET_GATE_TIME, 1, 0x08, // 2us per line
+	},
+	Update: []uint8{
+		DISPLAY_UPDATE_CONTROL_2, 1, 0xC4,
+		MASTER_ACTIVATION, 0,
+		TERMINATE_FRAME_READ_WRITE, 0,
+	},
+	Sleep: []uint8{
+		DEEP_SLEEP_MODE, 0x80,
+	},
 }
 
-type Rotation uint8
-
 // Look up table for full updates
-var lutFullUpdate = [30]uint8{
+var lutFullUpdate = LUT{
 	0x22, 0x55, 0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x11,
 	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	0x1E, 0x1E, 0x1E, 0x1E, 0x1E, 0x1E, 0x1E, 0x1E,
 	0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
 }
 
+// Look up table for fast updates: the phases of the full update, driven for
+// fewer frames. It is quicker, but some ghosting remains.
+var lutFastUpdate = LUT{
+	0x22, 0x55, 0xAA, 0x55, 0xAA, 0x55, 0xAA, 0x11,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x17, 0x17, 0x17, 0x17, 0x17, 0x17, 0x17, 0x17,
+	0x01, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
 // Look up table for partial updates, faster but there will be some ghosting
-var lutPartialUpdate = [30]uint8{
+var lutPartialUpdate = LUT{
 	0x18, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	0x0F, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 }
 
-// New returns a new epd2in13x driver. Pass in a fully configured SPI bus.
+// New returns a new epd2in13 driver. Pass in a fully configured SPI bus.
 func New(bus machine.SPI, csPin, dcPin, rstPin, busyPin machine.Pin) Device {
-	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	dcPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	rstPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	busyPin.Configure(machine.PinConfig{Mode: machine.PinInput})
 	return Device{
-		bus:  bus,
-		cs:   csPin,
-		dc:   dcPin,
-		rst:  rstPin,
-		busy: busyPin,
+		Device: waveshareepd.New(bus, csPin, dcPin, rstPin, busyPin, &panel),
 	}
 }
 
 // Configure sets up the device.
 func (d *Device) Configure(cfg Config) {
-	if cfg.LogicalWidth != 0 {
-		d.logicalWidth = cfg.LogicalWidth
-	} else {
-		d.logicalWidth = 128
+	if cfg.LogicalWidth == 0 {
+		cfg.LogicalWidth = 128
 	}
-	if cfg.Width != 0 {
-		d.width = cfg.Width
-	} else {
-		d.width = 122
-	}
-	if cfg.Height != 0 {
-		d.height = cfg.Height
-	} else {
-		d.height = 250
-	}
-	d.rotation = cfg.Rotation
-	d.bufferLength = (uint32(d.logicalWidth) * uint32(d.height)) / 8
-	d.buffer = make([]uint8, d.bufferLength)
-	for i := uint32(0); i < d.bufferLength; i++ {
-		d.buffer[i] = 0xFF
+	d.Device.Configure(waveshareepd.Config{
+		Width:        cfg.Width,
+		Height:       cfg.Height,
+		LogicalWidth: cfg.LogicalWidth,
+		Rotation:     cfg.Rotation,
+	})
+	d.luts = [3]*LUT{&lutFullUpdate, &lutFastUpdate, &lutPartialUpdate}
+	d.policy = refresh.Policy{
+		Modes:    []RefreshMode{FAST_REFRESH, PARTIAL_REFRESH},
+		Interval: cfg.FullRefreshInterval,
 	}
-
-	d.cs.Low()
-	d.dc.Low()
-	d.rst.Low()
-
-	d.Reset()
-
-	d.SendCommand(DRIVER_OUTPUT_CONTROL)
-	d.SendData(uint8((d.height - 1) & 0xFF))
-	d.SendData(uint8(((d.height - 1) >> 8) & 0xFF))
-	d.SendData(0x00) // GD = 0; SM = 0; TB = 0;
-	d.SendCommand(BOOSTER_SOFT_START_CONTROL)
-	d.SendData(0xD7)
-	d.SendData(0xD6)
-	d.SendData(0x9D)
-	d.SendCommand(WRITE_VCOM_REGISTER)
-	d.SendData(0xA8) // VCOM 7C
-	d.SendCommand(SET_DUMMY_LINE_PERIOD)
-	d.SendData(0x1A) // 4 dummy lines per gate
-	d.SendCommand(SET_GATE_TIME)
-	d.SendData(0x08) // 2us per line
-	d.SendCommand(DATA_ENTRY_MODE_SETTING)
-	d.SendData(0x03) // X increment; Y increment
-
-	d.SetLUT(true)
+	d.SetRefreshMode(cfg.RefreshMode)
+	d.sendLUT(d.luts[d.policy.Mode()])
 }
 
-// Reset resets the device
-func (d *Device) Reset() {
-	d.rst.Low()
-	time.Sleep(200 * time.Millisecond)
-	d.rst.High()
-	time.Sleep(200 * time.Millisecond)
+// SetLUT sets the look up tables for full or partial updates
+func (d *Device) SetLUT(fullUpdate bool) {
+	if fullUpdate {
+		d.sendLUT(d.luts[FULL_REFRESH])
+	} else {
+		d.sendLUT(d.luts[PARTIAL_REFRESH])
+	}
 }
 
-// DeepSleep puts the display into deepsleep
-func (d *Device) DeepSleep() {
-	d.SendCommand(DEEP_SLEEP_MODE)
-	d.WaitUntilIdle()
+// sendLUT sends a look up table to the display
+func (d *Device) sendLUT(lut *LUT) {
+	d.loaded = lut
+	d.SendCommand(WRITE_LUT_REGISTER)
+	for _, b := range lut {
+		d.SendData(b)
+	}
 }
 
-// SendCommand sends a command to the display
-func (d *Device) SendCommand(command uint8) {
-	d.sendDataCommand(true, command)
+// SetRefreshMode changes the waveform used by Display and DisplayRect.
+// FAST_REFRESH and PARTIAL_REFRESH are quicker and do not flash the screen,
+// at the cost of some ghosting, see Config.FullRefreshInterval. Unsupported
+// modes, such as GRAY4_REFRESH, are ignored.
+func (d *Device) SetRefreshMode(mode RefreshMode) {
+	d.policy.SetMode(mode)
 }
 
-// SendData sends a data byte to the display
-func (d *Device) SendData(data uint8) {
-	d.sendDataCommand(false, data)
+// SetFullRefreshInterval sets the number of fast or partial refreshes after
+// which a full refresh is done to clear the ghosting. Zero disables it.
+func (d *Device) SetFullRefreshInterval(n uint16) {
+	d.policy.Interval = n
 }
 
-// sendDataCommand sends image data or a command to the screen
-func (d *Device) sendDataCommand(isCommand bool, data uint8) {
-	if isCommand {
-		d.dc.Low()
-	} else {
-		d.dc.High()
+// SetCustomLUT replaces the look up table used for a refresh mode. Passing
+// nil restores the default table.
+func (d *Device) SetCustomLUT(mode RefreshMode, lut *LUT) {
+	if int(mode) >= len(d.luts) {
+		return
 	}
-	d.cs.Low()
-	d.bus.Transfer(data)
-	d.cs.High()
-}
-
-// SetLUT sets the look up tables for full or partial updates
-func (d *Device) SetLUT(fullUpdate bool) {
-	d.SendCommand(WRITE_LUT_REGISTER)
-	if fullUpdate {
-		for i := 0; i < 30; i++ {
-			d.SendData(lutFullUpdate[i])
-		}
-	} else {
-		for i := 0; i < 30; i++ {
-			d.SendData(lutPartialUpdate[i])
-		}
+	if lut == nil {
+		lut = [...]*LUT{&lutFullUpdate, &lutFastUpdate, &lutPartialUpdate}[mode]
 	}
+	d.luts[mode] = lut
+	// send it again before the next update, even if it is the same table
+	d.loaded = nil
 }
 
-// SetPixel modifies the internal buffer in a single pixel.
-// The display have 2 colors: black and white
-// We use RGBA(0,0,0, 255) as white (transparent)
-// Anything else as black
-func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
-	x, y = d.xy(x, y)
-	if x < 0 || x >= d.logicalWidth || y < 0 || y >= d.height {
-		return
-	}
-	byteIndex := (x + y*d.logicalWidth) / 8
-	if c.R == 0 && c.G == 0 && c.B == 0 { // TRANSPARENT / WHITE
-		d.buffer[byteIndex] |= 0x80 >> uint8(x%8)
-	} else { // WHITE / EMPTY
-		d.buffer[byteIndex] &^= 0x80 >> uint8(x%8)
+// selectLUT loads the look up table for the next update, a full one if the
+// full refresh interval has been reached.
+func (d *Device) selectLUT() {
+	lut := d.luts[d.policy.Next()]
+	if lut != d.loaded {
+		d.WaitUntilIdle()
+		d.sendLUT(lut)
 	}
 }
 
 // Display sends the buffer to the screen.
 func (d *Device) Display() error {
-	d.setMemoryArea(0, 0, d.logicalWidth-1, d.height-1)
-	for j := int16(0); j < d.height; j++ {
-		d.setMemoryPointer(0, j)
-		d.SendCommand(WRITE_RAM)
-		for i := int16(0); i < d.logicalWidth/8; i++ {
-			d.SendData(d.buffer[i+j*(d.logicalWidth/8)])
-		}
-	}
-
-	d.SendCommand(DISPLAY_UPDATE_CONTROL_2)
-	d.SendData(0xC4)
-	d.SendCommand(MASTER_ACTIVATION)
-	d.SendCommand(TERMINATE_FRAME_READ_WRITE)
-	return nil
+	d.selectLUT()
+	return d.Device.Display()
 }
 
 // DisplayRect sends only an area of the buffer to the screen.
 // The rectangle points need to be a multiple of 8 in the screen.
 // They might not work as expected if the screen is rotated.
 func (d *Device) DisplayRect(x int16, y int16, width int16, height int16) error {
-	x, y = d.xy(x, y)
-	if x < 0 || y < 0 || x >= d.logicalWidth || y >= d.height || width < 0 || height < 0 {
-		return errors.New("wrong rectangle")
-	}
-	if d.rotation == ROTATION_90 {
-		width, height = height, width
-		x -= width
-	} else if d.rotation == ROTATION_180 {
-		x -= width - 1
-		y -= height - 1
-	} else if d.rotation == ROTATION_270 {
-		width, height = height, width
-		y -= height
-	}
-	x &= 0xF8
-	width &= 0xF8
-	width = x + width // reuse variables
-	if width >= d.logicalWidth {
-		width = d.logicalWidth
-	}
-	height = y + height
-	if height > d.height {
-		height = d.height
-	}
-	d.setMemoryArea(x, y, width, height)
-	x = x / 8
-	width = width / 8
-	for ; y < height; y++ {
-		d.setMemoryPointer(8*x, y)
-		d.SendCommand(WRITE_RAM)
-		for i := int16(x); i < width; i++ {
-			d.SendData(d.buffer[i+y*d.logicalWidth/8])
-		}
-	}
-
-	d.SendCommand(DISPLAY_UPDATE_CONTROL_2)
-	d.SendData(0xC4)
-	d.SendCommand(MASTER_ACTIVATION)
-	d.SendCommand(TERMINATE_FRAME_READ_WRITE)
-	return nil
+	d.selectLUT()
+	return d.Device.DisplayRect(x, y, width, height)
 }
 
 // ClearDisplay erases the device SRAM
 func (d *Device) ClearDisplay() {
-	d.setMemoryArea(0, 0, d.logicalWidth-1, d.height-1)
-	d.setMemoryPointer(0, 0)
-	d.SendCommand(WRITE_RAM)
-	for i := uint32(0); i < d.bufferLength; i++ {
-		d.SendData(0xFF)
-	}
+	d.Device.ClearDisplay()
 	d.Display()
 }
-
-// setMemoryArea sets the area of the display that will be updated
-func (d *Device) setMemoryArea(x0 int16, y0 int16, x1 int16, y1 int16) {
-	d.SendCommand(SET_RAM_X_ADDRESS_START_END_POSITION)
-	d.SendData(uint8((x0 >> 3) & 0xFF))
-	d.SendData(uint8((x1 >> 3) & 0xFF))
-	d.SendCommand(SET_RAM_Y_ADDRESS_START_END_POSITION)
-	d.SendData(uint8(y0 & 0xFF))
-	d.SendData(uint8((y0 >> 8) & 0xFF))
-	d.SendData(uint8(y1 & 0xFF))
-	d.SendData(uint8((y1 >> 8) & 0xFF))
-}
-
-// setMemoryPointer moves the internal pointer to the speficied coordinates
-func (d *Device) setMemoryPointer(x int16, y int16) {
-	d.SendCommand(SET_RAM_X_ADDRESS_COUNTER)
-	d.SendData(uint8((x >> 3) & 0xFF))
-	d.SendCommand(SET_RAM_Y_ADDRESS_COUNTER)
-	d.SendData(uint8(y & 0xFF))
-	d.SendData(uint8((y >> 8) & 0xFF))
-	d.WaitUntilIdle()
-}
-
-// WaitUntilIdle waits until the display is ready
-func (d *Device) WaitUntilIdle() {
-	for d.busy.Get() {
-		time.Sleep(100 * time.Millisecond)
-	}
-}
-
-// IsBusy returns the busy status of the display
-func (d *Device) IsBusy() bool {
-	return d.busy.Get()
-}
-
-// ClearBuffer sets the buffer to 0xFF (white)
-func (d *Device) ClearBuffer() {
-	for i := uint32(0); i < d.bufferLength; i++ {
-		d.buffer[i] = 0xFF
-	}
-}
-
-// Size returns the current size of the display.
-func (d *Device) Size() (w, h int16) {
-	if d.rotation == ROTATION_90 || d.rotation == ROTATION_270 {
-		return d.height, d.logicalWidth
-	}
-	return d.logicalWidth, d.height
-}
-
-// SetRotation changes the rotation (clock-wise) of the device
-func (d *Device) SetRotation(rotation Rotation) {
-	d.rotation = rotation
-}
-
-// xy chages the coordinates according to the rotation
-func (d *Device) xy(x, y int16) (int16, int16) {
-	switch d.rotation {
-	case NO_ROTATION:
-		return x, y
-	case ROTATION_90:
-		return d.width - y - 1, x
-	case ROTATION_180:
-		return d.width - x - 1, d.height - y - 1
-	case ROTATION_270:
-		return y, d.height - x - 1
-	}
-	return x, y
-}
@@ -1,5 +1,7 @@
 package epd2in13
 
+import "tinygo.org/x/drivers/waveshare-epd/refresh"
+
 // Registers
 const (
 	DRIVER_OUTPUT_CONTROL                = 0x01
@@ -28,4 +30,8 @@ const (
 	ROTATION_90  Rotation = 1 // 90 degrees clock-wise rotation
 	ROTATION_180 Rotation = 2
 	ROTATION_270 Rotation = 3
+
+	FULL_REFRESH    RefreshMode = refresh.Full    // slow with flashing, no ghosting
+	FAST_REFRESH    RefreshMode = refresh.Fast    // shorter phases, little ghosting
+	PARTIAL_REFRESH RefreshMode = refresh.Partial // faster without flashing, some ghosting
 )
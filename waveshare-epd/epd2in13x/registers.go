@@ -1,10 +1,12 @@
 package epd2in13x
 
+import waveshareepd "tinygo.org/x/drivers/waveshare-epd"
+
 // Registers
 const (
-	WHITE   Color = 0
-	BLACK   Color = 1
-	COLORED Color = 2 // In some board it's red in others yellow
+	WHITE   = waveshareepd.WHITE
+	BLACK   = waveshareepd.BLACK
+	COLORED = waveshareepd.COLORED // In some board it's red in others yellow
 
 	PANEL_SETTING                  = 0x00
 	POWER_SETTING                  = 0x01
@@ -9,9 +9,12 @@
 package epd4in2
 
 import (
-	"image/color"
+	"errors"
 	"machine"
 	"time"
+
+	waveshareepd "tinygo.org/x/drivers/waveshare-epd"
+	"tinygo.org/x/drivers/waveshare-epd/refresh"
 )
 
 type Config struct {
@@ -19,93 +22,74 @@ type Config struct {
 	Height       int16
 	LogicalWidth int16    // LogicalWidth must be a multiple of 8 and same size or bigger than Width
 	Rotation     Rotation // Rotation is clock-wise
+	RefreshMode  RefreshMode
+	// FullRefreshInterval is the number of fast or partial refreshes after
+	// which a full refresh is done to clear the accumulated ghosting.
+	// Zero disables the automatic full refresh.
+	FullRefreshInterval uint16
 }
 
 type Device struct {
-	bus          machine.SPI
-	cs           machine.Pin
-	dc           machine.Pin
-	rst          machine.Pin
-	busy         machine.Pin
-	logicalWidth int16
-	width        int16
-	height       int16
-	buffer       []uint8
-	bufferLength uint32
-	rotation     Rotation
+	waveshareepd.Device
+	policy   refresh.Policy
+	luts     [4]*LUT
+	oldValid bool
 }
 
-type Rotation uint8
+type Rotation = waveshareepd.Rotation
+
+// RefreshMode selects the waveform used to update the display. This panel
+// supports all of them: FULL_REFRESH, FAST_REFRESH, PARTIAL_REFRESH and
+// GRAY4_REFRESH with DisplayGray.
+type RefreshMode = refresh.Mode
+
+var panel = waveshareepd.Panel{
+	Controller: waveshareepd.UC8176,
+	Width:      EPD_WIDTH,
+	Height:     EPD_HEIGHT,
+	Colors:     2,
+	Init: []uint8{
+		POWER_SETTING, 5,
+		0x03, // VDS_EN, VDG_EN
+		0x00, // VCOM_HV, VGHL_LV[1], VGHL_LV[0]
+		0x2b, // VDH
+		0x2b, // VDL
+		0xff, // VDHR
+
+		BOOSTER_SOFT_START, 3, 0x17, 0x17, 0x17, //07 0f 17 1f 27 2F 37 2f
+		POWER_ON, 0x80,
+		PANEL_SETTING, 2, 0xbf, 0x0b, // KW-BF   KWR-AF  BWROTP 0f
+		PLL_CONTROL, 1, 0x3c, // 3A 100HZ   29 150Hz 39 200HZ  31 171HZ
+	},
+	// BUSY only goes active a while after DISPLAY_REFRESH, refresh waits
+	// before polling it
+	Update: []uint8{
+		DISPLAY_REFRESH, 0,
+	},
+}
 
 // New returns a new epd4in2 driver. Pass in a fully configured SPI bus.
 func New(bus machine.SPI, csPin, dcPin, rstPin, busyPin machine.Pin) Device {
-	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	dcPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	rstPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	busyPin.Configure(machine.PinConfig{Mode: machine.PinInput})
 	return Device{
-		bus:  bus,
-		cs:   csPin,
-		dc:   dcPin,
-		rst:  rstPin,
-		busy: busyPin,
+		Device: waveshareepd.New(bus, csPin, dcPin, rstPin, busyPin, &panel),
 	}
 }
 
 // Configure sets up the device.
 func (d *Device) Configure(cfg Config) {
-	if cfg.LogicalWidth != 0 {
-		d.logicalWidth = cfg.LogicalWidth
-	} else {
-		d.logicalWidth = EPD_WIDTH
-	}
-	if cfg.Width != 0 {
-		d.width = cfg.Width
-	} else {
-		d.width = EPD_WIDTH
-	}
-	if cfg.Height != 0 {
-		d.height = cfg.Height
-	} else {
-		d.height = EPD_HEIGHT
-	}
-	d.rotation = cfg.Rotation
-	d.bufferLength = (uint32(d.logicalWidth) * uint32(d.height)) / 8
-	d.buffer = make([]uint8, d.bufferLength)
-	for i := uint32(0); i < d.bufferLength; i++ {
-		d.buffer[i] = 0xFF
+	d.Device.Configure(waveshareepd.Config{
+		Width:        cfg.Width,
+		Height:       cfg.Height,
+		LogicalWidth: cfg.LogicalWidth,
+		Rotation:     cfg.Rotation,
+	})
+	d.oldValid = false
+	d.luts = [4]*LUT{&lutFull, &lutFast, &lutPartial, &lutGray4}
+	d.policy = refresh.Policy{
+		Modes:    []RefreshMode{FAST_REFRESH, PARTIAL_REFRESH},
+		Interval: cfg.FullRefreshInterval,
 	}
-
-	d.cs.Low()
-	d.dc.Low()
-	d.rst.Low()
-
-	d.Reset()
-	d.SendCommand(POWER_SETTING)
-	d.SendData(0x03) // VDS_EN, VDG_EN
-	d.SendData(0x00) // VCOM_HV, VGHL_LV[1], VGHL_LV[0]
-	d.SendData(0x2b) // VDH
-	d.SendData(0x2b) // VDL
-	d.SendData(0xff) // VDHR
-	d.SendCommand(BOOSTER_SOFT_START)
-	d.SendData(0x17)
-	d.SendData(0x17)
-	d.SendData(0x17) //07 0f 17 1f 27 2F 37 2f
-	d.SendCommand(POWER_ON)
-	d.WaitUntilIdle()
-	d.SendCommand(PANEL_SETTING)
-	d.SendData(0xbf) // KW-BF   KWR-AF  BWROTP 0f
-	d.SendData(0x0b)
-	d.SendCommand(PLL_CONTROL)
-	d.SendData(0x3c) // 3A 100HZ   29 150Hz 39 200HZ  31 171HZ
-}
-
-// Reset resets the device
-func (d *Device) Reset() {
-	d.rst.Low()
-	time.Sleep(200 * time.Millisecond)
-	d.rst.High()
-	time.Sleep(200 * time.Millisecond)
+	d.SetRefreshMode(cfg.RefreshMode)
 }
 
 // DeepSleep puts the display into deepsleep
@@ -130,226 +114,173 @@ func (d *Device) DeepSleep() {
 	d.SendData(0xA5)
 }
 
-// SendCommand sends a command to the display
-func (d *Device) SendCommand(command uint8) {
-	d.sendDataCommand(true, command)
+// SetLUT sets the look up tables for the current refresh mode
+func (d *Device) SetLUT() {
+	d.sendLUT(d.luts[d.policy.Mode()])
 }
 
-// SendData sends a data byte to the display
-func (d *Device) SendData(data uint8) {
-	d.sendDataCommand(false, data)
+// SetRefreshMode changes the waveform used by Display. FAST_REFRESH and
+// PARTIAL_REFRESH are quicker and do not flash the screen, at the cost of
+// some ghosting, see Config.FullRefreshInterval. GRAY4_REFRESH is only used by
+// DisplayGray and is ignored here, as are unknown modes.
+func (d *Device) SetRefreshMode(mode RefreshMode) {
+	d.policy.SetMode(mode)
 }
 
-// sendDataCommand sends image data or a command to the screen
-func (d *Device) sendDataCommand(isCommand bool, data uint8) {
-	if isCommand {
-		d.dc.Low()
-	} else {
-		d.dc.High()
+// SetFullRefreshInterval sets the number of fast or partial refreshes after
+// which Display does a full refresh to clear the ghosting. Zero disables it.
+func (d *Device) SetFullRefreshInterval(n uint16) {
+	d.policy.Interval = n
+}
+
+// SetCustomLUT replaces the look up tables used for a refresh mode. Passing
+// nil restores the default tables.
+func (d *Device) SetCustomLUT(mode RefreshMode, lut *LUT) {
+	if int(mode) >= len(d.luts) {
+		return
 	}
-	d.cs.Low()
-	d.bus.Transfer(data)
-	d.cs.High()
+	if lut == nil {
+		lut = [...]*LUT{&lutFull, &lutFast, &lutPartial, &lutGray4}[mode]
+	}
+	d.luts[mode] = lut
 }
 
-// SetLUT sets the look up tables for full or partial updates
-func (d *Device) SetLUT() {
-	lut_vcom0 := []uint8{
-		0x00, 0x17, 0x00, 0x00, 0x00, 0x02,
-		0x00, 0x17, 0x17, 0x00, 0x00, 0x02,
-		0x00, 0x0A, 0x01, 0x00, 0x00, 0x01,
-		0x00, 0x0E, 0x0E, 0x00, 0x00, 0x02,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, // 44 bytes, unlike the others
+// sendLUT sends the look up tables to the display
+func (d *Device) sendLUT(lut *LUT) {
+	d.SendCommand(LUT_FOR_VCOM)
+	for _, b := range lut.VCOM {
+		d.SendData(b)
 	}
-	lut_ww := []uint8{
-		0x40, 0x17, 0x00, 0x00, 0x00, 0x02,
-		0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
-		0x40, 0x0A, 0x01, 0x00, 0x00, 0x01,
-		0xA0, 0x0E, 0x0E, 0x00, 0x00, 0x02,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	d.SendCommand(LUT_WHITE_TO_WHITE)
+	for _, b := range lut.WW {
+		d.SendData(b)
 	}
-	lut_bw := []uint8{
-		0x40, 0x17, 0x00, 0x00, 0x00, 0x02,
-		0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
-		0x40, 0x0A, 0x01, 0x00, 0x00, 0x01,
-		0xA0, 0x0E, 0x0E, 0x00, 0x00, 0x02,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	d.SendCommand(LUT_BLACK_TO_WHITE)
+	for _, b := range lut.BW {
+		d.SendData(b)
 	}
-	lut_bb := []uint8{
-		0x80, 0x17, 0x00, 0x00, 0x00, 0x02,
-		0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
-		0x80, 0x0A, 0x01, 0x00, 0x00, 0x01,
-		0x50, 0x0E, 0x0E, 0x00, 0x00, 0x02,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	d.SendCommand(LUT_WHITE_TO_BLACK)
+	for _, b := range lut.WB {
+		d.SendData(b)
 	}
-	lut_wb := []uint8{
-		0x80, 0x17, 0x00, 0x00, 0x00, 0x02,
-		0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
-		0x80, 0x0A, 0x01, 0x00, 0x00, 0x01,
-		0x50, 0x0E, 0x0E, 0x00, 0x00, 0x02,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	d.SendCommand(LUT_BLACK_TO_BLACK)
+	for _, b := range lut.BB {
+		d.SendData(b)
 	}
+}
 
-	d.SendCommand(LUT_FOR_VCOM) //vcom
-	for count := 0; count < 44; count++ {
-		d.SendData(lut_vcom0[count])
+// Display sends the buffer to the screen.
+//
+// With FAST_REFRESH and PARTIAL_REFRESH, every FullRefreshInterval updates
+// a full refresh is done instead to clear the ghosting.
+func (d *Device) Display() error {
+	buffer := d.Buffer(waveshareepd.BLACK)
+	d.setResolution()
+
+	// The previous frame is the old data of the differential waveforms.
+	// Without one, start from white.
+	if !d.oldValid {
+		d.SendCommand(DATA_START_TRANSMISSION_1)
+		for range buffer {
+			d.SendData(0xFF) // bit set: white, bit reset: black
+		}
+		time.Sleep(2 * time.Millisecond)
 	}
-
-	d.SendCommand(LUT_WHITE_TO_WHITE) //ww --
-	for count := 0; count < 42; count++ {
-		d.SendData(lut_ww[count])
+	d.SendCommand(DATA_START_TRANSMISSION_2)
+	for _, b := range buffer {
+		d.SendData(b)
 	}
+	time.Sleep(2 * time.Millisecond)
 
-	d.SendCommand(LUT_BLACK_TO_WHITE) //bw r
-	for count := 0; count < 42; count++ {
-		d.SendData(lut_bw[count])
+	if !d.oldValid {
+		d.policy.Reset()
 	}
+	d.refresh(d.luts[d.policy.Next()])
 
-	d.SendCommand(LUT_WHITE_TO_BLACK) //wb w
-	for count := 0; count < 42; count++ {
-		d.SendData(lut_bb[count])
+	// keep the current frame as old data for the next update
+	d.SendCommand(DATA_START_TRANSMISSION_1)
+	for _, b := range buffer {
+		d.SendData(b)
 	}
+	d.oldValid = true
 
-	d.SendCommand(LUT_BLACK_TO_BLACK) //bb b
-	for count := 0; count < 42; count++ {
-		d.SendData(lut_wb[count])
-	}
+	return nil
 }
 
-// SetPixel modifies the internal buffer in a single pixel.
-// The display have 2 colors: black and white
-// We use RGBA(0,0,0, 255) as white (transparent)
-// Anything else as black
-func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
-	x, y = d.xy(x, y)
-	if x < 0 || x >= d.logicalWidth || y < 0 || y >= d.height {
-		return
-	}
-	byteIndex := (uint32(x) + uint32(y)*uint32(d.logicalWidth)) / 8
-	if c.R == 0 && c.G == 0 && c.B == 0 { // TRANSPARENT / WHITE
-		d.buffer[byteIndex] |= 0x80 >> uint8(x%8)
-	} else { // WHITE / EMPTY
-		d.buffer[byteIndex] &^= 0x80 >> uint8(x%8)
-	}
+// DisplayRect is not supported by this display, as the differential
+// waveforms need the whole previous frame. Use Display instead.
+func (d *Device) DisplayRect(x int16, y int16, width int16, height int16) error {
+	return errors.New("DisplayRect is not supported, use Display")
 }
 
-// Display sends the buffer to the screen.
-func (d *Device) Display() error {
-	d.SendCommand(RESOLUTION_SETTING)
-	d.SendData(uint8(d.height >> 8))
-	d.SendData(uint8(d.logicalWidth & 0xff))
-	d.SendData(uint8(d.height >> 8))
-	d.SendData(uint8(d.height & 0xff))
-
-	d.SendCommand(VCM_DC_SETTING)
-	d.SendData(0x12)
-
-	d.SendCommand(VCOM_AND_DATA_INTERVAL_SETTING)
-	d.SendCommand(0x97) //VBDF 17|D7 VBDW 97  VBDB 57  VBDF F7  VBDW 77  VBDB 37  VBDR B7
+// DisplayGray sends a 4 level grayscale image to the screen. The buffer holds
+// 2 bits per pixel, 4 pixels per byte with the leftmost one in the most
+// significant bits, from 0 (black) to 3 (white). Its size must be
+// LogicalWidth*Height/4 bytes. The image is sent as is, the rotation is not
+// applied and the internal buffer is not modified.
+//
+// The next call to Display does a full refresh.
+func (d *Device) DisplayGray(buffer []uint8) error {
+	if len(buffer) != len(d.Buffer(waveshareepd.BLACK))*2 {
+		return errors.New("buffer length does not match with display size")
+	}
+	d.setResolution()
 
+	// the high bit of every pixel is the old data, the low bit the new data
 	d.SendCommand(DATA_START_TRANSMISSION_1)
-	var i int16
-	for i = 0; i < d.logicalWidth/8*d.height; i++ {
-		d.SendData(0xFF) // bit set: white, bit reset: black
-	}
-	time.Sleep(2 * time.Millisecond)
+	d.sendGrayPlane(buffer, 1)
 	d.SendCommand(DATA_START_TRANSMISSION_2)
-	for i = 0; i < d.logicalWidth/8*d.height; i++ {
-		d.SendData(d.buffer[i])
+	d.sendGrayPlane(buffer, 0)
+
+	d.refresh(d.luts[GRAY4_REFRESH])
+	d.oldValid = false
+	return nil
+}
+
+// sendGrayPlane sends one bit of every pixel of a 2 bits per pixel buffer
+func (d *Device) sendGrayPlane(buffer []uint8, bit uint8) {
+	for i := 0; i < len(buffer); i += 2 {
+		d.SendData(refresh.GrayPlane(buffer[i], buffer[i+1], bit))
 	}
 	time.Sleep(2 * time.Millisecond)
+}
 
-	d.SetLUT()
+// setResolution sets the display settings before sending an image, the
+// resolution itself is set by Configure
+func (d *Device) setResolution() {
+	d.SendCommand(VCM_DC_SETTING)
+	d.SendData(0x12)
 
-	d.SendCommand(DISPLAY_REFRESH)
+	d.SendCommand(VCOM_AND_DATA_INTERVAL_SETTING)
+	d.SendData(0x97) //VBDF 17|D7 VBDW 97  VBDB 57  VBDF F7  VBDW 77  VBDB 37  VBDR B7
+}
+
+// refresh loads the look up tables and refreshes the screen
+func (d *Device) refresh(lut *LUT) {
+	d.sendLUT(lut)
+	d.SendSequence(panel.Update)
 	time.Sleep(100 * time.Millisecond)
 	d.WaitUntilIdle()
-
-	return nil
 }
 
 // ClearDisplay erases the device SRAM
 func (d *Device) ClearDisplay() {
-	d.SendCommand(RESOLUTION_SETTING)
-	d.SendData(uint8(d.height >> 8))
-	d.SendData(uint8(d.logicalWidth & 0xff))
-	d.SendData(uint8(d.height >> 8))
-	d.SendData(uint8(d.height & 0xff))
+	d.setResolution()
+	n := len(d.Buffer(waveshareepd.BLACK))
 
 	d.SendCommand(DATA_START_TRANSMISSION_1)
 	time.Sleep(2 * time.Millisecond)
-	var i int16
-	for i = 0; i < d.logicalWidth/8*d.height; i++ {
+	for i := 0; i < n; i++ {
 		d.SendData(0xFF)
 	}
 	time.Sleep(2 * time.Millisecond)
 	d.SendCommand(DATA_START_TRANSMISSION_2)
 	time.Sleep(2 * time.Millisecond)
-	for i = 0; i < d.logicalWidth/8*d.height; i++ {
+	for i := 0; i < n; i++ {
 		d.SendData(0xFF)
 	}
 	time.Sleep(2 * time.Millisecond)
 
-	d.SetLUT()
-	d.SendCommand(DISPLAY_REFRESH)
-	time.Sleep(100 * time.Millisecond)
-	d.WaitUntilIdle()
-}
-
-// WaitUntilIdle waits until the display is ready
-func (d *Device) WaitUntilIdle() {
-	for d.busy.Get() {
-		time.Sleep(100 * time.Millisecond)
-	}
-}
-
-// IsBusy returns the busy status of the display
-func (d *Device) IsBusy() bool {
-	return d.busy.Get()
-}
-
-// ClearBuffer sets the buffer to 0xFF (white)
-func (d *Device) ClearBuffer() {
-	for i := uint32(0); i < d.bufferLength; i++ {
-		d.buffer[i] = 0xFF
-	}
-}
-
-// Size returns the current size of the display.
-func (d *Device) Size() (w, h int16) {
-	if d.rotation == ROTATION_90 || d.rotation == ROTATION_270 {
-		return d.height, d.logicalWidth
-	}
-	return d.logicalWidth, d.height
-}
-
-// SetRotation changes the rotation (clock-wise) of the device
-func (d *Device) SetRotation(rotation Rotation) {
-	d.rotation = rotation
-}
-
-// xy chages the coordinates according to the rotation
-func (d *Device) xy(x, y int16) (int16, int16) {
-	switch d.rotation {
-	case NO_ROTATION:
-		return x, y
-	case ROTATION_90:
-		return d.width - y - 1, x
-	case ROTATION_180:
-		return d.width - x - 1, d.height - y - 1
-	case ROTATION_270:
-		return y, d.height - x - 1
-	}
-	return x, y
+	d.refresh(d.luts[FULL_REFRESH])
+	d.oldValid = true
 }
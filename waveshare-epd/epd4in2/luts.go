@@ -0,0 +1,218 @@
+package epd4in2
+
+// LUT holds the waveform look up tables of the controller. Each table is sent
+// to its register as is: VCOM to LUT_FOR_VCOM (0x20), WW to 0x21, BW to 0x22,
+// WB to 0x23 and BB to 0x24.
+type LUT struct {
+	VCOM []uint8
+	WW   []uint8
+	BW   []uint8
+	WB   []uint8
+	BB   []uint8
+}
+
+// Derived from https://github.com/waveshare/e-Paper/blob/master/Arduino/epd4in2/epd4in2.cpp
+// and https://github.com/waveshare/e-Paper/blob/master/RaspberryPi_JetsonNano/c/lib/e-Paper/EPD_4in2.c
+
+// lutFull is the default waveform, slow and flashing but without ghosting.
+var lutFull = LUT{
+	VCOM: []uint8{
+		0x00, 0x17, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x17, 0x17, 0x00, 0x00, 0x02,
+		0x00, 0x0A, 0x01, 0x00, 0x00, 0x01,
+		0x00, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, // 44 bytes, unlike the others
+	},
+	WW: []uint8{
+		0x40, 0x17, 0x00, 0x00, 0x00, 0x02,
+		0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
+		0x40, 0x0A, 0x01, 0x00, 0x00, 0x01,
+		0xA0, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	BW: []uint8{
+		0x40, 0x17, 0x00, 0x00, 0x00, 0x02,
+		0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
+		0x40, 0x0A, 0x01, 0x00, 0x00, 0x01,
+		0xA0, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	WB: []uint8{
+		0x80, 0x17, 0x00, 0x00, 0x00, 0x02,
+		0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
+		0x80, 0x0A, 0x01, 0x00, 0x00, 0x01,
+		0x50, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	BB: []uint8{
+		0x80, 0x17, 0x00, 0x00, 0x00, 0x02,
+		0x90, 0x17, 0x17, 0x00, 0x00, 0x02,
+		0x80, 0x0A, 0x01, 0x00, 0x00, 0x01,
+		0x50, 0x0E, 0x0E, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+}
+
+// lutFast is a single phase waveform: much faster and without flashing, but
+// some ghosting remains.
+var lutFast = LUT{
+	VCOM: []uint8{
+		0x00, 0x0E, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00,
+	},
+	WW: []uint8{
+		0xA0, 0x0E, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	BW: []uint8{
+		0xA0, 0x0E, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	WB: []uint8{
+		0x50, 0x0E, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	BB: []uint8{
+		0x50, 0x0E, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+}
+
+// lutPartial only drives the pixels that changed from the previous frame
+// (WW and BB are idle), so the rest of the screen does not flicker.
+var lutPartial = LUT{
+	VCOM: []uint8{
+		0x00, 0x19, 0x01, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00,
+	},
+	WW: []uint8{
+		0x00, 0x19, 0x01, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	BW: []uint8{
+		0x80, 0x19, 0x01, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	WB: []uint8{
+		0x40, 0x19, 0x01, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	BB: []uint8{
+		0x00, 0x19, 0x01, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+}
+
+// lutGray4 drives 4 gray levels. The two bits of every pixel are sent as the
+// old (DATA_START_TRANSMISSION_1) and new (DATA_START_TRANSMISSION_2) data,
+// so each of the 4 tables produces one of the levels.
+var lutGray4 = LUT{
+	VCOM: []uint8{
+		0x00, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x60, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x00, 0x14, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x13, 0x0A, 0x01, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	WW: []uint8{
+		0x40, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x10, 0x14, 0x0A, 0x00, 0x00, 0x01,
+		0xA0, 0x13, 0x01, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	BW: []uint8{
+		0x40, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x00, 0x14, 0x0A, 0x00, 0x00, 0x01,
+		0x99, 0x0C, 0x01, 0x03, 0x04, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	WB: []uint8{
+		0x40, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x00, 0x14, 0x0A, 0x00, 0x00, 0x01,
+		0x99, 0x0B, 0x04, 0x04, 0x01, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+	BB: []uint8{
+		0x80, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x20, 0x14, 0x0A, 0x00, 0x00, 0x01,
+		0x50, 0x13, 0x01, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+}
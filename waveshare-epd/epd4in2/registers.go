@@ -1,5 +1,7 @@
 package epd4in2
 
+import "tinygo.org/x/drivers/waveshare-epd/refresh"
+
 // Derived from https://github.com/waveshare/e-Paper/blob/master/Arduino/epd4in2/epd4in2.h
 
 // Registers
@@ -52,4 +54,9 @@ const (
 	ROTATION_90  Rotation = 1 // 90 degrees clock-wise rotation
 	ROTATION_180 Rotation = 2
 	ROTATION_270 Rotation = 3
+
+	FULL_REFRESH    RefreshMode = refresh.Full    // slow with flashing, no ghosting
+	FAST_REFRESH    RefreshMode = refresh.Fast    // single phase waveform
+	PARTIAL_REFRESH RefreshMode = refresh.Partial // only drives the pixels that changed
+	GRAY4_REFRESH   RefreshMode = refresh.Gray4   // 4 gray levels, used by DisplayGray
 )
@@ -0,0 +1,95 @@
+package waveshareepd
+
+// Controller is the family of the controller chip driving a panel. Panels
+// with the same controller share the command set used to set up RAM windows,
+// write the image planes and refresh the screen.
+type Controller uint8
+
+const (
+	// SSD1680 covers the Solomon Systech SSD1608, SSD1675 and SSD1680 and the
+	// compatible IL3895 and IL3897 controllers. BUSY is high while busy.
+	SSD1680 Controller = iota
+
+	// UC8151 covers the UltraChip UC8151 and the compatible IL0373. BUSY is
+	// low while busy.
+	UC8151
+
+	// UC8176 covers the UltraChip UC8176 and the compatible IL0398, which
+	// take 16 bit horizontal resolutions. BUSY is low while busy.
+	UC8176
+)
+
+// Panel describes a panel model. Supporting a new panel with one of the
+// supported controllers only requires writing its Panel.
+//
+// The command sequences are encoded as a command byte, followed by a byte
+// holding the number of data bytes and then the data bytes. If bit 7 of the
+// count is set, the display is waited for after the command.
+type Panel struct {
+	Controller Controller
+
+	// Width and Height are the default resolution of the panel, in the
+	// orientation of its RAM: every row holds Width pixels.
+	Width  int16
+	Height int16
+
+	// Colors is 2 for black and white panels and 3 for panels with a second
+	// color plane (red or yellow).
+	Colors uint8
+
+	// ColorActiveHigh reports whether a bit set in the second color plane
+	// means colored. Otherwise, a bit reset means colored.
+	ColorActiveHigh bool
+
+	// GateScan is the last byte of the driver output control command of
+	// SSD1680 controllers, which sets the gate scanning order.
+	GateScan uint8
+
+	// Init is sent after a reset. The controller specific resolution and
+	// RAM addressing commands are sent by the driver afterwards.
+	Init []uint8
+
+	// Update refreshes the screen with the content of the RAM.
+	Update []uint8
+
+	// Sleep puts the display into deep sleep.
+	Sleep []uint8
+}
+
+// Commands shared by the controller families.
+const (
+	ssdDriverOutputControl  = 0x01
+	ssdDataEntryModeSetting = 0x11
+	ssdWriteRAM             = 0x24
+	ssdWriteRAMColor        = 0x26
+	ssdRAMXStartEnd         = 0x44
+	ssdRAMYStartEnd         = 0x45
+	ssdRAMXCounter          = 0x4E
+	ssdRAMYCounter          = 0x4F
+
+	ucDataStartTransmission1 = 0x10
+	ucDataStartTransmission2 = 0x13
+	ucResolutionSetting      = 0x61
+	ucPartialWindow          = 0x90
+	ucPartialIn              = 0x91
+	ucPartialOut             = 0x92
+)
+
+// Rotation is the clock-wise rotation of the display.
+type Rotation uint8
+
+const (
+	NO_ROTATION  Rotation = 0
+	ROTATION_90  Rotation = 1 // 90 degrees clock-wise rotation
+	ROTATION_180 Rotation = 2
+	ROTATION_270 Rotation = 3
+)
+
+// Color is the color of a pixel on the panel.
+type Color uint8
+
+const (
+	WHITE   Color = 0
+	BLACK   Color = 1
+	COLORED Color = 2 // In some board it's red in others yellow
+)
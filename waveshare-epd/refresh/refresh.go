@@ -0,0 +1,97 @@
+// Package refresh implements the refresh modes shared by the waveshare-epd
+// drivers: the choice of the waveform of every update, and the packing of
+// grayscale images into the planes sent to the panels.
+//
+// Not every panel supports every mode, the drivers list the modes they
+// support.
+//
+package refresh // import "tinygo.org/x/drivers/waveshare-epd/refresh"
+
+// Mode selects the waveform used to update a display.
+type Mode uint8
+
+const (
+	Full    Mode = 0 // slow with flashing, no ghosting
+	Fast    Mode = 1 // quicker without flashing, some ghosting
+	Partial Mode = 2 // only drives the pixels that changed, more ghosting
+	Gray4   Mode = 3 // 4 gray levels, for images of 2 bits per pixel
+)
+
+// Policy chooses the mode of each update of a display. When the mode is not
+// Full, a full refresh is done every Interval updates to clear the
+// accumulated ghosting.
+type Policy struct {
+	// Modes are the modes supported by the display, Full is always
+	// supported.
+	Modes []Mode
+
+	// Interval is the number of updates after which a full refresh is done.
+	// Zero disables the automatic full refresh.
+	Interval uint16
+
+	mode    Mode
+	updates uint16
+	full    bool
+}
+
+// Mode returns the current mode.
+func (p *Policy) Mode() Mode {
+	return p.mode
+}
+
+// SetMode changes the mode, and restarts counting the updates. Modes not
+// supported by the display are ignored, SetMode reports whether the mode was
+// changed.
+func (p *Policy) SetMode(mode Mode) bool {
+	if !p.Supports(mode) {
+		return false
+	}
+	p.mode = mode
+	p.updates = 0
+	return true
+}
+
+// Supports returns whether the display supports a mode.
+func (p *Policy) Supports(mode Mode) bool {
+	if mode == Full {
+		return true
+	}
+	for _, m := range p.Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset makes the next update a full refresh, for instance when the screen
+// content is not known anymore.
+func (p *Policy) Reset() {
+	p.full = true
+}
+
+// Next returns the mode of the next update: the current mode, or Full when
+// the interval is reached or after Reset.
+func (p *Policy) Next() Mode {
+	if p.mode == Full || p.full || (p.Interval > 0 && p.updates >= p.Interval) {
+		p.full = false
+		p.updates = 0
+		return Full
+	}
+	p.updates++
+	return p.mode
+}
+
+// GrayPlane extracts one bit of 8 pixels of an image of 2 bits per pixel,
+// held in hi and lo with the leftmost pixel in the most significant bits of
+// hi. The result holds the leftmost pixel in its most significant bit, as in
+// the planes of the panels. Bit 1 selects the high bit of the pixels, bit 0
+// the low bit.
+func GrayPlane(hi, lo uint8, bit uint8) uint8 {
+	return pack(hi>>bit)<<4 | pack(lo>>bit)
+}
+
+// pack gathers bits 6, 4, 2 and 0 of b
+func pack(b uint8) uint8 {
+	return (b>>3)&0x08 | (b>>2)&0x04 | (b>>1)&0x02 | b&0x01
+}
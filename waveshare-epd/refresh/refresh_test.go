@@ -0,0 +1,75 @@
+package refresh
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// updates returns the modes of the next n updates
+func updates(p *Policy, n int) []Mode {
+	var modes []Mode
+	for i := 0; i < n; i++ {
+		modes = append(modes, p.Next())
+	}
+	return modes
+}
+
+func TestSetMode(t *testing.T) {
+	c := qt.New(t)
+	p := &Policy{Modes: []Mode{Fast, Partial}}
+	c.Assert(p.Mode(), qt.Equals, Full)
+	c.Assert(p.SetMode(Partial), qt.IsTrue)
+	c.Assert(p.Mode(), qt.Equals, Partial)
+
+	// unsupported and unknown modes are ignored
+	c.Assert(p.SetMode(Gray4), qt.IsFalse)
+	c.Assert(p.SetMode(Mode(42)), qt.IsFalse)
+	c.Assert(p.Mode(), qt.Equals, Partial)
+
+	c.Assert(p.SetMode(Full), qt.IsTrue)
+	c.Assert(p.Mode(), qt.Equals, Full)
+	c.Assert((&Policy{}).SetMode(Fast), qt.IsFalse)
+}
+
+func TestNext(t *testing.T) {
+	c := qt.New(t)
+	p := &Policy{Modes: []Mode{Fast, Partial}}
+	c.Assert(updates(p, 3), qt.DeepEquals, []Mode{Full, Full, Full})
+
+	// without interval, the mode is always used
+	p.SetMode(Partial)
+	c.Assert(updates(p, 3), qt.DeepEquals, []Mode{Partial, Partial, Partial})
+
+	// a full refresh every 2 updates
+	p.Interval = 2
+	p.SetMode(Fast)
+	c.Assert(updates(p, 7), qt.DeepEquals, []Mode{Fast, Fast, Full, Fast, Fast, Full, Fast})
+
+	// changing the mode restarts counting
+	p.SetMode(Partial)
+	c.Assert(updates(p, 3), qt.DeepEquals, []Mode{Partial, Partial, Full})
+}
+
+func TestReset(t *testing.T) {
+	c := qt.New(t)
+	p := &Policy{Modes: []Mode{Partial}, Interval: 3}
+	p.SetMode(Partial)
+	p.Reset()
+	c.Assert(updates(p, 5), qt.DeepEquals, []Mode{Full, Partial, Partial, Partial, Full})
+
+	p.Next()
+	p.Reset()
+	c.Assert(updates(p, 2), qt.DeepEquals, []Mode{Full, Partial})
+}
+
+func TestGrayPlane(t *testing.T) {
+	c := qt.New(t)
+	// pixels 0 (black) 1 2 3 (white), then 3 2 1 0
+	hi, lo := uint8(0x1b), uint8(0xe4)
+	c.Assert(GrayPlane(hi, lo, 1), qt.Equals, uint8(0x3c))
+	c.Assert(GrayPlane(hi, lo, 0), qt.Equals, uint8(0x5a))
+	c.Assert(GrayPlane(0xff, 0x00, 1), qt.Equals, uint8(0xf0))
+	c.Assert(GrayPlane(0x55, 0xaa, 0), qt.Equals, uint8(0xf0))
+	c.Assert(GrayPlane(0x55, 0xaa, 1), qt.Equals, uint8(0x0f))
+}
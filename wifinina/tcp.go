@@ -13,13 +13,21 @@ const (
 )
 
 func (d *Device) NewDriver() net.DeviceDriver {
-	return &Driver{dev: d, sock: NoSocketAvail}
+	return &Driver{dev: d}
 }
 
+// Driver implements net.DeviceDriver, using the socket numbers of the device
+// as socket handles.
 type Driver struct {
 	dev     *Device
-	sock    uint8
-	readBuf readBuffer
+	sockets [MaxSockets]*socket
+}
+
+// socket is the state of an open socket.
+type socket struct {
+	sock     uint8
+	readBuf  readBuffer
+	listener bool
 
 	proto uint8
 	ip    uint32
@@ -37,63 +45,57 @@ func (drv *Driver) GetDNS(domain string) (string, error) {
 	return ipAddr.String(), err
 }
 
-func (drv *Driver) ConnectTCPSocket(addr, portStr string) error {
+func (drv *Driver) ConnectTCPSocket(addr, portStr string) (net.Socket, error) {
 	return drv.connectSocket(addr, portStr, ProtoModeTCP)
 }
 
-func (drv *Driver) ConnectSSLSocket(addr, portStr string) error {
+func (drv *Driver) ConnectSSLSocket(addr, portStr string) (net.Socket, error) {
 	return drv.connectSocket(addr, portStr, ProtoModeTLS)
 }
 
-func (drv *Driver) connectSocket(addr, portStr string, mode uint8) error {
-
-	drv.proto, drv.ip, drv.port = mode, 0, 0
+func (drv *Driver) connectSocket(addr, portStr string, mode uint8) (net.Socket, error) {
 
 	// convert port to uint16
-	p64, err := strconv.ParseUint(portStr, 10, 16)
+	port, err := convertPort(portStr)
 	if err != nil {
-		return fmt.Errorf("could not convert port to uint16: %s", err.Error())
+		return 0, err
 	}
-	port := uint16(p64)
 
 	// look up the hostname if necessary; if an IP address was specified, the
 	// same will be returned.  Otherwise, an IPv4 for the hostname is returned.
 	ipAddr, err := drv.dev.GetHostByName(addr)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	ip := ipAddr.AsUint32()
 
-	// check to see if socket is already set; if so, stop it
-	if drv.sock != NoSocketAvail {
-		if err := drv.stop(); err != nil {
-			return err
-		}
-	}
-
 	// get a socket from the device
-	if drv.sock, err = drv.dev.GetSocket(); err != nil {
-		return err
+	s, err := drv.newSocket(mode)
+	if err != nil {
+		return 0, err
 	}
 
 	// attempt to start the client
-	if err := drv.dev.StartClient(ip, port, drv.sock, mode); err != nil {
-		return err
+	if err := drv.dev.StartClient(ip, port, s.sock, mode); err != nil {
+		drv.release(s)
+		return 0, err
 	}
 
 	// FIXME: this 4 second timeout is simply mimicking the Arduino driver
 	for t := newTimer(4 * time.Second); !t.Expired(); {
-		connected, err := drv.IsConnected()
+		connected, err := drv.isConnected(s)
 		if err != nil {
-			return err
+			drv.stop(s)
+			return 0, err
 		}
 		if connected {
-			return nil
+			return net.Socket(s.sock), nil
 		}
 		wait(1 * time.Millisecond)
 	}
 
-	return ErrConnectionTimeout
+	drv.stop(s)
+	return 0, ErrConnectionTimeout
 }
 
 func convertPort(portStr string) (uint16, error) {
@@ -104,100 +106,175 @@ func convertPort(portStr string) (uint16, error) {
 	return uint16(p64), nil
 }
 
-func (drv *Driver) ConnectUDPSocket(addr, portStr, lportStr string) (err error) {
-
-	drv.proto, drv.ip, drv.port = ProtoModeUDP, 0, 0
+func (drv *Driver) ConnectUDPSocket(addr, portStr, lportStr string) (net.Socket, error) {
 
 	// convert remote port to uint16
-	if drv.port, err = convertPort(portStr); err != nil {
-		return err
+	port, err := convertPort(portStr)
+	if err != nil {
+		return 0, err
 	}
 
 	// convert local port to uint16
-	var lport uint16
-	if lport, err = convertPort(lportStr); err != nil {
-		return err
+	lport, err := convertPort(lportStr)
+	if err != nil {
+		return 0, err
 	}
 
 	// look up the hostname if necessary; if an IP address was specified, the
 	// same will be returned.  Otherwise, an IPv4 for the hostname is returned.
 	ipAddr, err := drv.dev.GetHostByName(addr)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	drv.ip = ipAddr.AsUint32()
 
-	// check to see if socket is already set; if so, stop it
-	// TODO: we can probably have more than one socket at once right?
-	if drv.sock != NoSocketAvail {
-		if err := drv.stop(); err != nil {
-			return err
-		}
+	// get a socket from the device
+	s, err := drv.newSocket(ProtoModeUDP)
+	if err != nil {
+		return 0, err
+	}
+	s.ip, s.port = ipAddr.AsUint32(), port
+
+	// start listening for UDP packets on the local port
+	if err := drv.dev.StartServer(lport, s.sock, s.proto); err != nil {
+		drv.release(s)
+		return 0, err
+	}
+
+	return net.Socket(s.sock), nil
+}
+
+func (drv *Driver) ListenTCPSocket(portStr string) (net.Socket, error) {
+
+	// convert port to uint16
+	port, err := convertPort(portStr)
+	if err != nil {
+		return 0, err
 	}
 
 	// get a socket from the device
-	if drv.sock, err = drv.dev.GetSocket(); err != nil {
-		return err
+	s, err := drv.newSocket(ProtoModeTCP)
+	if err != nil {
+		return 0, err
 	}
 
-	// start listening for UDP packets on the local port
-	if err := drv.dev.StartServer(lport, drv.sock, drv.proto); err != nil {
-		return err
+	// start listening for TCP connections on the port
+	if err := drv.dev.StartServer(port, s.sock, s.proto); err != nil {
+		drv.release(s)
+		return 0, err
 	}
+	s.listener = true
 
-	return nil
+	return net.Socket(s.sock), nil
+}
+
+// AcceptSocket returns the socket of a new client of the server socket.
+//
+// The device only reports clients once they have sent data, and keeps
+// reporting them as long as they have data available, so clients that are
+// already open are not returned again.
+func (drv *Driver) AcceptSocket(listener net.Socket) (net.Socket, error) {
+	l, err := drv.socket(listener)
+	if err != nil {
+		return 0, err
+	}
+	if !l.listener {
+		return 0, ErrNoSocketAvail
+	}
+	sock, err := drv.dev.AvailServer(l.sock)
+	if err != nil {
+		return 0, err
+	}
+	if int(sock) >= len(drv.sockets) {
+		return 0, net.ErrNoConnection
+	}
+	if _, err := drv.socket(net.Socket(sock)); err == nil {
+		// already accepted
+		return 0, net.ErrNoConnection
+	}
+	drv.open(sock, ProtoModeTCP)
+	return net.Socket(sock), nil
 }
 
-func (drv *Driver) DisconnectSocket() error {
-	return drv.stop()
+// newSocket gets a socket from the device.
+func (drv *Driver) newSocket(mode uint8) (*socket, error) {
+	sock, err := drv.dev.GetSocket()
+	if err != nil {
+		return nil, err
+	}
+	if int(sock) >= len(drv.sockets) {
+		// NoSocketAvail, or more sockets than expected
+		return nil, net.ErrNoSocket
+	}
+	return drv.open(sock, mode), nil
 }
 
-func (drv *Driver) StartSocketSend(size int) error {
-	// not needed for WiFiNINA???
-	return nil
+// open resets the state of a socket of the device.
+func (drv *Driver) open(sock uint8, mode uint8) *socket {
+	s := drv.sockets[sock]
+	if s == nil {
+		s = &socket{}
+		drv.sockets[sock] = s
+	}
+	*s = socket{sock: sock, proto: mode}
+	return s
 }
 
-func (drv *Driver) Response(timeout int) ([]byte, error) {
-	return nil, nil
+// socket returns the state of an open socket.
+func (drv *Driver) socket(sock net.Socket) (*socket, error) {
+	if sock < 0 || int(sock) >= len(drv.sockets) || drv.sockets[sock] == nil || drv.sockets[sock].sock == NoSocketAvail {
+		return nil, ErrNoSocketAvail
+	}
+	return drv.sockets[sock], nil
 }
 
-func (drv *Driver) Write(b []byte) (n int, err error) {
-	if drv.sock == NoSocketAvail {
-		return 0, ErrNoSocketAvail
+func (drv *Driver) DisconnectSocket(sock net.Socket) error {
+	s, err := drv.socket(sock)
+	if err != nil {
+		return nil
+	}
+	return drv.stop(s)
+}
+
+func (drv *Driver) WriteSocket(sock net.Socket, b []byte) (n int, err error) {
+	s, err := drv.socket(sock)
+	if err != nil {
+		return 0, err
 	}
 	if len(b) == 0 {
 		return 0, ErrNoData
 	}
-	if drv.proto == ProtoModeUDP {
-		if err := drv.dev.StartClient(drv.ip, drv.port, drv.sock, drv.proto); err != nil {
+	if s.proto == ProtoModeUDP {
+		if err := drv.dev.StartClient(s.ip, s.port, s.sock, s.proto); err != nil {
 			return 0, fmt.Errorf("error in startClient: %w", err)
 		}
-		if _, err := drv.dev.InsertDataBuf(b, drv.sock); err != nil {
+		if _, err := drv.dev.InsertDataBuf(b, s.sock); err != nil {
 			return 0, fmt.Errorf("error in insertDataBuf: %w", err)
 		}
-		if _, err := drv.dev.SendUDPData(drv.sock); err != nil {
+		if _, err := drv.dev.SendUDPData(s.sock); err != nil {
 			return 0, fmt.Errorf("error in sendUDPData: %w", err)
 		}
 		return len(b), nil
-	} else {
-		written, err := drv.dev.SendData(b, drv.sock)
-		if err != nil {
-			return 0, err
-		}
-		if written == 0 {
-			return 0, ErrDataNotWritten
-		}
-		if sent, _ := drv.dev.CheckDataSent(drv.sock); !sent {
-			return 0, ErrCheckDataError
-		}
-		return len(b), nil
 	}
 
+	written, err := drv.dev.SendData(b, s.sock)
+	if err != nil {
+		return 0, err
+	}
+	if written == 0 {
+		return 0, ErrDataNotWritten
+	}
+	if sent, _ := drv.dev.CheckDataSent(s.sock); !sent {
+		return 0, ErrCheckDataError
+	}
 	return len(b), nil
 }
 
-func (drv *Driver) ReadSocket(b []byte) (n int, err error) {
-	avail, err := drv.available()
+func (drv *Driver) ReadSocket(sock net.Socket, b []byte) (n int, err error) {
+	s, err := drv.socket(sock)
+	if err != nil {
+		return 0, err
+	}
+	avail, err := drv.available(s)
 	if err != nil {
 		println("ReadSocket error: " + err.Error())
 		return 0, err
@@ -209,43 +286,53 @@ func (drv *Driver) ReadSocket(b []byte) (n int, err error) {
 	if avail < length {
 		length = avail
 	}
-	copy(b, drv.readBuf.data[drv.readBuf.head:drv.readBuf.head+length])
-	drv.readBuf.head += length
-	drv.readBuf.size -= length
+	copy(b, s.readBuf.data[s.readBuf.head:s.readBuf.head+length])
+	s.readBuf.head += length
+	s.readBuf.size -= length
 	return length, nil
 }
 
 // IsSocketDataAvailable returns of there is socket data available
-func (drv *Driver) IsSocketDataAvailable() bool {
-	n, err := drv.available()
+func (drv *Driver) IsSocketDataAvailable(sock net.Socket) bool {
+	s, err := drv.socket(sock)
+	if err != nil {
+		return false
+	}
+	n, err := drv.available(s)
 	return err == nil && n > 0
 }
 
-func (drv *Driver) available() (int, error) {
-	if drv.readBuf.size == 0 {
-		n, err := drv.dev.GetDataBuf(drv.sock, drv.readBuf.data[:])
+func (drv *Driver) available(s *socket) (int, error) {
+	if s.readBuf.size == 0 {
+		n, err := drv.dev.GetDataBuf(s.sock, s.readBuf.data[:])
 		if n > 0 {
-			drv.readBuf.head = 0
-			drv.readBuf.size = n
+			s.readBuf.head = 0
+			s.readBuf.size = n
 		}
 		if err != nil {
 			return int(n), err
 		}
 	}
-	return drv.readBuf.size, nil
+	return s.readBuf.size, nil
 }
 
-func (drv *Driver) IsConnected() (bool, error) {
-	if drv.sock == NoSocketAvail {
+// IsConnected returns whether the socket is connected.
+func (drv *Driver) IsConnected(sock net.Socket) (bool, error) {
+	s, err := drv.socket(sock)
+	if err != nil {
 		return false, nil
 	}
-	s, err := drv.status()
+	return drv.isConnected(s)
+}
+
+func (drv *Driver) isConnected(s *socket) (bool, error) {
+	st, err := drv.dev.GetClientState(s.sock)
 	if err != nil {
 		return false, err
 	}
-	isConnected := !(s == TCPStateListen || s == TCPStateClosed ||
-		s == TCPStateFinWait1 || s == TCPStateFinWait2 || s == TCPStateTimeWait ||
-		s == TCPStateSynSent || s == TCPStateSynRcvd || s == TCPStateCloseWait)
+	isConnected := !(st == TCPStateListen || st == TCPStateClosed ||
+		st == TCPStateFinWait1 || st == TCPStateFinWait2 || st == TCPStateTimeWait ||
+		st == TCPStateSynSent || st == TCPStateSynRcvd || st == TCPStateCloseWait)
 	// TODO: investigate if the below is necessary (as per Arduino driver)
 	//if !isConnected {
 	//	//close socket buffer?
@@ -255,20 +342,10 @@ func (drv *Driver) IsConnected() (bool, error) {
 	return isConnected, nil
 }
 
-func (drv *Driver) status() (uint8, error) {
-	if drv.sock == NoSocketAvail {
-		return TCPStateClosed, nil
-	}
-	return drv.dev.GetClientState(drv.sock)
-}
-
-func (drv *Driver) stop() error {
-	if drv.sock == NoSocketAvail {
-		return nil
-	}
-	drv.dev.StopClient(drv.sock)
+func (drv *Driver) stop(s *socket) error {
+	drv.dev.StopClient(s.sock)
 	for t := newTimer(5 * time.Second); !t.Expired(); {
-		st, _ := drv.status()
+		st, _ := drv.dev.GetClientState(s.sock)
 		if st == TCPStateClosed {
 			break
 		}
@@ -277,6 +354,11 @@ func (drv *Driver) stop() error {
 		// an issue so should investigate further
 		//time.Sleep(1 * time.Millisecond)
 	}
-	drv.sock = NoSocketAvail
+	drv.release(s)
 	return nil
 }
+
+// release marks the socket as closed.
+func (drv *Driver) release(s *socket) {
+	s.sock = NoSocketAvail
+}
@@ -19,7 +19,7 @@ import (
 const _debug = false
 
 const (
-	MaxSockets  = 4
+	MaxSockets  = 10
 	MaxNetworks = 10
 	MaxAttempts = 10
 
@@ -41,6 +41,9 @@ const (
 	StatusConnectFailed  ConnectionStatus = 4
 	StatusConnectionLost ConnectionStatus = 5
 	StatusDisconnected   ConnectionStatus = 6
+	StatusAPListening    ConnectionStatus = 7
+	StatusAPConnected    ConnectionStatus = 8
+	StatusAPFailed       ConnectionStatus = 9
 
 	EncTypeTKIP EncryptionType = 2
 	EncTypeCCMP EncryptionType = 4
@@ -180,6 +183,12 @@ func (c ConnectionStatus) String() string {
 		return "Connection Lost"
 	case StatusDisconnected:
 		return "Disconnected"
+	case StatusAPListening:
+		return "AP Listening"
+	case StatusAPConnected:
+		return "AP Connected"
+	case StatusAPFailed:
+		return "AP Failed"
 	case StatusNoShield:
 		return "No Shield"
 	default:
@@ -386,10 +395,28 @@ func (d *Device) StartServer(port uint16, sock uint8, mode uint8) error {
 	l += d.sendParam8(mode, true)
 	d.addPadding(l)
 	d.spiChipDeselect()
-	_, err := d.waitRspCmd1(CmdStartClientTCP)
+	_, err := d.waitRspCmd1(CmdStartServerTCP)
 	return err
 }
 
+// GetServerState returns the TCP state of a server socket.
+func (d *Device) GetServerState(sock uint8) (uint8, error) {
+	return d.getUint8(d.reqUint8(CmdGetStateTCP, sock))
+}
+
+// AvailServer returns the socket of a client of the server socket that has
+// data available, or NoSocketAvail if there is none.
+func (d *Device) AvailServer(sock uint8) (uint8, error) {
+	if _, err := d.getUint16(d.reqUint8(CmdAvailDataTCP, sock)); err != nil {
+		return NoSocketAvail, err
+	}
+	// the firmware replies with a little endian uint16
+	if d.buf[1] != 0 {
+		return NoSocketAvail, nil
+	}
+	return d.buf[0], nil
+}
+
 // InsertDataBuf adds data to the buffer used for sending UDP data
 func (d *Device) InsertDataBuf(buf []byte, sock uint8) (bool, error) {
 	if err := d.waitForChipSelect(); err != nil {
@@ -7,17 +7,79 @@ import (
 	"machine"
 )
 
-var errUnknownClockSpeed = errors.New("ws2812: unknown CPU clock speed")
+var (
+	errUnknownClockSpeed = errors.New("ws2812: unknown CPU clock speed")
+	errUnknownColorOrder = errors.New("ws2812: unknown color order")
+)
+
+const (
+	// GRB aka "Green Red Blue" is the color order of WS2812 and SK6812 LEDs.
+	GRB = iota
+
+	// RGB aka "Red Green Blue" is the color order of most WS2811 pixels.
+	RGB
+
+	// BRG aka "Blue Red Green" is used by some WS2811 pixels.
+	BRG
+
+	// RBG aka "Red Blue Green".
+	RBG
+
+	// GBR aka "Green Blue Red".
+	GBR
+
+	// BGR aka "Blue Green Red".
+	BGR
+
+	// GRBW is the color order of SK6812 RGBW LEDs, which have a fourth white
+	// channel.
+	GRBW
+
+	// RGBW aka "Red Green Blue White".
+	RGBW
+)
+
+// orders holds the index of the color component (R, G, B, W) sent in each
+// position for every color order.
+var orders = [...][4]uint8{
+	GRB:  {1, 0, 2},
+	RGB:  {0, 1, 2},
+	BRG:  {2, 0, 1},
+	RBG:  {0, 2, 1},
+	GBR:  {1, 2, 0},
+	BGR:  {2, 1, 0},
+	GRBW: {1, 0, 2, 3},
+	RGBW: {0, 1, 2, 3},
+}
 
 // Device wraps a pin object for an easy driver interface.
 type Device struct {
 	Pin machine.Pin
+
+	// Order is the color order used by WriteColors, GRB by default.
+	Order int
+
+	// Gamma enables gamma correction in WriteColors, so that the perceived
+	// brightness of the colors is roughly linear.
+	Gamma bool
+
+	level uint16 // brightness + 1, or 0 for full brightness
 }
 
 // New returns a new WS2812 driver. It does not touch the pin object: you have
 // to configure it as an output pin before calling New.
 func New(pin machine.Pin) Device {
-	return Device{pin}
+	return Device{Pin: pin, Order: GRB}
+}
+
+// SetBrightness sets the global brightness used by WriteColors, from 0 (off)
+// to 255 (full brightness, the default).
+func (d *Device) SetBrightness(brightness uint8) {
+	if brightness == 255 {
+		d.level = 0
+	} else {
+		d.level = uint16(brightness) + 1
+	}
 }
 
 // Write the raw bitstring out using the WS2812 protocol.
@@ -29,12 +91,46 @@ func (d Device) Write(buf []byte) (n int, err error) {
 }
 
 // Write the given color slice out using the WS2812 protocol.
-// Colors are sent out in the usual GRB format.
+// Colors are sent out in the color order of the device, GRB by default, after
+// gamma correction and brightness scaling.
+//
+// With RGBW color orders, the white channel is extracted from the color: the
+// common part of the R, G and B components is sent as white instead.
 func (d Device) WriteColors(buf []color.RGBA) error {
-	for _, color := range buf {
-		d.WriteByte(color.G) // green
-		d.WriteByte(color.R) // red
-		d.WriteByte(color.B) // blue
+	if d.Order < 0 || d.Order >= len(orders) {
+		return errUnknownColorOrder
+	}
+	order := orders[d.Order]
+	channels := 3
+	if d.Order >= GRBW {
+		channels = 4
+	}
+	for _, c := range buf {
+		components := [4]uint8{c.R, c.G, c.B, 0}
+		if channels == 4 {
+			w := c.R
+			if c.G < w {
+				w = c.G
+			}
+			if c.B < w {
+				w = c.B
+			}
+			components = [4]uint8{c.R - w, c.G - w, c.B - w, w}
+		}
+		for _, i := range order[:channels] {
+			d.WriteByte(d.scale(components[i]))
+		}
 	}
 	return nil
 }
+
+// scale applies the gamma correction and brightness to a color component.
+func (d Device) scale(v uint8) uint8 {
+	if d.Gamma {
+		v = gamma8[v]
+	}
+	if d.level != 0 {
+		v = uint8(uint16(v) * d.level >> 8)
+	}
+	return v
+}